@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate [stats-file...]",
+	Short: "Pool the statistics of several runs",
+	Long: `Read statistics files written with "generate --output json" and print the pooled statistics of all their branches.
+
+All the files must share the same schema version.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			runs         int
+			leaves       int
+			transactions int
+			sizes        = newPooledDistribution()
+			weights      = newPooledDistribution()
+		)
+
+		for _, path := range args {
+			report, err := loadStatsReport(path)
+			if err != nil {
+				fmt.Printf("❌ Error: %s: %s\n", path, err)
+				os.Exit(1)
+			}
+
+			if err := sizes.add(report.BranchSizes); err != nil {
+				fmt.Printf("❌ Error: %s: branch sizes: %s\n", path, err)
+				os.Exit(1)
+			}
+			if err := weights.add(report.BroadcastWeights); err != nil {
+				fmt.Printf("❌ Error: %s: broadcast weights: %s\n", path, err)
+				os.Exit(1)
+			}
+
+			runs++
+			leaves += report.Leaves
+			transactions += report.TotalTransactions
+		}
+
+		fmt.Println("\n" + strings.Repeat("─", 60))
+		fmt.Println("📊 AGGREGATED STATISTICS")
+		fmt.Println(strings.Repeat("─", 60))
+
+		t := newTable(os.Stdout, false, true)
+		t.row("📁 Runs:", strconv.Itoa(runs))
+		t.row("🍃 Total Leaves:", strconv.Itoa(leaves))
+		t.row("🌳 Total Transactions:", strconv.Itoa(transactions))
+		t.flush()
+
+		printPooled("🌿 BRANCH SIZE", sizes)
+		printPooled("📡 TX TO BROADCAST", weights)
+
+		fmt.Println(strings.Repeat("─", 60))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(aggregateCmd)
+}
+
+func loadStatsReport(path string) (statsReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return statsReport{}, err
+	}
+	defer f.Close()
+
+	return readStatsReport(f)
+}
+
+func printPooled(name string, p *pooledDistribution) {
+	fmt.Printf("\n%s:\n", name)
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.rowf("Max:", "%.2f", p.max)
+	t.rowf("Mean:", "%.2f", p.mean())
+	t.rowf("Stddev:", "%.2f", p.stddev())
+	t.rowf("p50:", "%.2f", p.percentile(0.5))
+	t.rowf("p90:", "%.2f", p.percentile(0.9))
+	t.rowf("p99:", "%.2f", p.percentile(0.99))
+	t.flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// loadAmountSamples reads the amounts of an --amount-samples file, one
+// positive integer of sats per line, skipping blank lines
+func loadAmountSamples(path string) ([]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readAmountSamples(f, path)
+}
+
+// readAmountSamples reads the amounts of r, see loadAmountSamples; source
+// names the file in errors
+func readAmountSamples(r io.Reader, source string) ([]uint64, error) {
+	var samples []uint64
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		sample, err := strconv.ParseUint(text, 10, 64)
+		if err != nil || sample == 0 {
+			return nil, fmt.Errorf("line %d: amount must be a positive integer of sats, got %q", line, text)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no amount in %s", source)
+	}
+	return samples, nil
+}
+
+// amountSummary describes the distribution of the amounts of leaves
+type amountSummary struct {
+	total  uint64
+	min    uint64
+	median float64
+	mean   float64
+	max    uint64
+}
+
+func summarizeAmounts(leaves []tree.Leaf) amountSummary {
+	if len(leaves) == 0 {
+		return amountSummary{}
+	}
+
+	amounts := make([]uint64, 0, len(leaves))
+	var summary amountSummary
+	for _, leaf := range leaves {
+		amounts = append(amounts, leaf.Amount)
+		summary.total += leaf.Amount
+	}
+	slices.Sort(amounts)
+
+	n := len(amounts)
+	summary.min, summary.max = amounts[0], amounts[n-1]
+	summary.mean = float64(summary.total) / float64(n)
+	summary.median = float64(amounts[n/2])
+	if n%2 == 0 {
+		summary.median = (float64(amounts[n/2-1]) + float64(amounts[n/2])) / 2
+	}
+	return summary
+}
+
+func (s amountSummary) String() string {
+	return fmt.Sprintf("total %d sats (min %d, median %.1f, mean %.1f, max %d)", s.total, s.min, s.median, s.mean, s.max)
+}
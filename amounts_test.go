@@ -0,0 +1,31 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestReadAmountSamples(t *testing.T) {
+	samples, err := readAmountSamples(strings.NewReader("1000\n\n 250 \n"), "samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(samples, []uint64{1000, 250}) {
+		t.Errorf("read %v", samples)
+	}
+	for _, invalid := range []string{"0", "-5", "1.5", "1e3", ""} {
+		if _, err := readAmountSamples(strings.NewReader(invalid), "samples"); err == nil {
+			t.Errorf("%q accepted", invalid)
+		}
+	}
+}
+
+func TestSummarizeAmounts(t *testing.T) {
+	summary := summarizeAmounts([]tree.Leaf{{Amount: 100}, {Amount: 400}, {Amount: 200}, {Amount: 1300}})
+	if summary.total != 2000 || summary.min != 100 || summary.max != 1300 || summary.median != 300 || summary.mean != 500 {
+		t.Errorf("got %+v", summary)
+	}
+}
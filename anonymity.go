@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var anonymitySetCmd = &cobra.Command{
+	Use:   "anonymity-set [tree-file]",
+	Short: "Print how many users each shared tx of an exported tree links when they exit together",
+	Long: `Import a tree exported with "generate --out" and print its co-exit groups: the users under each tx, who all broadcast it to exit, so that exiting together links them to each other through it.
+
+For each level, the root first, it prints the number of groups and their smallest, mean and largest size, then the number of shared txs, those of more than one user, by group size. The privacy score sums them up: 1 minus the mean number of users a shared tx links over the number of users, 0 when the root alone links all of them and closer to 1 as each shared tx links fewer.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		groups := arktree.CoExitGroups(txtree)
+
+		fmt.Println("\n🕵️ CO-EXIT GROUPS BY LEVEL:")
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, true, true, true, true, true)
+		t.row("Level", "Groups", "Min", "Mean", "Max")
+		shared := make(map[int]int)
+		for i, level := range groups {
+			smallest, largest, users := level[0], level[0], 0
+			for _, size := range level {
+				smallest, largest = min(smallest, size), max(largest, size)
+				users += size
+				if size > 1 {
+					shared[size]++
+				}
+			}
+			t.row(strconv.Itoa(i+1), strconv.Itoa(len(level)), strconv.Itoa(smallest),
+				fmt.Sprintf("%.2f", float64(users)/float64(len(level))), strconv.Itoa(largest))
+		}
+		t.flush()
+
+		sizes := make([]int, 0, len(shared))
+		for size := range shared {
+			sizes = append(sizes, size)
+		}
+		sort.Ints(sizes)
+
+		fmt.Println("\n👥 SHARED TXS BY USERS LINKED:")
+		fmt.Println(strings.Repeat("─", 40))
+		t = newTable(os.Stdout, true, true)
+		t.row("Users", "Txs")
+		for _, size := range sizes {
+			t.row(strconv.Itoa(size), strconv.Itoa(shared[size]))
+		}
+		t.flush()
+
+		fmt.Println(strings.Repeat("─", 40))
+		fmt.Printf("🔏 Privacy Score:          %.2f (1 = each shared tx links few users)\n", arktree.PrivacyScore(groups))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(anonymitySetCmd)
+}
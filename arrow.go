@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// flatBuilder builds a flatbuffer back to front, children before the tables
+// referring to them, as the reference builder does. Offsets are counted from
+// the end of the buffer until it is finished. It only covers what the Arrow
+// IPC messages need: the Arrow module, which the tests read the files back
+// with, would pull its array, memory and flatbuffers packages into the binary.
+type flatBuilder struct {
+	buf      []byte // the bytes written so far, the last ones written first
+	minAlign int
+	fields   []int // end offset of each field of the table being built, 0 if unset
+	start    int   // end offset at the start of the table being built
+}
+
+func (b *flatBuilder) offset() int { return len(b.buf) }
+
+func (b *flatBuilder) prepend(p []byte) {
+	b.buf = append(append(make([]byte, 0, len(p)+len(b.buf)), p...), b.buf...)
+}
+
+// prep pads the buffer so that once n more bytes are written, its size is a
+// multiple of align
+func (b *flatBuilder) prep(align, n int) {
+	b.minAlign = max(b.minAlign, align)
+	if pad := (align - (len(b.buf)+n)%align) % align; pad > 0 {
+		b.prepend(make([]byte, pad))
+	}
+}
+
+func (b *flatBuilder) uint8(v uint8) {
+	b.prepend([]byte{v})
+}
+
+func (b *flatBuilder) int16(v int16) {
+	b.prep(2, 0)
+	b.prepend(binary.LittleEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (b *flatBuilder) int32(v int32) {
+	b.prep(4, 0)
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (b *flatBuilder) int64(v int64) {
+	b.prep(8, 0)
+	b.prepend(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+}
+
+// uoffset writes a reference to the object ending at off, relative to itself
+func (b *flatBuilder) uoffset(off int) {
+	b.prep(4, 0)
+	b.int32(int32(b.offset() - off + 4))
+}
+
+func (b *flatBuilder) string(s string) int {
+	b.prep(4, len(s)+1)
+	b.prepend(append([]byte(s), 0))
+	b.int32(int32(len(s)))
+	return b.offset()
+}
+
+func (b *flatBuilder) offsetVector(offs []int) int {
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.uoffset(offs[i])
+	}
+	b.int32(int32(len(offs)))
+	return b.offset()
+}
+
+// structVector writes a vector of structs made of int64 fields, each given as
+// its fields; padding fields are given as 0
+func (b *flatBuilder) structVector(structs [][]int64) int {
+	size := 0
+	if len(structs) > 0 {
+		size = 8 * len(structs[0])
+	}
+	b.prep(4, size*len(structs))
+	b.prep(8, size*len(structs))
+	for i := len(structs) - 1; i >= 0; i-- {
+		for j := len(structs[i]) - 1; j >= 0; j-- {
+			b.int64(structs[i][j])
+		}
+	}
+	b.int32(int32(len(structs)))
+	return b.offset()
+}
+
+func (b *flatBuilder) startTable(numFields int) {
+	b.fields = make([]int, numFields)
+	b.start = b.offset()
+}
+
+// field records that the value of field i was just written
+func (b *flatBuilder) field(i int) {
+	b.fields[i] = b.offset()
+}
+
+func (b *flatBuilder) tableInt16(i int, v int16)  { b.int16(v); b.field(i) }
+func (b *flatBuilder) tableInt32(i int, v int32)  { b.int32(v); b.field(i) }
+func (b *flatBuilder) tableInt64(i int, v int64)  { b.int64(v); b.field(i) }
+func (b *flatBuilder) tableUint8(i int, v uint8)  { b.uint8(v); b.field(i) }
+func (b *flatBuilder) tableOffset(i int, off int) { b.uoffset(off); b.field(i) }
+
+func (b *flatBuilder) tableBool(i int, v bool) {
+	var u uint8
+	if v {
+		u = 1
+	}
+	b.tableUint8(i, u)
+}
+
+// endTable writes the table, preceded by its vtable, and returns its offset
+func (b *flatBuilder) endTable() int {
+	b.int32(0) // offset to the vtable, set below
+	table := b.offset()
+
+	vtable := make([]byte, 0, 4+2*len(b.fields))
+	vtable = binary.LittleEndian.AppendUint16(vtable, uint16(4+2*len(b.fields)))
+	vtable = binary.LittleEndian.AppendUint16(vtable, uint16(table-b.start))
+	for _, field := range b.fields {
+		var position uint16
+		if field != 0 {
+			position = uint16(table - field)
+		}
+		vtable = binary.LittleEndian.AppendUint16(vtable, position)
+	}
+	b.prepend(vtable)
+
+	binary.LittleEndian.PutUint32(b.buf[b.offset()-table:], uint32(b.offset()-table))
+	b.fields = nil
+	return table
+}
+
+// finish writes the reference to the root table and returns the flatbuffer
+func (b *flatBuilder) finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.uoffset(root)
+	return b.buf
+}
+
+// Values of the Arrow flatbuffer schemas, see format/Schema.fbs and
+// format/Message.fbs in the Arrow repository
+const (
+	arrowMetadataV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt           = 2
+	arrowTypeFloatingPoint = 3
+	arrowTypeUtf8          = 5
+
+	arrowPrecisionDouble = 2
+)
+
+// arrowMagic starts and ends an Arrow IPC file
+const arrowMagic = "ARROW1"
+
+// arrowColumn is a non-nullable column of a record batch
+type arrowColumn struct {
+	name    string
+	typ     uint8
+	bits    int32    // of an Int column
+	buffers [][]byte // the buffers of the values, after the validity bitmap
+}
+
+func utf8Column(name string, values []string) arrowColumn {
+	offsets := make([]byte, 0, 4*(len(values)+1))
+	var data []byte
+	offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+	for _, v := range values {
+		data = append(data, v...)
+		offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+	}
+	return arrowColumn{name: name, typ: arrowTypeUtf8, buffers: [][]byte{offsets, data}}
+}
+
+func int32Column(name string, values []int32) arrowColumn {
+	data := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(v))
+	}
+	return arrowColumn{name: name, typ: arrowTypeInt, bits: 32, buffers: [][]byte{data}}
+}
+
+func int64Column(name string, values []int64) arrowColumn {
+	data := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(v))
+	}
+	return arrowColumn{name: name, typ: arrowTypeInt, bits: 64, buffers: [][]byte{data}}
+}
+
+func float64Column(name string, values []float64) arrowColumn {
+	data := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	return arrowColumn{name: name, typ: arrowTypeFloatingPoint, buffers: [][]byte{data}}
+}
+
+// arrowSchema writes the Schema table of columns to b
+func arrowSchema(b *flatBuilder, columns []arrowColumn) int {
+	fields := make([]int, 0, len(columns))
+	for _, column := range columns {
+		name := b.string(column.name)
+		b.startTable(2)
+		switch column.typ {
+		case arrowTypeInt:
+			b.tableInt32(0, column.bits)
+			b.tableBool(1, true)
+		case arrowTypeFloatingPoint:
+			b.tableInt16(0, arrowPrecisionDouble)
+		}
+		typ := b.endTable()
+		children := b.offsetVector(nil)
+
+		b.startTable(7)
+		b.tableOffset(0, name)
+		b.tableOffset(3, typ)
+		b.tableOffset(5, children)
+		b.tableBool(1, false)
+		b.tableUint8(2, column.typ)
+		fields = append(fields, b.endTable())
+	}
+	vector := b.offsetVector(fields)
+
+	b.startTable(4)
+	b.tableOffset(1, vector)
+	b.tableInt16(0, 0) // little endian
+	return b.endTable()
+}
+
+// arrowMessage returns a Message flatbuffer of the given header
+func arrowMessage(headerType uint8, bodyLength int64, header func(*flatBuilder) int) []byte {
+	b := &flatBuilder{}
+	h := header(b)
+	b.startTable(5)
+	b.tableInt64(3, bodyLength)
+	b.tableOffset(2, h)
+	b.tableInt16(0, arrowMetadataV5)
+	b.tableUint8(1, headerType)
+	return b.finish(b.endTable())
+}
+
+// arrowWriter writes the messages of an Arrow IPC file, keeping track of
+// where the record batches are for the footer
+type arrowWriter struct {
+	w       io.Writer
+	written int64
+	batches [][]int64 // Block structs: offset, metadata length (and padding), body length
+	err     error
+}
+
+func (a *arrowWriter) write(p []byte) {
+	if a.err != nil {
+		return
+	}
+	n, err := a.w.Write(p)
+	a.written += int64(n)
+	a.err = err
+}
+
+func (a *arrowWriter) pad() {
+	if rest := a.written % 8; rest != 0 {
+		a.write(make([]byte, 8-rest))
+	}
+}
+
+// message writes an encapsulated message: a continuation marker, the length
+// of the metadata padded to 8 bytes, the metadata then the body. It returns
+// the length of the metadata with its prefix
+func (a *arrowWriter) message(metadata []byte, body []byte) int64 {
+	padded := (len(metadata) + 8 + 7) / 8 * 8
+	prefix := binary.LittleEndian.AppendUint32(nil, math.MaxUint32)
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(padded-8))
+	a.write(prefix)
+	a.write(metadata)
+	a.write(make([]byte, padded-8-len(metadata)))
+	a.write(body)
+	return int64(padded)
+}
+
+// writeArrowFile writes columns of numRows rows as an Arrow IPC file holding
+// a single record batch. Every buffer is padded to 8 bytes, the columns have
+// no nulls so their validity bitmaps are empty.
+func writeArrowFile(w io.Writer, numRows int, columns []arrowColumn) error {
+	a := &arrowWriter{w: w}
+	a.write([]byte(arrowMagic))
+	a.pad()
+
+	a.message(arrowMessage(arrowHeaderSchema, 0, func(b *flatBuilder) int {
+		return arrowSchema(b, columns)
+	}), nil)
+
+	var (
+		body    []byte
+		nodes   [][]int64
+		buffers [][]int64
+	)
+	for _, column := range columns {
+		nodes = append(nodes, []int64{int64(numRows), 0})
+		buffers = append(buffers, []int64{int64(len(body)), 0})
+		for _, buffer := range column.buffers {
+			buffers = append(buffers, []int64{int64(len(body)), int64(len(buffer))})
+			body = append(body, buffer...)
+			body = append(body, make([]byte, (8-len(body)%8)%8)...)
+		}
+	}
+	batchOffset := a.written
+	metadataLength := a.message(arrowMessage(arrowHeaderRecordBatch, int64(len(body)), func(b *flatBuilder) int {
+		nodesVector := b.structVector(nodes)
+		buffersVector := b.structVector(buffers)
+		b.startTable(5)
+		b.tableInt64(0, int64(numRows))
+		b.tableOffset(1, nodesVector)
+		b.tableOffset(2, buffersVector)
+		return b.endTable()
+	}), body)
+	a.batches = append(a.batches, []int64{batchOffset, metadataLength, int64(len(body))})
+
+	// end of stream marker
+	a.write(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, math.MaxUint32), 0))
+
+	b := &flatBuilder{}
+	schema := arrowSchema(b, columns)
+	dictionaries := b.structVector(nil)
+	batches := b.structVector(a.batches)
+	b.startTable(5)
+	b.tableOffset(1, schema)
+	b.tableOffset(2, dictionaries)
+	b.tableOffset(3, batches)
+	b.tableInt16(0, arrowMetadataV5)
+	footer := b.finish(b.endTable())
+
+	a.write(footer)
+	a.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	a.write([]byte(arrowMagic))
+	return a.err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	assertMaxDepth  int
+	assertMaxWeight float64
+	assertBinary    bool
+	assertFeeBudget bool
+	assertQuiet     bool
+)
+
+// addAssertFlags registers the --assert-* gates on cmd, a zero threshold
+// disables the gate
+func addAssertFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&assertMaxDepth, "assert-max-depth", 0, "Fail if the biggest branch has more tx than this")
+	cmd.Flags().Float64Var(&assertMaxWeight, "assert-max-weight", 0, "Fail if any branch has more tx to broadcast than this")
+	cmd.Flags().BoolVar(&assertBinary, "assert-binary", false, "Fail if any node has more than two children")
+	cmd.Flags().BoolVar(&assertFeeBudget, "assert-fee-budget", false, "Fail if any exit fee exceeds --fee-budget")
+	cmd.Flags().BoolVar(&assertQuiet, "assert-quiet", false, "Print nothing but the failed assertions")
+}
+
+// assertion is the result of an --assert-* gate
+type assertion struct {
+	flag      string
+	actual    float64
+	threshold float64
+	// detail is printed when the assertion fails
+	detail string
+}
+
+func (a assertion) failed() bool {
+	return a.actual > a.threshold
+}
+
+// assertionsEnabled reports whether any --assert-* gate is enabled
+func assertionsEnabled() bool {
+	return assertBinary || assertMaxDepth > 0 || assertMaxWeight > 0 || assertFeeBudget && feeBudget > 0
+}
+
+// evaluateAssertions returns the enabled --assert-* gates evaluated on the tree and its stats
+func evaluateAssertions(txtree *tree.TxGraph, stats *arktree.Report) []assertion {
+	var assertions []assertion
+	if assertBinary {
+		maxChildren, offending := widestNodes(txtree)
+		assertions = append(assertions, assertion{
+			flag:      "assert-binary",
+			actual:    float64(maxChildren),
+			threshold: 2,
+			detail:    fmt.Sprintf("nodes with more than 2 children: %s", strings.Join(offending, ", ")),
+		})
+	}
+	if assertMaxDepth > 0 {
+		assertions = append(assertions, assertion{
+			flag:      "assert-max-depth",
+			actual:    float64(stats.BiggestBranch()),
+			threshold: float64(assertMaxDepth),
+		})
+	}
+	if assertMaxWeight > 0 {
+		assertions = append(assertions, assertion{
+			flag:      "assert-max-weight",
+			actual:    stats.HeaviestBranch(),
+			threshold: assertMaxWeight,
+		})
+	}
+	if assertFeeBudget && feeBudget > 0 {
+		maxFee := int64(0)
+		for _, cost := range stats.ExitCosts {
+			maxFee = max(maxFee, cost.Fee)
+		}
+		over := arktree.OverBudget(stats.ExitCosts, feeBudget)
+		assertions = append(assertions, assertion{
+			flag:      "assert-fee-budget",
+			actual:    float64(maxFee),
+			threshold: float64(feeBudget),
+			detail:    fmt.Sprintf("%d exits exceed the budget of %d sats", len(over), feeBudget),
+		})
+	}
+	return assertions
+}
+
+// printAssertions prints to w the result of every assertion, only the failed
+// ones if quiet, and returns whether any failed
+func printAssertions(w io.Writer, assertions []assertion, quiet bool) bool {
+	if len(assertions) == 0 {
+		return false
+	}
+
+	if !quiet {
+		fmt.Fprintln(w, "\n🚦 ASSERTIONS:")
+		fmt.Fprintln(w, strings.Repeat("─", 40))
+	}
+
+	failed := false
+	for _, a := range assertions {
+		if a.failed() {
+			failed = true
+			fmt.Fprintf(w, "❌ FAIL  --%s: %.2f > %.2f\n", a.flag, a.actual, a.threshold)
+			if a.detail != "" {
+				fmt.Fprintf(w, "         %s\n", a.detail)
+			}
+		} else if !quiet {
+			fmt.Fprintf(w, "✅ PASS  --%s: %.2f <= %.2f\n", a.flag, a.actual, a.threshold)
+		}
+	}
+
+	return failed
+}
+
+// checkGates exits with an error if the tree fails --min-cosigners or any
+// --assert-* gate, printing the results to w
+func checkGates(w io.Writer, txtree *tree.TxGraph, stats *arktree.Report) {
+	checkMinCosigners(w, txtree)
+	if printAssertions(w, evaluateAssertions(txtree, stats), assertQuiet) {
+		os.Exit(1)
+	}
+}
+
+// widestNodes returns the maximum number of children of a node in g and the
+// sorted txids of the nodes having more than two
+func widestNodes(g *tree.TxGraph) (int, []string) {
+	maxChildren := 0
+	var offending []string
+	// the callback never fails
+	_ = g.Apply(func(node *tree.TxGraph) (bool, error) {
+		maxChildren = max(maxChildren, len(node.Children))
+		if len(node.Children) > 2 {
+			offending = append(offending, node.Root.UnsignedTx.TxID())
+		}
+		return true, nil
+	})
+	sort.Strings(offending)
+	return maxChildren, offending
+}
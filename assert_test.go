@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGatesRunWhateverTheOutput(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		output   []string
+		assert   string
+		wantCode int
+	}{
+		{"CDF passing", []string{"--cdf"}, "4", 0},
+		{"CDF failing", []string{"--cdf"}, "1", 1},
+		{"JSON passing", []string{"-o", "json"}, "4", 0},
+		{"JSON failing", []string{"-o", "json"}, "1", 1},
+		{"Newick failing", []string{"-o", "newick"}, "1", 1},
+		{"DOT failing", []string{"-o", "dot"}, "1", 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			log := filepath.Join(t.TempDir(), "runs.jsonl")
+			args := append([]string{"generate", "8", "--seed", "1", "--assert-max-depth", test.assert, "--log-json", log}, test.output...)
+			stdout, stderr, code := runArktree(t, args...)
+			if code != test.wantCode {
+				t.Fatalf("exit code %d, expected %d\nstdout:\n%s\nstderr:\n%s", code, test.wantCode, stdout, stderr)
+			}
+
+			// the gates report on stderr, stdout carrying the output
+			if strings.Contains(stdout, "--assert-max-depth") || !strings.Contains(stderr, "--assert-max-depth") {
+				t.Errorf("assertion printed on stdout:\n%s\nstderr:\n%s", stdout, stderr)
+			}
+			// only the runs passing the gates are logged
+			data, err := os.ReadFile(log)
+			if logged := err == nil && strings.Count(string(data), "\n") == 1; logged != (test.wantCode == 0) {
+				t.Errorf("run logged: %t (%v), expected %t", logged, err, test.wantCode == 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// baselinePath is the --baseline flag of generate and rebuild
+var baselinePath string
+
+// runMetrics returns the value of every compare metric of report by name, as
+// recorded in the manifests of the exports for --baseline
+func runMetrics(report statsReport) map[string]float64 {
+	metrics := make(map[string]float64, len(compareMetrics))
+	for _, m := range compareMetrics {
+		metrics[m.name] = m.value(report)
+	}
+	return metrics
+}
+
+// loadBaselineMetrics reads the metrics recorded in the manifest of an
+// exported tree, or of a manifest saved on its own
+func loadBaselineMetrics(path string) (map[string]float64, error) {
+	r, closeFile, err := openExport(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	var file struct {
+		Manifest *exportManifest    `json:"manifest"`
+		Metrics  map[string]float64 `json:"metrics"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	metrics := file.Metrics
+	if file.Manifest != nil {
+		metrics = file.Manifest.Metrics
+	}
+	if metrics == nil {
+		return nil, fmt.Errorf("%s records no metrics, it wasn't exported by generate or rebuild --out, or by an older version", path)
+	}
+	return metrics, nil
+}
+
+// printBaselineChanges prints the metrics of stats differing from those of
+// the baseline, with their deltas. The metrics the baseline doesn't record
+// are left out.
+func printBaselineChanges(stats *arktree.Report, baseline map[string]float64) {
+	current := newStatsReport(stats, nil)
+	color := os.Getenv("NO_COLOR") == ""
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Printf("🔀 CHANGES FROM %s\n", baselinePath)
+	fmt.Println(strings.Repeat("─", 60))
+
+	t := newTable(os.Stdout, false, true, true, true)
+	t.row("Metric", "Baseline", "Current", "Delta")
+	changed := 0
+	for _, m := range compareMetrics {
+		before, ok := baseline[m.name]
+		after := m.value(current)
+		if !ok || before == after {
+			continue
+		}
+		t.row(m.name, formatMetric(before), formatMetric(after), m.delta(before, after, color))
+		changed++
+	}
+	if changed == 0 {
+		fmt.Println("No metric changed")
+		return
+	}
+	t.flush()
+}
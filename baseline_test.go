@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRunMetricsSurviveTheManifest(t *testing.T) {
+	metrics := runMetrics(newStatsReport(seededReport(t, 7, 1), nil))
+	encoded, err := json.Marshal(exportManifest{Metrics: metrics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest exportManifest
+	if err := json.Unmarshal(encoded, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range compareMetrics {
+		if value, ok := manifest.Metrics[m.name]; !ok || value != metrics[m.name] {
+			t.Errorf("%s is %v after decoding, expected %v", m.name, value, metrics[m.name])
+		}
+	}
+}
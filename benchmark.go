@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Benchmark parts of the tree analysis",
+}
+
+var benchmarkStatsCmd = &cobra.Command{
+	Use:   "stats [number-of-leaves]",
+	Short: "Compare serial and parallel branch statistics on the same tree",
+	Long:  `Build a tree with the specified number of leaves, compute the branch statistics both serially and in parallel, and report the speedup and whether both results are identical.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		numLeaves, err := strconv.Atoi(args[0])
+		if err != nil || numLeaves <= 0 {
+			fmt.Println("Error: Number of leaves must be a positive integer")
+			os.Exit(1)
+		}
+
+		fmt.Println("⏱️  Branch Statistics Benchmark")
+		fmt.Println("=" + strings.Repeat("=", 50))
+
+		fmt.Printf("🌿 Building tree with %d leaves... ", numLeaves)
+		generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: numLeaves})
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅")
+
+		fmt.Print("🐢 Serial statistics... ")
+		start := time.Now()
+		serialSizes, err := arktree.SizeOfBranches(generation.Tree)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to get size of branches: %s\n", err)
+			os.Exit(1)
+		}
+		serialWeights, err := arktree.WeightOfBranches(generation.Tree, false)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to get weight of branches: %s\n", err)
+			os.Exit(1)
+		}
+		serialElapsed := time.Since(start)
+		fmt.Printf("✅ (%s)\n", serialElapsed)
+
+		fmt.Printf("🐇 Parallel statistics (%d workers)... ", benchmarkWorkers)
+		start = time.Now()
+		parallelSizes, parallelWeights, err := arktree.BranchStatsParallel(generation.Tree, benchmarkWorkers)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to get branch statistics: %s\n", err)
+			os.Exit(1)
+		}
+		parallelElapsed := time.Since(start)
+		fmt.Printf("✅ (%s)\n", parallelElapsed)
+
+		// both paths order the branches by leaf txid
+		identical := equalInts(serialSizes, parallelSizes) &&
+			equalFloats(serialWeights, parallelWeights)
+
+		fmt.Println("\n" + strings.Repeat("─", 60))
+		fmt.Printf("🐢 Serial:                %s\n", serialElapsed)
+		fmt.Printf("🐇 Parallel:              %s\n", parallelElapsed)
+		fmt.Printf("🚀 Speedup:               %8.2fx\n", serialElapsed.Seconds()/parallelElapsed.Seconds())
+		if identical {
+			fmt.Println("✅ Results:               identical")
+		} else {
+			fmt.Println("❌ Results:               different")
+		}
+		fmt.Println(strings.Repeat("─", 60))
+
+		if !identical {
+			os.Exit(1)
+		}
+	},
+}
+
+var benchmarkBuildCmd = &cobra.Command{
+	Use:   "build [number-of-leaves...]",
+	Short: "Time the build of trees by size, for regression tracking",
+	Long: `Build a seeded tree --count times for each number of leaves and report the time of every build, in the format of go test -bench so that the runs of two commits can be compared with benchstat, or as JSON with --json, e.g. to graph the build time across commits in CI.
+
+Every build is of the same tree, leaves with random script bytes and a fixed seed, so that only the performance changes between runs. The throughput is given in txs built per second, to compare trees of different sizes. Repeat the builds with --count for statistically meaningful results, benchstat wants at least 5 runs.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sizes := make([]int, 0, len(args))
+		for _, arg := range args {
+			numLeaves, err := strconv.Atoi(arg)
+			if err != nil || numLeaves <= 0 {
+				fmt.Printf("Error: Number of leaves must be a positive integer, got %s\n", arg)
+				os.Exit(1)
+			}
+			sizes = append(sizes, numLeaves)
+		}
+		if benchmarkCount < 1 {
+			fmt.Printf("Error: --count must be positive, got %d\n", benchmarkCount)
+			os.Exit(1)
+		}
+
+		results := make([]buildBenchmark, 0, len(sizes))
+		for _, numLeaves := range sizes {
+			result, err := benchmarkBuild(numLeaves, benchmarkCount)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: tree of %d leaves: %s\n", numLeaves, err)
+				os.Exit(1)
+			}
+			results = append(results, result)
+		}
+
+		if err := writeBuildBenchmarks(os.Stdout, results, benchmarkJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var (
+	benchmarkWorkers int
+	benchmarkCount   int
+	benchmarkJSON    bool
+)
+
+func init() {
+	benchmarkStatsCmd.Flags().IntVar(&benchmarkWorkers, "workers", runtime.NumCPU(), "Number of workers for the parallel statistics")
+	benchmarkBuildCmd.Flags().IntVar(&benchmarkCount, "count", 5, "Number of builds of each tree")
+	benchmarkBuildCmd.Flags().BoolVar(&benchmarkJSON, "json", false, "Print the results as JSON instead of the go test -bench format")
+
+	benchmarkCmd.AddCommand(benchmarkStatsCmd)
+	benchmarkCmd.AddCommand(benchmarkBuildCmd)
+	rootCmd.AddCommand(benchmarkCmd)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// buildBenchmark is the time of the builds of a tree of a benchmark build run
+type buildBenchmark struct {
+	Leaves      int       `json:"leaves"`
+	Nodes       int       `json:"nodes"`
+	RunsNs      []float64 `json:"runs_ns"` // in build order
+	MeanNs      float64   `json:"mean_ns"`
+	MedianNs    float64   `json:"median_ns"`
+	StddevNs    float64   `json:"stddev_ns"`
+	NodesPerSec float64   `json:"nodes_per_sec"` // at the median build time
+}
+
+// buildBenchmarkReport is the document printed by benchmark build --json
+type buildBenchmarkReport struct {
+	GoVersion  string           `json:"go_version"`
+	ArkVersion string           `json:"ark_version"`
+	GOOS       string           `json:"goos"`
+	GOARCH     string           `json:"goarch"`
+	CPUs       int              `json:"cpus"`
+	Benchmarks []buildBenchmark `json:"benchmarks"`
+}
+
+// benchmarkBuild builds the seeded tree of numLeaves leaves count times,
+// timing each build. A first build, untimed, warms up the caches and the
+// allocator, which would make the first timed one an outlier.
+func benchmarkBuild(numLeaves, count int) (buildBenchmark, error) {
+	result := buildBenchmark{Leaves: numLeaves}
+	for i := range count + 1 {
+		seed := int64(1)
+		start := time.Now()
+		generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: numLeaves, RawScripts: true, Seed: &seed})
+		elapsed := time.Since(start)
+		if err != nil {
+			return buildBenchmark{}, err
+		}
+		if i == 0 {
+			if result.Nodes, err = arktree.NumberOfNodes(generation.Tree); err != nil {
+				return buildBenchmark{}, err
+			}
+			continue
+		}
+		result.RunsNs = append(result.RunsNs, float64(elapsed.Nanoseconds()))
+	}
+
+	result.MeanNs = arktree.CalculateAverageFloat(result.RunsNs)
+	result.MedianNs = arktree.CalculateMedianFloat(result.RunsNs)
+	result.StddevNs = arktree.CalculateStddevFloat(result.RunsNs)
+	result.NodesPerSec = float64(result.Nodes) / (result.MedianNs / 1e9)
+	return result, nil
+}
+
+// writeBuildBenchmarks writes results as JSON or in the go test -bench
+// format, a line per build under the configuration lines benchstat groups
+// the results by
+func writeBuildBenchmarks(w io.Writer, results []buildBenchmark, asJSON bool) error {
+	arkVersion, _ := arkDependencyVersion()
+	if asJSON {
+		return writeJSON(w, buildBenchmarkReport{
+			GoVersion:  runtime.Version(),
+			ArkVersion: arkVersion,
+			GOOS:       runtime.GOOS,
+			GOARCH:     runtime.GOARCH,
+			CPUs:       runtime.NumCPU(),
+			Benchmarks: results,
+		}, true, false)
+	}
+
+	if _, err := fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: github.com/louisinger/arktree\ngo: %s\nark: %s\n",
+		runtime.GOOS, runtime.GOARCH, runtime.Version(), arkVersion); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, ns := range result.RunsNs {
+			if _, err := fmt.Fprintf(w, "BenchmarkBuild/leaves=%d-%d\t1\t%.0f ns/op\t%.2f nodes/s\n",
+				result.Leaves, runtime.GOMAXPROCS(0), ns, float64(result.Nodes)/(ns/1e9)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
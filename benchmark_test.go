@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteBuildBenchmarks(t *testing.T) {
+	result, err := benchmarkBuild(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Nodes != 3 || len(result.RunsNs) != 3 || result.NodesPerSec <= 0 {
+		t.Fatalf("%d nodes, %d runs, %g nodes/s", result.Nodes, len(result.RunsNs), result.NodesPerSec)
+	}
+	var b strings.Builder
+	if err := writeBuildBenchmarks(&b, []buildBenchmark{result}, false); err != nil {
+		t.Fatal(err)
+	}
+	// a benchstat line per run
+	if lines := strings.Count(b.String(), "\nBenchmarkBuild/leaves=2-"); lines != 3 {
+		t.Errorf("%d benchmark lines in %q", lines, b.String())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var bestBranchCmd = &cobra.Command{
+	Use:   "best-branch [tree-file]",
+	Short: "Show the cheapest branches to exit of an exported tree",
+	Long: `Import a tree exported with "generate --out" and show the branch with the fewest transactions and the branch with the lowest broadcast weight, with their transactions in broadcast order, root first.
+
+Together with worst-branch, it brackets the unilateral exit cost of the users of the tree. Ties are broken by leaf txid, the smallest one winning.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		sizes, err := arktree.SizeOfBranches(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to get size of branches: %s\n", err)
+			os.Exit(1)
+		}
+
+		weights, err := arktree.WeightOfBranches(txtree, false)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to get weight of branches: %s\n", err)
+			os.Exit(1)
+		}
+
+		// sizes and weights follow arktree.LeafTxids order, so keeping the first
+		// minimum breaks ties by leaf txid
+		leaves := arktree.LeafTxids(txtree)
+		smallest, lightest := 0, 0
+		for i := range leaves {
+			if sizes[i] < sizes[smallest] {
+				smallest = i
+			}
+			if weights[i] < weights[lightest] {
+				lightest = i
+			}
+		}
+
+		if err := printBestBranch(txtree, "📏 SMALLEST BRANCH", leaves[smallest], sizes[smallest], weights[smallest]); err != nil {
+			fmt.Printf("❌ Error: Failed to extract branch: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+		if err := printBestBranch(txtree, "📡 LIGHTEST BRANCH", leaves[lightest], sizes[lightest], weights[lightest]); err != nil {
+			fmt.Printf("❌ Error: Failed to extract branch: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bestBranchCmd)
+}
+
+// printBestBranch prints the branch of leaf with its size, weight and broadcast order
+func printBestBranch(txtree *tree.TxGraph, title, leaf string, size int, weight float64) error {
+	branch, err := txtree.SubGraph([]string{leaf})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Println(title)
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("🍃 Leaf:                  %s\n", leaf)
+	fmt.Printf("📏 Branch Size:           %8d tx\n", size)
+	fmt.Printf("📡 Tx to Broadcast:       %8.2f\n", weight)
+	fmt.Println("\n🔗 Broadcast order:")
+	short := txidShortener(os.Stdout, broadcastOrder(txtree))
+	for i, node := range branchPath(branch) {
+		fmt.Printf("%3d. %s\n", i+1, short(node.Root.UnsignedTx.TxID()))
+	}
+	return nil
+}
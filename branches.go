@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/parquet-go/parquet-go"
+	"github.com/spf13/cobra"
+)
+
+const (
+	branchesFormatCSV     = "csv"
+	branchesFormatParquet = "parquet"
+	branchesFormatArrow   = "arrow"
+)
+
+var (
+	branchesFormat string
+	branchesOut    string
+)
+
+var branchesCmd = &cobra.Command{
+	Use:   "branches [tree-file]",
+	Short: "Export the statistics of each branch of an exported tree as CSV, Parquet or Arrow",
+	Long: `Import a tree exported with "generate --out" and write one row per branch, ordered by leaf txid: leaf_txid, branch_size, branch_weight, the exit fee at --feerate and the relative locktime to wait before its first exit tx can be broadcast, as exit_locktime and exit_locktime_type (block or second, both empty if the tree carries none) and as exit_delay_seconds, blocks lasting --block-interval.
+
+CSV is written to stdout unless --out is set, a row at a time as each branch is computed: memory doesn't grow with the rows and an interrupted export keeps the rows written so far. Parquet and Arrow, with typed columns (string, int32, double, int64), need --out. Arrow writes an IPC file of a single record batch that DuckDB or Polars load without conversion.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch branchesFormat {
+		case branchesFormatCSV:
+		case branchesFormatParquet, branchesFormatArrow:
+			if branchesOut == "" {
+				fmt.Printf("Error: --format %s needs --out\n", branchesFormat)
+				os.Exit(1)
+			}
+		default:
+			fmt.Printf("Error: unknown format %q (expected %s, %s or %s)\n", branchesFormat, branchesFormatCSV, branchesFormatParquet, branchesFormatArrow)
+			os.Exit(1)
+		}
+
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		// CSV rows are written as each branch is computed, Parquet and Arrow
+		// need them all to lay out their columns
+		write := func(w io.Writer) error {
+			return writeBranchesCSV(w, txtree, feerate, witnessModel(), blockInterval)
+		}
+		if branchesFormat != branchesFormatCSV {
+			rows, err := branchRows(txtree, feerate, witnessModel(), blockInterval)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to get branch statistics: %s\n", err)
+				os.Exit(1)
+			}
+			write = func(w io.Writer) error {
+				if branchesFormat == branchesFormatParquet {
+					return writeBranchesParquet(w, rows)
+				}
+				return writeBranchesArrow(w, rows)
+			}
+		}
+
+		if branchesOut == "" {
+			err = write(os.Stdout)
+		} else {
+			err = writeBranchesFile(branchesOut, write)
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to write branches: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	branchesCmd.Flags().StringVar(&branchesFormat, "format", branchesFormatCSV, "Format of the rows: csv, parquet or arrow")
+	branchesCmd.Flags().StringVar(&branchesOut, "out", "", "File the rows are written to, stdout if empty (csv only)")
+	branchesCmd.Flags().Float64Var(&feerate, "feerate", 1, "Feerate in sat/vB used to estimate exit fees")
+	addWitnessFlags(branchesCmd)
+	branchesCmd.Flags().DurationVar(&blockInterval, "block-interval", arktree.DefaultBlockInterval, "Time between two blocks used to convert a locktime in blocks to exit_delay_seconds")
+
+	rootCmd.AddCommand(branchesCmd)
+}
+
+// branchRow is the statistics of one branch, as a CSV record, a Parquet row or
+// an Arrow row
+type branchRow struct {
+	LeafTxid     string  `parquet:"leaf_txid"`
+	BranchSize   int32   `parquet:"branch_size"`
+	BranchWeight float64 `parquet:"branch_weight"`
+	Fee          int64   `parquet:"fee"`
+	// ExitLocktime is the relative locktime gating the first exit tx of the
+	// branch, in blocks or seconds as ExitLocktimeType says, 0 and "" without
+	ExitLocktime     int64  `parquet:"exit_locktime"`
+	ExitLocktimeType string `parquet:"exit_locktime_type"`
+	ExitDelay        int64  `parquet:"exit_delay_seconds"`
+}
+
+// streamBranchRows calls fn with the row of each branch of g ordered by leaf
+// txid, a block lasting blockInterval in their exit delay, computing each
+// branch only once the previous row was handled
+func streamBranchRows(g *tree.TxGraph, feerate float64, witness arktree.WitnessModel, blockInterval time.Duration, fn func(branchRow) error) error {
+	return arktree.StreamBranches(g, feerate, witness, func(branch arktree.BranchStats) error {
+		row := branchRow{
+			LeafTxid:     branch.LeafTxid,
+			BranchSize:   int32(branch.Size),
+			BranchWeight: branch.Weight,
+			Fee:          branch.Cost.Fee,
+		}
+		if expiry := branch.Expiry; expiry != nil {
+			row.ExitLocktime = int64(expiry.Value)
+			row.ExitLocktimeType = locktimeTypeName(*expiry)
+			row.ExitDelay = int64(arktree.LocktimeDuration(*expiry, blockInterval) / time.Second)
+		}
+		return fn(row)
+	})
+}
+
+// branchRows returns the rows of the branches of g, see streamBranchRows
+func branchRows(g *tree.TxGraph, feerate float64, witness arktree.WitnessModel, blockInterval time.Duration) ([]branchRow, error) {
+	var rows []branchRow
+	if err := streamBranchRows(g, feerate, witness, blockInterval, func(row branchRow) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func writeBranchesFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeBranchesCSV writes the rows of the branches of g as CSV, flushing each
+// row to w as soon as its branch is computed
+func writeBranchesCSV(w io.Writer, g *tree.TxGraph, feerate float64, witness arktree.WitnessModel, blockInterval time.Duration) error {
+	cw := csv.NewWriter(w)
+	writeRow := func(record []string) error {
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if err := writeRow([]string{"leaf_txid", "branch_size", "branch_weight", "fee", "exit_locktime", "exit_locktime_type", "exit_delay_seconds"}); err != nil {
+		return err
+	}
+	return streamBranchRows(g, feerate, witness, blockInterval, func(row branchRow) error {
+		return writeRow([]string{
+			row.LeafTxid,
+			strconv.Itoa(int(row.BranchSize)),
+			strconv.FormatFloat(row.BranchWeight, 'f', -1, 64),
+			strconv.FormatInt(row.Fee, 10),
+			strconv.FormatInt(row.ExitLocktime, 10),
+			row.ExitLocktimeType,
+			strconv.FormatInt(row.ExitDelay, 10),
+		})
+	})
+}
+
+func writeBranchesParquet(w io.Writer, rows []branchRow) error {
+	pw := parquet.NewGenericWriter[branchRow](w)
+	if _, err := pw.Write(rows); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// writeBranchesArrow writes rows as an Arrow IPC file with the columns of the
+// Parquet output, none nullable
+func writeBranchesArrow(w io.Writer, rows []branchRow) error {
+	var (
+		txids     = make([]string, 0, len(rows))
+		sizes     = make([]int32, 0, len(rows))
+		weights   = make([]float64, 0, len(rows))
+		fees      = make([]int64, 0, len(rows))
+		locktimes = make([]int64, 0, len(rows))
+		types     = make([]string, 0, len(rows))
+		delays    = make([]int64, 0, len(rows))
+	)
+	for _, row := range rows {
+		txids = append(txids, row.LeafTxid)
+		sizes = append(sizes, row.BranchSize)
+		weights = append(weights, row.BranchWeight)
+		fees = append(fees, row.Fee)
+		locktimes = append(locktimes, row.ExitLocktime)
+		types = append(types, row.ExitLocktimeType)
+		delays = append(delays, row.ExitDelay)
+	}
+	return writeArrowFile(w, len(rows), []arrowColumn{
+		utf8Column("leaf_txid", txids),
+		int32Column("branch_size", sizes),
+		float64Column("branch_weight", weights),
+		int64Column("fee", fees),
+		int64Column("exit_locktime", locktimes),
+		utf8Column("exit_locktime_type", types),
+		int64Column("exit_delay_seconds", delays),
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestWriteBranchesCSVStreamsRows(t *testing.T) {
+	txtree := seededTree(t, 7, 1).Tree
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBranchesCSV(pw, txtree, 1, arktree.DefaultWitnessModel, arktree.DefaultBlockInterval))
+	}()
+	// each write to the pipe is read whole, so a read holding more than a
+	// line means the rows were buffered
+	buf := make([]byte, 64<<10)
+	var lines []string
+	for {
+		n, err := pr.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := string(buf[:n])
+		if strings.Count(chunk, "\n") != 1 || !strings.HasSuffix(chunk, "\n") {
+			t.Fatalf("read %q, expected a single row", chunk)
+		}
+		lines = append(lines, chunk)
+	}
+	leaves := arktree.LeafTxids(txtree)
+	if len(lines) != len(leaves)+1 {
+		t.Fatalf("%d lines for %d leaves", len(lines), len(leaves))
+	}
+	for i, txid := range leaves {
+		if !strings.HasPrefix(lines[i+1], txid+",") {
+			t.Errorf("row %d is %q, expected leaf %s", i, lines[i+1], txid)
+		}
+	}
+}
+
+func TestWriteBranchesArrow(t *testing.T) {
+	rows, err := branchRows(seededTree(t, 9, 2).Tree, 1, arktree.DefaultWitnessModel, arktree.DefaultBlockInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := writeBranchesArrow(&b, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is read back by the reference implementation
+	reader, err := ipc.NewFileReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "leaf_txid", Type: arrow.BinaryTypes.String},
+		{Name: "branch_size", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "branch_weight", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "fee", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "exit_locktime", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "exit_locktime_type", Type: arrow.BinaryTypes.String},
+		{Name: "exit_delay_seconds", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	if !reader.Schema().Equal(schema) {
+		t.Fatalf("schema %s, expected %s", reader.Schema(), schema)
+	}
+	if reader.NumRecords() != 1 {
+		t.Fatalf("%d record batches, expected 1", reader.NumRecords())
+	}
+	record, err := reader.Record(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(record.NumRows()) != len(rows) {
+		t.Fatalf("%d rows, expected %d", record.NumRows(), len(rows))
+	}
+
+	for i, want := range rows {
+		got := branchRow{
+			LeafTxid:         record.Column(0).(*array.String).Value(i),
+			BranchSize:       record.Column(1).(*array.Int32).Value(i),
+			BranchWeight:     record.Column(2).(*array.Float64).Value(i),
+			Fee:              record.Column(3).(*array.Int64).Value(i),
+			ExitLocktime:     record.Column(4).(*array.Int64).Value(i),
+			ExitLocktimeType: record.Column(5).(*array.String).Value(i),
+			ExitDelay:        record.Column(6).(*array.Int64).Value(i),
+		}
+		if got != want {
+			t.Errorf("row %d: %+v, expected %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// branchIncludeRoot is the --branch-include-root flag of the statistics
+var branchIncludeRoot bool
+
+// sharedBranchTxs returns the number of txs on every branch of g, which every
+// exit broadcasts: the root and, down from it, each tx that is the only child
+// of the previous one
+func sharedBranchTxs(g *tree.TxGraph) int {
+	shared := 1
+	for len(g.Children) == 1 {
+		for _, child := range g.Children {
+			g = child
+		}
+		shared++
+	}
+	return shared
+}
+
+// excludedBranchTxs returns the txs left out of every branch size of stats as
+// reported, the shared ones with --branch-include-root=false, none otherwise
+func excludedBranchTxs(stats *arktree.Report) int {
+	if branchIncludeRoot || stats.Tree == nil {
+		return 0
+	}
+	return sharedBranchTxs(stats.Tree)
+}
+
+// reportedBranchSizes returns the branch sizes of stats as reported, without
+// the excludedBranchTxs. The statistics derived from the branch sizes, such as
+// the balance or the exit times, always count every tx.
+func reportedBranchSizes(stats *arktree.Report) []int {
+	return stats.ReportedBranchSizes(excludedBranchTxs(stats))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestReportedBranchSizes(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *arktree.Report) {
+		if sizes := reportedBranchSizes(stats); !slices.Equal(sizes, stats.BranchSizes) {
+			t.Errorf("branch sizes %v by default, expected %v", sizes, stats.BranchSizes)
+		}
+
+		setFlag(t, &branchIncludeRoot, false)
+		// the builder splits the root unless it is the only leaf
+		shared := 1
+		if excluded := excludedBranchTxs(stats); excluded != shared {
+			t.Errorf("%d shared txs excluded, expected %d", excluded, shared)
+		}
+		sizes := reportedBranchSizes(stats)
+		for i, size := range sizes {
+			if size != stats.BranchSizes[i]-shared {
+				t.Errorf("branch %d of size %d, expected %d", i, size, stats.BranchSizes[i]-shared)
+			}
+		}
+		report := newStatsReport(stats, nil)
+		if report.ExcludedBranchTxs != shared || report.Branches[0].Size != sizes[0] {
+			t.Errorf("report excludes %d txs with a first branch of %d", report.ExcludedBranchTxs, report.Branches[0].Size)
+		}
+		if depth := newCompactStatsReport(report, stats).Depth; depth != stats.BiggestBranch() {
+			t.Errorf("compact depth %d, expected %d", depth, stats.BiggestBranch())
+		}
+	})
+}
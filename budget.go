@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// timeBudget is the --time-budget flag of generate
+var timeBudget time.Duration
+
+// budgetFirstProbe is the number of leaves of the first tree built with
+// --time-budget, each next probe doubling it
+const budgetFirstProbe = 16
+
+// budgetProbe is a tree built while probing --time-budget
+type budgetProbe struct {
+	leaves  int
+	elapsed time.Duration
+}
+
+// predictBuildTime extrapolates the build time of a tree of leaves leaves
+// from the last probes, assuming it grows as leaves^k: k is fitted on the two
+// last probes, at least 1 as a build never gets cheaper per leaf and 1 with a
+// single probe
+func predictBuildTime(probes []budgetProbe, leaves int) time.Duration {
+	last := probes[len(probes)-1]
+	exponent := 1.0
+	if len(probes) > 1 {
+		prev := probes[len(probes)-2]
+		if prev.elapsed > 0 && last.elapsed > prev.elapsed {
+			exponent = max(1, math.Log(float64(last.elapsed)/float64(prev.elapsed))/math.Log(float64(last.leaves)/float64(prev.leaves)))
+		}
+	}
+	return time.Duration(float64(last.elapsed) * math.Pow(float64(leaves)/float64(last.leaves), exponent))
+}
+
+// generateWithinBudget builds trees with opts of doubling numbers of leaves,
+// as long as the next one is predicted to complete before all the builds
+// together exceed budget, and returns the last tree built. Each probe is
+// reported on out.
+func generateWithinBudget(out io.Writer, opts arktree.GenerateOptions, budget time.Duration) (*arktree.Generation, error) {
+	var (
+		probes     []budgetProbe
+		generation *arktree.Generation
+		spent      time.Duration
+	)
+	for leaves := budgetFirstProbe; ; leaves *= 2 {
+		opts.NumLeaves = leaves
+		next, err := arktree.Generate(opts)
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", leaves, err)
+		}
+		elapsed := next.Timings[len(next.Timings)-1].Elapsed
+		spent += elapsed
+		if generation == nil && spent > budget {
+			return nil, fmt.Errorf("the smallest tree, of %d leaves, took %s, over the %s budget", leaves, elapsed, budget)
+		}
+		generation = next
+		probes = append(probes, budgetProbe{leaves: leaves, elapsed: elapsed})
+		fmt.Fprintf(out, "   %d leaves built in %s\n", leaves, elapsed)
+
+		predicted := predictBuildTime(probes, 2*leaves)
+		if spent+predicted > budget {
+			fmt.Fprintf(out, "⏳ %d leaves would take about %s, %s of the %s budget left\n", 2*leaves, predicted.Round(time.Millisecond), (budget - spent).Round(time.Millisecond), budget)
+			return generation, nil
+		}
+	}
+}
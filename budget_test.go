@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPredictBuildTime(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		probes []budgetProbe
+		leaves int
+		want   time.Duration
+	}{
+		{"linear from a single probe", []budgetProbe{{16, time.Second}}, 32, 2 * time.Second},
+		{"quadratic from the last probes", []budgetProbe{{16, time.Second}, {32, 4 * time.Second}}, 64, 16 * time.Second},
+		{"at least linear", []budgetProbe{{16, time.Second}, {32, time.Second}}, 64, 2 * time.Second},
+	} {
+		if predicted := predictBuildTime(test.probes, test.leaves); predicted.Round(time.Millisecond) != test.want {
+			t.Errorf("%s: %d leaves predicted in %s, expected %s", test.name, test.leaves, predicted, test.want)
+		}
+	}
+}
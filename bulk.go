@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var bulkCmd = &cobra.Command{
+	Use:   "bulk [number-of-trees] [number-of-leaves]",
+	Short: "Generate many trees of the same size and write their pooled statistics",
+	Long: `Generate the given number of trees with the given number of leaves each, as fast as possible, and write the statistics of all their branches pooled in a single JSON file.
+
+The random source and the buffers are reused from one tree to the next, and only the branch sizes and weights are computed. The file has the fields of "generate --output json" that make sense over several trees, so it can be passed to "aggregate".`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		numTrees, err := strconv.Atoi(args[0])
+		if err != nil || numTrees <= 0 {
+			fmt.Printf("Error: Invalid number of trees: %s\n", args[0])
+			os.Exit(1)
+		}
+		numLeaves, err := strconv.Atoi(args[1])
+		if err != nil || numLeaves <= 0 {
+			fmt.Printf("Error: Invalid number of leaves: %s\n", args[1])
+			os.Exit(1)
+		}
+
+		fmt.Println("🏭 Bulk Ark Tree Generator")
+		fmt.Println("=" + strings.Repeat("=", 50))
+		fmt.Printf("📊 Generating %d trees with %d leaves... ", numTrees, numLeaves)
+
+		start := time.Now()
+		report, err := generateBulk(numTrees, numLeaves, arktree.RandomSource(bulkSeed, cmd.Flags().Changed("seed")))
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		elapsed := time.Since(start)
+		fmt.Println("✅")
+
+		report.WallTimeMs = float64(elapsed.Microseconds()) / 1000
+		report.TreesPerSecond = float64(numTrees) / elapsed.Seconds()
+
+		fmt.Printf("💾 Writing statistics to %s... ", bulkOut)
+		if err := writeBulkReport(bulkOut, report); err != nil {
+			fmt.Printf("\n❌ Error: Failed to write statistics: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅")
+
+		fmt.Println("\n" + strings.Repeat("─", 60))
+		t := newTable(os.Stdout, false, true)
+		t.row("🌳 Trees:", strconv.Itoa(report.Trees))
+		t.row("🌳 Total Transactions:", strconv.Itoa(report.TotalTransactions))
+		t.row("⏱️ Wall Time:", elapsed.Round(time.Millisecond).String())
+		t.rowf("🚀 Trees per Second:", "%.1f", report.TreesPerSecond)
+		t.flush()
+		fmt.Println(strings.Repeat("─", 60))
+	},
+}
+
+var (
+	bulkOut  string
+	bulkSeed int64
+)
+
+func init() {
+	bulkCmd.Flags().StringVar(&bulkOut, "out", "bulk.json", "File the pooled statistics are written to")
+	bulkCmd.Flags().Int64Var(&bulkSeed, "seed", 0, "Seed the random source shared by all the trees for a reproducible run")
+
+	rootCmd.AddCommand(bulkCmd)
+}
+
+// bulkReport holds the statistics of the branches of all the trees of a bulk
+// run, its fields named as in statsReport
+type bulkReport struct {
+	SchemaVersion     int                  `json:"schema_version"`
+	Trees             int                  `json:"trees"`
+	Leaves            int                  `json:"leaves"`
+	TotalTransactions int                  `json:"total_transactions"`
+	BranchSizes       arktree.Distribution `json:"branch_sizes"`
+	BroadcastWeights  arktree.Distribution `json:"broadcast_weights"`
+	WallTimeMs        float64              `json:"wall_time_ms"`
+	TreesPerSecond    float64              `json:"trees_per_second"`
+}
+
+// generateBulk builds numTrees trees of numLeaves leaves from rnd and pools
+// the sizes and weights of all their branches
+func generateBulk(numTrees, numLeaves int, rnd io.Reader) (*bulkReport, error) {
+	sweepTreeRoot := make([]byte, 32)
+	rootTxid := make([]byte, 32)
+	sizes := make([]int, 0, numTrees*numLeaves)
+	weights := make([]float64, 0, numTrees*numLeaves)
+
+	report := &bulkReport{SchemaVersion: statsSchemaVersion, Trees: numTrees}
+	for i := 0; i < numTrees; i++ {
+		if _, err := io.ReadFull(rnd, sweepTreeRoot); err != nil {
+			return nil, fmt.Errorf("tree %d: %w", i, err)
+		}
+		if _, err := io.ReadFull(rnd, rootTxid); err != nil {
+			return nil, fmt.Errorf("tree %d: %w", i, err)
+		}
+
+		leaves, err := arktree.GenerateLeaves(numLeaves, arktree.DefaultLeafAmount, 0, false, rnd, nil)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %w", i, err)
+		}
+
+		txtree, err := arktree.BuildTree(leaves, sweepTreeRoot, rootTxid, arktree.DefaultLocktime)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: failed to build tree: %w", i, err)
+		}
+
+		totalSize, err := arktree.NumberOfNodes(txtree)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %w", i, err)
+		}
+		branchSizes, branchWeights, err := arktree.BranchStatsParallel(txtree, 1)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d: %w", i, err)
+		}
+
+		sizes = append(sizes, branchSizes...)
+		weights = append(weights, branchWeights...)
+		report.Leaves += len(branchSizes)
+		report.TotalTransactions += totalSize
+	}
+
+	report.BranchSizes = arktree.NewSizeDistribution(sizes)
+	report.BroadcastWeights = arktree.NewDistribution(weights)
+	return report, nil
+}
+
+func writeBulkReport(path string, report *bulkReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// colorBySubtreeSize is the --color-by shading the nodes of the diagrams by
+// the number of leaves under them
+const colorBySubtreeSize = "subtree-size"
+
+// colorBy is the --color-by flag of generate, empty for uniform nodes
+var colorBy string
+
+// validateColorBy checks --color-by, which colors the dot and html diagrams
+// only
+func validateColorBy(value string) error {
+	switch value {
+	case "":
+		return nil
+	case colorBySubtreeSize:
+		if !hasOutput(outputDOT) && !hasOutput(outputHTML) {
+			return errors.New("--color-by requires --output dot or html")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown --color-by %q (expected %s)", value, colorBySubtreeSize)
+	}
+}
+
+// rgb is a color of the gradients of the diagrams
+type rgb struct{ r, g, b float64 }
+
+var (
+	// subtreeGradient goes from light yellow to dark red, grayGradient from
+	// light to dark gray with colors disabled
+	subtreeGradient = [2]rgb{{255, 255, 204}, {189, 0, 38}}
+	grayGradient    = [2]rgb{{240, 240, 240}, {37, 37, 37}}
+)
+
+// gradientColor returns the hex color at fraction, between 0 and 1, of
+// gradient
+func gradientColor(gradient [2]rgb, fraction float64) string {
+	from, to := gradient[0], gradient[1]
+	mix := func(a, b float64) int { return int(math.Round(a + (b-a)*fraction)) }
+	return fmt.Sprintf("#%02x%02x%02x", mix(from.r, to.r), mix(from.g, to.g), mix(from.b, to.b))
+}
+
+// subtreeSizeFills returns the fill color of every node of g by the number
+// of leaves under it, on a log scale from a leaf, the lightest, to the root,
+// the darkest: each level of a balanced tree gets its own shade. color false
+// gives shades of gray.
+func subtreeSizeFills(g *tree.TxGraph, color bool) map[*tree.TxGraph]string {
+	gradient := subtreeGradient
+	if !color {
+		gradient = grayGradient
+	}
+
+	leaves := arktree.LeavesUnder(g)
+	total := math.Log2(float64(leaves[g]))
+	fills := make(map[*tree.TxGraph]string, len(leaves))
+	for node, count := range leaves {
+		fraction := 1.0
+		if total > 0 {
+			fraction = math.Log2(float64(count)) / total
+		}
+		fills[node] = gradientColor(gradient, fraction)
+	}
+	return fills
+}
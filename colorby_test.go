@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestSubtreeSizeFills(t *testing.T) {
+	txtree := seededTree(t, 7, 1).Tree
+	nodes, err := arktree.NumberOfNodes(txtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for color, gradient := range map[bool][2]rgb{true: subtreeGradient, false: grayGradient} {
+		fills := subtreeSizeFills(txtree, color)
+		lightest, darkest := gradientColor(gradient, 0), gradientColor(gradient, 1)
+		if fills[txtree] != darkest {
+			t.Errorf("root filled with %s, expected %s", fills[txtree], darkest)
+		}
+		for _, leaf := range txtree.Leaves() {
+			if node := txtree.Find(leaf.UnsignedTx.TxID()); fills[node] != lightest {
+				t.Errorf("leaf filled with %s, expected %s", fills[node], lightest)
+			}
+		}
+		var b strings.Builder
+		if _, err := writeDOT(&b, txtree, "", fills); err != nil {
+			t.Fatal(err)
+		}
+		if filled := strings.Count(b.String(), "style=filled"); filled != nodes {
+			t.Errorf("%d filled nodes for %d txs", filled, nodes)
+		}
+	}
+}
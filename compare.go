@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	colorGreen = "\x1b[32m"
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+var noColor bool
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [before-stats-file] [after-stats-file]",
+	Short: "Compare the statistics of two runs side by side",
+	Long: `Read two statistics files written with "generate --output json" and print their metrics side by side with the delta from the first to the second.
+
+Deltas are green when the second run is better, red when it is worse: lower is better for the depth, branch sizes, tx to broadcast, amortization and size on wire, higher for the balance. The other metrics are left uncolored.
+Colors are disabled with --no-color or the NO_COLOR environment variable.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var reports [2]statsReport
+		for i, path := range args {
+			report, err := loadStatsReport(path)
+			if err != nil {
+				fmt.Printf("❌ Error: %s: %s\n", path, err)
+				os.Exit(1)
+			}
+			reports[i] = report
+		}
+
+		color := !noColor && os.Getenv("NO_COLOR") == ""
+
+		fmt.Println("\n" + strings.Repeat("─", 60))
+		fmt.Println("🔀 COMPARISON")
+		fmt.Println(strings.Repeat("─", 60))
+
+		t := newTable(os.Stdout, false, true, true, true)
+		t.row("Metric", "Before", "After", "Delta")
+		for _, m := range compareMetrics {
+			before, after := m.value(reports[0]), m.value(reports[1])
+			t.row(m.name, formatMetric(before), formatMetric(after), m.delta(before, after, color))
+		}
+		t.flush()
+
+		fmt.Println(strings.Repeat("─", 60))
+		if color {
+			fmt.Printf("Legend: %simprovement%s, %sregression%s, uncolored metrics have no better direction\n",
+				colorGreen, colorReset, colorRed, colorReset)
+		} else {
+			fmt.Println("Legend: (+) improvement, (-) regression, unmarked metrics have no better direction")
+		}
+	},
+}
+
+func init() {
+	compareCmd.Flags().BoolVar(&noColor, "no-color", false, "Print the deltas without colors, marking improvements (+) and regressions (-)")
+
+	rootCmd.AddCommand(compareCmd)
+}
+
+// better is the direction in which a metric improves
+type better int
+
+const (
+	neither better = iota
+	lower
+	higher
+)
+
+// compareMetric is a row of the comparison
+type compareMetric struct {
+	name   string
+	better better
+	value  func(r statsReport) float64
+}
+
+var compareMetrics = []compareMetric{
+	{"Leaves", neither, func(r statsReport) float64 { return float64(r.Leaves) }},
+	{"Total transactions", neither, func(r statsReport) float64 { return float64(r.TotalTransactions) }},
+	{"Biggest branch", lower, func(r statsReport) float64 { return r.BranchSizes.Max }},
+	{"Mean branch size", lower, func(r statsReport) float64 { return r.BranchSizes.Mean }},
+	{"Most tx to broadcast", lower, func(r statsReport) float64 { return r.BroadcastWeights.Max }},
+	{"Mean tx to broadcast", lower, func(r statsReport) float64 { return r.BroadcastWeights.Mean }},
+	{"Balance", higher, func(r statsReport) float64 { return r.Balance }},
+	{"Amortization", lower, func(r statsReport) float64 { return r.Amortization }},
+	{"Branching factor", neither, func(r statsReport) float64 { return r.BranchingFactor }},
+	{"Size on wire", lower, func(r statsReport) float64 { return float64(r.SizeOnWire) }},
+	{"Storage per VTXO", lower, func(r statsReport) float64 { return r.StoragePerVtxo.Total }},
+	{"Total value", neither, func(r statsReport) float64 { return float64(r.TotalValue) }},
+}
+
+// delta formats after - before, colored (or marked without color) when the
+// metric has a better direction and changed
+func (m compareMetric) delta(before, after float64, color bool) string {
+	diff := after - before
+	text := formatMetric(diff)
+	if diff > 0 {
+		text = "+" + text
+	}
+	if before != 0 {
+		text += fmt.Sprintf(" (%+.1f%%)", diff/before*100)
+	}
+
+	if diff == 0 || m.better == neither {
+		return text
+	}
+	improved := (diff < 0) == (m.better == lower)
+	switch {
+	case color && improved:
+		return colorGreen + text + colorReset
+	case color:
+		return colorRed + text + colorReset
+	case improved:
+		return text + " (+)"
+	default:
+		return text + " (-)"
+	}
+}
+
+// formatMetric prints integers without decimals and the others with 2
+func formatMetric(v float64) string {
+	if v == float64(int64(v)) {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+// compatSeed and compatLeaves describe the trees of the compat golden, small
+// enough to build in a fraction of a second, odd counts giving unbalanced trees
+const compatSeed = 1
+
+var compatLeaves = []int{1, 2, 3, 5, 8, 16, 33}
+
+var (
+	compatWrite  bool
+	compatArkRef string
+)
+
+var compatCmd = &cobra.Command{
+	Use:   "compat [golden-file]",
+	Short: "Compare the trees of the linked ark version with a golden recorded by another one",
+	Long: `Build a fixed set of seeded trees with the linked ark version and compare their root txids, checksums and statistics with a golden file recorded by another ark version, to surface behavioral changes of the tree builder. It exits with status 1 if any tree differs.
+
+A binary links a single version of the ark module, which the go.mod of arktree pins, so the two versions can't be built side by side. Instead, record the golden with --write from a build of the reference version, then run compat on it from a build of the new one:
+
+  arktree compat --write golden.json      # built with the reference ark version
+  arktree compat golden.json --ark-ref v1 # built with the new one
+
+--ark-ref fails if the golden wasn't recorded with that ark version, not to compare with the wrong reference.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if compatWrite {
+			if compatArkRef != "" {
+				fmt.Printf("Error: --ark-ref can't be used with --write, the golden records the linked ark version\n")
+				os.Exit(1)
+			}
+			golden, err := buildCompatGolden()
+			if err != nil {
+				fmt.Printf("❌ Error: %s\n", err)
+				os.Exit(1)
+			}
+			if err := writeCompatGolden(args[0], golden); err != nil {
+				fmt.Printf("❌ Error: Failed to write golden: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("💾 Wrote the golden of %d trees built with ark %s to %s\n", len(golden.Trees), golden.ArkVersion, args[0])
+			return
+		}
+
+		reference, err := readCompatGolden(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to read golden: %s\n", err)
+			os.Exit(1)
+		}
+		if compatArkRef != "" && reference.ArkVersion != compatArkRef {
+			fmt.Printf("Error: %s was recorded with ark %s, not %s\n", args[0], reference.ArkVersion, compatArkRef)
+			os.Exit(1)
+		}
+		current, err := buildCompatGolden()
+		if err != nil {
+			fmt.Printf("❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n🔬 ARK %s AGAINST %s:\n", current.ArkVersion, reference.ArkVersion)
+		fmt.Println(strings.Repeat("─", 60))
+		differences := diffCompatGoldens(os.Stdout, reference, current)
+		fmt.Println(strings.Repeat("─", 60))
+		if differences > 0 {
+			fmt.Printf("❌ %d difference(s) in the trees built\n", differences)
+			os.Exit(1)
+		}
+		fmt.Println("✅ The trees built are identical")
+	},
+}
+
+func init() {
+	compatCmd.Flags().BoolVar(&compatWrite, "write", false, "Write the golden of the linked ark version to the file instead of comparing with it")
+	compatCmd.Flags().StringVar(&compatArkRef, "ark-ref", "", "Ark version the golden must have been recorded with")
+
+	rootCmd.AddCommand(compatCmd)
+}
+
+// compatGolden is the file format of the compat golden: the trees of
+// compatLeaves built with compatSeed by an ark version
+type compatGolden struct {
+	ArkVersion string       `json:"ark_version"`
+	Seed       int64        `json:"seed"`
+	Trees      []compatTree `json:"trees"`
+}
+
+// compatTree is a tree of the golden, its metrics being the compare metrics
+// by name as in the export manifests
+type compatTree struct {
+	Leaves   int                `json:"leaves"`
+	RootTxid string             `json:"root_txid"`
+	Checksum string             `json:"checksum"`
+	Metrics  map[string]float64 `json:"metrics"`
+}
+
+// buildCompatGolden builds the trees of the golden with the linked ark version
+func buildCompatGolden() (*compatGolden, error) {
+	arkVersion, _ := arkDependencyVersion()
+	golden := &compatGolden{ArkVersion: arkVersion, Seed: compatSeed}
+	for _, leaves := range compatLeaves {
+		seed := int64(compatSeed)
+		generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: leaves, Seed: &seed})
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", leaves, err)
+		}
+		stats, err := arktree.Analyze(generation.Tree, arktree.AnalyzeOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", leaves, err)
+		}
+		golden.Trees = append(golden.Trees, compatTree{
+			Leaves:   leaves,
+			RootTxid: generation.Tree.Root.UnsignedTx.TxID(),
+			Checksum: treeChecksum(generation.Tree),
+			Metrics:  runMetrics(newStatsReport(stats, nil)),
+		})
+	}
+	return golden, nil
+}
+
+func writeCompatGolden(path string, golden *compatGolden) error {
+	data, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func readCompatGolden(path string) (*compatGolden, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var golden compatGolden
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("invalid golden: %w", err)
+	}
+	if golden.Seed != compatSeed {
+		return nil, fmt.Errorf("golden built with seed %d, expected %d", golden.Seed, compatSeed)
+	}
+	return &golden, nil
+}
+
+// diffCompatGoldens writes a line per tree of reference to w, with the
+// differences of the same tree of current, and returns their number. A tree
+// missing from current counts as a difference.
+func diffCompatGoldens(w io.Writer, reference, current *compatGolden) int {
+	built := make(map[int]compatTree, len(current.Trees))
+	for _, tree := range current.Trees {
+		built[tree.Leaves] = tree
+	}
+
+	differences := 0
+	for _, want := range reference.Trees {
+		got, ok := built[want.Leaves]
+		if !ok {
+			fmt.Fprintf(w, "❌ %d leaves: not built\n", want.Leaves)
+			differences++
+			continue
+		}
+
+		var diffs []string
+		if got.RootTxid != want.RootTxid {
+			diffs = append(diffs, fmt.Sprintf("root txid %s, was %s", got.RootTxid, want.RootTxid))
+		}
+		if got.Checksum != want.Checksum {
+			diffs = append(diffs, fmt.Sprintf("checksum %s, was %s", got.Checksum, want.Checksum))
+		}
+		for _, m := range compareMetrics {
+			before, ok := want.Metrics[m.name]
+			if after := got.Metrics[m.name]; ok && before != after {
+				diffs = append(diffs, fmt.Sprintf("%s %s, was %s", m.name, formatMetric(after), formatMetric(before)))
+			}
+		}
+
+		if len(diffs) == 0 {
+			fmt.Fprintf(w, "✅ %d leaves: identical\n", want.Leaves)
+			continue
+		}
+		fmt.Fprintf(w, "❌ %d leaves:\n", want.Leaves)
+		for _, diff := range diffs {
+			fmt.Fprintf(w, "   %s\n", diff)
+		}
+		differences += len(diffs)
+	}
+	return differences
+}
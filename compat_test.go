@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffCompatGoldens(t *testing.T) {
+	golden, err := buildCompatGolden()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(golden.Trees) != len(compatLeaves) {
+		t.Fatalf("%d trees for %d leaf counts", len(golden.Trees), len(compatLeaves))
+	}
+	if differences := diffCompatGoldens(io.Discard, golden, golden); differences != 0 {
+		t.Errorf("%d differences with itself", differences)
+	}
+	changed := *golden
+	changed.Trees = slices.Clone(golden.Trees)
+	changed.Trees[0].RootTxid = strings.Repeat("0", 64)
+	if differences := diffCompatGoldens(io.Discard, golden, &changed); differences != 1 {
+		t.Errorf("%d differences for a changed root txid, expected 1", differences)
+	}
+}
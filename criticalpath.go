@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var criticalPathCmd = &cobra.Command{
+	Use:   "critical-path [tree-file]",
+	Short: "Print the root-to-leaf chain of an exported tree with the biggest summed vsize",
+	Long: `Import a tree exported with "generate --out" and print its critical path: the root-to-leaf chain whose txs have the biggest summed vsize, with that vsize and the fee of broadcasting it at --feerate.
+
+It is the worst-case exit in bytes, which isn't always the deepest branch: a shallower branch with bigger txs can cost more.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		path, err := arktree.FindCriticalPath(txtree, witnessModel())
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to find the critical path: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("\n🛤️ CRITICAL PATH (root first):")
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, true, false, true)
+		short := txidShortener(os.Stdout, broadcastOrder(txtree))
+		for i, txid := range path.Txids {
+			t.row(strconv.Itoa(i+1), short(txid), strconv.Itoa(path.Vsizes[i]), "vB")
+		}
+		t.flush()
+
+		fmt.Println(strings.Repeat("─", 40))
+		t = newTable(os.Stdout, false, true)
+		t.row("Transactions:", strconv.Itoa(len(path.Txids)), fmt.Sprintf("tx (deepest branch: %d)", arktree.TreeDepth(txtree)))
+		t.row("Total vsize:", strconv.Itoa(path.Vsize), "vB")
+		t.row("Estimated fee:", strconv.FormatInt(path.Fee(feerate), 10), fmt.Sprintf("sats at %g sat/vB", feerate))
+		t.flush()
+	},
+}
+
+func init() {
+	criticalPathCmd.Flags().Float64Var(&feerate, "feerate", 1, "Feerate in sat/vB used to estimate the fee of the path")
+	addWitnessFlags(criticalPathCmd)
+
+	rootCmd.AddCommand(criticalPathCmd)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depthTableMax    int
+	depthTableFactor float64
+	depthTableBuilt  int
+)
+
+var depthTableCmd = &cobra.Command{
+	Use:   "depth-table",
+	Short: "Print the tree depth for a geometric series of leaf counts",
+	Long: `Build a tree for each leaf count of a geometric series, 1 then multiplied by --factor up to --max, and print its depth: the number of txs confirmed one after the other by the deepest exit, with the time it takes at --block-interval per block.
+
+The depth of the trees of up to --build-limit leaves is measured and checked against the ceil(log2 N) + 1 of the balanced binary trees BuildVtxoTree builds. The build time grows faster than the leaf count, a tree of 2000 leaves taking about a minute, so the depth of the bigger trees is derived from that formula instead. The trees are built with random script bytes, see --raw-scripts, as the depth doesn't depend on the scripts.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if depthTableMax < 1 {
+			fmt.Printf("Error: --max must be positive, got %d\n", depthTableMax)
+			os.Exit(1)
+		}
+		if depthTableFactor <= 1 {
+			fmt.Printf("Error: --factor must be greater than 1, got %g\n", depthTableFactor)
+			os.Exit(1)
+		}
+		if blockInterval <= 0 {
+			fmt.Printf("Error: --block-interval must be positive, got %s\n", blockInterval)
+			os.Exit(1)
+		}
+
+		fmt.Println("\n📏 DEPTH BY LEAF COUNT:")
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, true, true, false, true)
+		t.row("leaves", "depth", "source", "exit time")
+		exitTime := func(depth int) string { return (time.Duration(depth) * blockInterval).String() }
+
+		mismatches := 0
+		for _, numLeaves := range geometricSeries(depthTableMax, depthTableFactor) {
+			predicted, _ := arktree.BranchSizeBounds(numLeaves)
+			if numLeaves > depthTableBuilt {
+				t.row(strconv.Itoa(numLeaves), strconv.Itoa(predicted), "derived", exitTime(predicted))
+				continue
+			}
+
+			generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: numLeaves, RawScripts: true})
+			if err != nil {
+				fmt.Printf("❌ Error: tree of %d leaves: %s\n", numLeaves, err)
+				os.Exit(1)
+			}
+			depth := arktree.TreeDepth(generation.Tree)
+			source := "built ✅"
+			if depth != predicted {
+				mismatches++
+				source = fmt.Sprintf("built ❌ (%d predicted)", predicted)
+			}
+			t.row(strconv.Itoa(numLeaves), strconv.Itoa(depth), source, exitTime(depth))
+		}
+		t.flush()
+
+		fmt.Println(strings.Repeat("─", 40))
+		if mismatches > 0 {
+			fmt.Printf("❌ %d tree(s) differ from the predicted depth\n", mismatches)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	depthTableCmd.Flags().IntVar(&depthTableMax, "max", 10000, "Largest leaf count of the series, always included")
+	depthTableCmd.Flags().Float64Var(&depthTableFactor, "factor", 2, "Ratio between two leaf counts of the series")
+	depthTableCmd.Flags().IntVar(&depthTableBuilt, "build-limit", 512, "Largest leaf count whose tree is built to measure its depth, the others are derived (0 derives them all)")
+	depthTableCmd.Flags().DurationVar(&blockInterval, "block-interval", arktree.DefaultBlockInterval, "Time between two blocks used to estimate exit times")
+
+	rootCmd.AddCommand(depthTableCmd)
+}
+
+// geometricSeries returns 1 then the previous count multiplied by factor and
+// rounded, growing by one at least, up to and including maxCount
+func geometricSeries(maxCount int, factor float64) []int {
+	counts := []int{1}
+	for last := 1; last < maxCount; {
+		last = min(maxCount, max(last+1, int(float64(last)*factor+0.5)))
+		counts = append(counts, last)
+	}
+	return counts
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix prefixes the environment variables providing flag defaults
+const envPrefix = "ARKTREE_"
+
+func init() {
+	// cobra runs only the closest persistent pre-run hook by default, which
+	// would silently skip this one for a subcommand defining its own
+	cobra.EnableTraverseRunHooks = true
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyEnvDefaults(cmd.Flags()); err != nil {
+			return err
+		}
+		if txidLength < 0 {
+			return fmt.Errorf("--txid-length must be at least 0, got %d", txidLength)
+		}
+		if paginate && isTerminal(os.Stdout) {
+			return runPaged()
+		}
+		return nil
+	}
+}
+
+// envName returns the environment variable of a flag: --leaves-file is ARKTREE_LEAVES_FILE
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnvDefaults sets every flag that isn't given on the command line from
+// its environment variable, if set. Flags given explicitly take precedence.
+func applyEnvDefaults(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
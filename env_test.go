@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestApplyEnvDefaults(t *testing.T) {
+	newFlags := func() (*pflag.FlagSet, *int, *string) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		return flags, flags.Int("num-leaves", 8, ""), flags.String("leaves-file", "", "")
+	}
+
+	t.Setenv("ARKTREE_NUM_LEAVES", "16")
+	t.Setenv("ARKTREE_LEAVES_FILE", "leaves.json")
+	flags, numLeaves, leavesFile := newFlags()
+	if err := flags.Parse([]string{"--num-leaves", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnvDefaults(flags); err != nil {
+		t.Fatal(err)
+	}
+	// the flag given explicitly beats its environment variable
+	if *numLeaves != 4 || *leavesFile != "leaves.json" {
+		t.Errorf("--num-leaves %d, --leaves-file %q", *numLeaves, *leavesFile)
+	}
+
+	t.Setenv("ARKTREE_NUM_LEAVES", "many")
+	flags, _, _ = newFlags()
+	if err := applyEnvDefaults(flags); err == nil || !strings.HasPrefix(err.Error(), "invalid ARKTREE_NUM_LEAVES: ") {
+		t.Errorf("got %v, expected invalid ARKTREE_NUM_LEAVES", err)
+	}
+}
+
+func TestEnvDefaultsReachPersistentFlags(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		env, value string
+		args       []string
+		wantCode   int
+		wantOutput string
+	}{
+		{"txid length", "ARKTREE_TXID_LENGTH", "-1", nil, 1, "--txid-length must be at least 0"},
+		{"explicit txid length", "ARKTREE_TXID_LENGTH", "-1", []string{"--txid-length", "8"}, 0, ""},
+		{"invalid paginate", "ARKTREE_PAGINATE", "sometimes", nil, 1, "invalid ARKTREE_PAGINATE"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(test.env, test.value)
+			args := append([]string{"generate", "3", "--seed", "1"}, test.args...)
+			stdout, stderr, code := runArktree(t, args...)
+			if code != test.wantCode || !strings.Contains(stdout+stderr, test.wantOutput) {
+				t.Errorf("exit code %d, expected %d with %q\nstdout:\n%s\nstderr:\n%s", code, test.wantCode, test.wantOutput, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestEnvDefaultsSurviveSubcommandHooks(t *testing.T) {
+	ran := false
+	sub := &cobra.Command{
+		Use:               "hooked",
+		PersistentPreRunE: func(*cobra.Command, []string) error { ran = true; return nil },
+		RunE:              func(*cobra.Command, []string) error { return nil },
+	}
+	rootCmd.AddCommand(sub)
+	t.Cleanup(func() { rootCmd.RemoveCommand(sub) })
+
+	setFlag(t, &txidLength, txidLength)
+	t.Setenv("ARKTREE_TXID_LENGTH", "5")
+	rootCmd.SetArgs([]string{"hooked"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !ran || txidLength != 5 {
+		t.Errorf("subcommand hook run: %t, --txid-length %d, expected both hooks to run", ran, txidLength)
+	}
+}
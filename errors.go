@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// Phases of the generate command reported by errorReport
+const (
+	phaseValidation = "validation"
+	phaseLoad       = "load"
+	phaseBuild      = "build"
+	phaseExport     = "export"
+	phaseStats      = "stats"
+	phaseOutput     = "output"
+)
+
+// errorReport is the machine-readable form of an error, printed to stderr with --output json
+type errorReport struct {
+	Error     string `json:"error"`
+	Phase     string `json:"phase"`
+	LeafIndex *int   `json:"leaf_index,omitempty"`
+}
+
+// exitWithError reports err and exits with status 1. With --output json or
+// jsonl it prints an errorReport to stderr, carrying the index of the leaf
+// that caused err if any, otherwise it prints the usual text line built from
+// format.
+func exitWithError(phase string, err error, format string, a ...any) {
+	if stdout := stdoutFormat(); stdout != outputJSON && stdout != outputJSONL {
+		fmt.Printf(format, a...)
+		os.Exit(1)
+	}
+
+	report := errorReport{Error: err.Error(), Phase: phase}
+	var leafErr *arktree.LeafError
+	if errors.As(err, &leafErr) {
+		report.LeafIndex = &leafErr.Index
+	}
+	json.NewEncoder(os.Stderr).Encode(report)
+	os.Exit(1)
+}
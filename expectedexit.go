@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var expectedExitByAmount bool
+
+var expectedExitSizeCmd = &cobra.Command{
+	Use:   "expected-exit-size [tree-file]",
+	Short: "Print the expected number of txs a random user of an exported tree broadcasts to exit alone",
+	Long: `Import a tree exported with "generate --out" and print the expected number of txs a random user broadcasts to exit unilaterally while the others cooperate: their branch, from the root to their leaf.
+
+Every leaf is drawn with the same probability by default, which gives the mean branch size. With --by-amount a leaf is drawn in proportion to its value, the expected exit of a random sat rather than of a random user: it differs from the mean as soon as the big VTXOs sit in shallower or deeper branches than the small ones.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		sizes, err := arktree.SizeOfBranches(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to get size of branches: %s\n", err)
+			os.Exit(1)
+		}
+		mean, err := arktree.ExpectedExitSize(sizes, nil)
+		if err != nil {
+			fmt.Printf("❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		expected, weighting := mean, "tx (every leaf equally likely)"
+		if expectedExitByAmount {
+			expected, err = arktree.ExpectedExitSize(sizes, arktree.LeafValues(txtree))
+			if err != nil {
+				fmt.Printf("❌ Error: %s\n", err)
+				os.Exit(1)
+			}
+			weighting = "tx (leaves weighted by amount)"
+		}
+
+		fmt.Println("\n🚪 EXPECTED EXIT SIZE:")
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, false, true)
+		t.row("Expected exit size:", fmt.Sprintf("%.2f", expected), weighting)
+		if expectedExitByAmount {
+			t.row("Mean branch size:", fmt.Sprintf("%.2f", mean), "tx (every leaf equally likely)")
+		}
+		t.row("Leaves:", strconv.Itoa(len(sizes)))
+		t.flush()
+	},
+}
+
+func init() {
+	expectedExitSizeCmd.Flags().BoolVar(&expectedExitByAmount, "by-amount", false, "Draw the exiting leaf in proportion to its value rather than uniformly")
+
+	rootCmd.AddCommand(expectedExitSizeCmd)
+}
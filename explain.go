@@ -0,0 +1,37 @@
+package main
+
+// statExplanations is the meaning of each statistic printed with --explain,
+// keyed by the label of its row in printStats
+var statExplanations = map[string]string{
+	"🌳 Total Transactions:":     "Transactions in the tree, all of them go on-chain if every user exits",
+	"🍃 Number of Leaves:":       "VTXOs, one per user output, at the bottom of the tree",
+	"📏 Biggest Branch Size:":    "Most transactions from the root to a leaf, both counted, what the unluckiest user confirms to exit; --branch-include-root=false leaves out the root and the txs every branch shares",
+	"📊 Average Branch Size:":    "Transactions from the root to a leaf, both counted, averaged over the users",
+	"📊 Median Branch Size:":     "Transactions from the root to a leaf, both counted, of the median user",
+	"📡 Most Tx to Broadcast:":   "Broadcast weight = transactions a single user must publish to exit alone, a tx shared by n cosigners counting for 1/n",
+	"📊 Avg Tx to Broadcast:":    "Broadcast weight averaged over the users",
+	"📊 Median Tx to Broadcast:": "Broadcast weight of the median user",
+	"🔢 Distinct Weights:":       "Number of different broadcast weights, 1 for a perfectly regular tree",
+	"⚖️ Balance:":               "How evenly the branch sizes are spread, 1 when every leaf is at the same depth and 0 for a chain",
+	"🤝 Amortization:":           "Transactions of a cooperative exit over those of every user exiting alone, lower means more sharing",
+	"📡 Total Tx to Broadcast:":  "Broadcast weights of all the users summed, against each user broadcasting their whole branch",
+	"💰 Total Value:":            "Sats owned by the leaves, anchor outputs excluded",
+	"💸 Fees in Tree:":           "Sats of the --per-tx-fee of every tx, locked in the output the root spends on top of the value of the leaves",
+	"🌲 Branching Factor:":       "Average children of the nodes that have any, 2 for a binary tree",
+	"🔷 Shape:":                  "perfect if every level is full, complete if all but the last are, irregular otherwise",
+	"⚓ Most Tx w/ Anchors:":     "Broadcast weight counting the CPFP child spending each anchor output",
+	"⚓ Avg Tx w/ Anchors:":      "Broadcast weight with anchors averaged over the users",
+	"⚓ Median Tx w/ Anchors:":   "Broadcast weight with anchors of the median user",
+	"📦 Most vB to Broadcast:":   "Broadcast weight in vbytes, each tx counting for its vsize split between its cosigners",
+	"📦 Avg vB to Broadcast:":    "Broadcast weight in vbytes averaged over the users",
+	"📦 Median vB to Broadcast:": "Broadcast weight in vbytes of the median user",
+	"🔑 Key Churn:":              "Cosigner keys a parent has on top of its largest child, averaged over the parents",
+	"👥 Cosigners per Node:":     "Fewest, average and most cosigners of a tx, a tx with n cosigners counting for 1/n in the broadcast weights",
+	"🫥 NUMS Cosigner:":          "Txs cosigned by the NUMS point of BIP341, a key without private key making the key path unspendable, which counts as a cosigner in the weights unless --exclude-nums",
+	"🔑 Cosigner Sets:":          "Each node is signed by exactly the cosigners of the leaves below it",
+	"🧾 Tx Version:":             "nVersion of the root tx, 3 for TRUC transactions",
+	"🧾 Tx Locktime:":            "nLockTime of the root tx",
+	"🔒 Checksum:":               "SHA256 of the txids in broadcast order, equal for identical trees",
+	"💾 Size on Wire:":           "Bytes of the whole tree once signed, what the server sends to the users",
+	"🗄️ Storage per VTXO:":      "Bytes of tree data each user stores to be able to exit, if the tree is split evenly",
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+const exportFormatVersion = 1
+
+// exportManifest describes an exported tree
+type exportManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Leaves    int       `json:"leaves"`
+	NodeCount int       `json:"node_count"`
+	Depth     int       `json:"depth"`
+
+	Build *buildParams `json:"build,omitempty"` // missing for trees built from a leaves file
+	// SweepTreeRoot is the hex taproot tweak of the outputs, needed to sign
+	// the tree and missing if unknown
+	SweepTreeRoot string `json:"sweep_tree_root,omitempty"`
+	// Metrics are the compare metrics of the run exporting the tree by name,
+	// the baseline of --baseline, missing for the exports of other commands
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// ArkVersion is the version of the ark module linked in the exporting
+	// binary, missing in the exports of older versions
+	ArkVersion string `json:"ark_version,omitempty"`
+}
+
+// treeExport is the file format of an exported tree: a manifest and the
+// serialized graph as a list of chunks
+type treeExport struct {
+	Manifest exportManifest      `json:"manifest"`
+	Chunks   []tree.TxGraphChunk `json:"chunks"`
+	// Cosigners are the hex compressed cosigner keys of each node by txid, as
+	// tree.GetCosignerKeys reads them from its input, with --include-cosigners
+	Cosigners map[string][]string `json:"cosigners,omitempty"`
+}
+
+// includeCosigners adds the cosigner keys of every node to the exports
+var includeCosigners bool
+
+// ignoreVersion silences the warning about exports of another ark version
+var ignoreVersion bool
+
+var importCmd = &cobra.Command{
+	Use:   "import [tree-file]",
+	Short: "Import a previously exported Ark tree and print its statistics",
+	Long:  `Import an Ark tree exported with "generate --out" and print its statistics. Gzip compressed exports are detected automatically.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+
+		// Progress output is silenced when only the CDF or the failed assertions are requested
+		out := io.Writer(os.Stdout)
+		if assertQuiet || cdf {
+			out = io.Discard
+		}
+
+		fmt.Fprintln(out, "🌳 Ark Tree Importer")
+		fmt.Fprintln(out, "="+strings.Repeat("=", 50))
+
+		fmt.Fprintf(out, "📥 Importing tree from %s... ", path)
+		export, err := readTreeExport(path)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+		txtree, err := importedGraph(export.Chunks)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "✅ (exported %s)\n", export.Manifest.CreatedAt.Format(time.RFC3339))
+
+		warnings, err := checkManifest(txtree, &export.Manifest)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to check tree against its manifest: %s\n", err)
+			os.Exit(1)
+		}
+		for _, warning := range warnings {
+			warnf(out, "%s, the export may be truncated or corrupted", warning)
+		}
+		if warning := checkArkVersion(&export.Manifest); warning != "" {
+			warnf(out, "%s", warning)
+		}
+
+		mismatches, err := checkCosigners(txtree, export.Cosigners)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to check the stored cosigners: %s\n", err)
+			os.Exit(1)
+		}
+		for _, mismatch := range mismatches {
+			warnf(out, "%s", mismatch)
+		}
+
+		fmt.Fprint(out, "📈 Calculating tree statistics... ")
+		stats, err := analyze(txtree, arktree.AnalyzeOptions{
+			Workers:         workers,
+			WithAnchors:     withAnchors,
+			WeightBy:        arktree.WeightUnit(weightBy),
+			VerifyCosigners: verifyCosigners,
+			Feerate:         feerate,
+			BlockInterval:   blockInterval,
+			Witness:         witnessModel(),
+			ClampValue:      clampValue,
+			ExcludeNUMS:     excludeNUMS,
+			PerTxFee:        perTxFee,
+		})
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(out, "✅")
+
+		if cdf {
+			// the CDF is written on stdout, the gates report on stderr before it
+			if !stats.Partial {
+				checkGates(os.Stderr, txtree, stats)
+			}
+			if err := writeCDF(os.Stdout, reportedBranchSizes(stats)); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: Failed to write CDF: %s\n", err)
+				os.Exit(1)
+			}
+			exitIfPartial(stats)
+			return
+		}
+
+		if !assertQuiet {
+			printStats(stats)
+		}
+		exitIfPartial(stats)
+		checkGates(os.Stdout, txtree, stats)
+	},
+}
+
+func init() {
+	addStatsFlags(importCmd)
+	addIgnoreVersionFlag(importCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+// exportTree writes the graph to path, gzip compressed if path ends in .gz,
+// recording the parameters it was built with and the metrics of its
+// statistics if known
+func exportTree(path string, g *tree.TxGraph, build *buildParams, sweepTreeRoot []byte, stats *arktree.Report) error {
+	chunks, err := g.Serialize()
+	if err != nil {
+		return err
+	}
+
+	nodeCount, err := arktree.NumberOfNodes(g)
+	if err != nil {
+		return err
+	}
+
+	var metrics map[string]float64
+	if stats != nil && !stats.Partial {
+		metrics = runMetrics(newStatsReport(stats, nil))
+	}
+
+	var cosigners map[string][]string
+	if includeCosigners {
+		cosigners, err = nodeCosigners(g)
+		if err != nil {
+			return err
+		}
+	}
+
+	arkVersion, _ := arkDependencyVersion()
+	export := treeExport{
+		Manifest: exportManifest{
+			Version:   exportFormatVersion,
+			CreatedAt: time.Now().UTC(),
+			Leaves:    len(g.Leaves()),
+			NodeCount: nodeCount,
+			Depth:     arktree.TreeDepth(g),
+			Build:     build,
+
+			SweepTreeRoot: hex.EncodeToString(sweepTreeRoot),
+			Metrics:       metrics,
+			ArkVersion:    arkVersion,
+		},
+		Chunks:    chunks,
+		Cosigners: cosigners,
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	err = json.NewEncoder(w).Encode(export)
+	// closing gz flushes its last block and writes the gzip footer, the
+	// export is truncated if it fails
+	if gz != nil {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// nodeCosigners returns the hex compressed cosigner keys of every node of g by
+// txid, in the order of its input
+func nodeCosigners(g *tree.TxGraph) (map[string][]string, error) {
+	cosigners := make(map[string][]string)
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return false, fmt.Errorf("node %s: %w", node.Root.UnsignedTx.TxID(), err)
+		}
+		encoded := make([]string, 0, len(keys))
+		for _, key := range keys {
+			encoded = append(encoded, hex.EncodeToString(key.SerializeCompressed()))
+		}
+		cosigners[node.Root.UnsignedTx.TxID()] = encoded
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	return cosigners, nil
+}
+
+// checkCosigners compares the cosigner keys stored in an export with the keys
+// of the nodes of g, whatever their order, and returns a message for every
+// node they don't match. An export without stored keys has nothing to check.
+func checkCosigners(g *tree.TxGraph, stored map[string][]string) ([]string, error) {
+	if stored == nil {
+		return nil, nil
+	}
+
+	actual, err := nodeCosigners(g)
+	if err != nil {
+		return nil, err
+	}
+
+	mismatches := make([]string, 0)
+	for _, txid := range slices.Sorted(maps.Keys(actual)) {
+		keys, ok := stored[txid]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("node %s has no stored cosigners", txid))
+			continue
+		}
+		if !slices.Equal(slices.Sorted(slices.Values(keys)), slices.Sorted(slices.Values(actual[txid]))) {
+			mismatches = append(mismatches, fmt.Sprintf("node %s: stored cosigners don't match the %d keys of its input", txid, len(actual[txid])))
+		}
+	}
+	for _, txid := range slices.Sorted(maps.Keys(stored)) {
+		if _, ok := actual[txid]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("stored cosigners of %s, not a node of the tree", txid))
+		}
+	}
+	return mismatches, nil
+}
+
+// openExport opens an exported tree, gzip compressed files are detected by
+// their magic bytes whatever the extension
+func openExport(path string) (io.Reader, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close(); f.Close() }, nil
+	}
+	return r, func() { f.Close() }, nil
+}
+
+// importTree reads a tree written by exportTree, see openExport
+func importTree(path string) (*tree.TxGraph, *exportManifest, error) {
+	export, err := readTreeExport(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g, err := importedGraph(export.Chunks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, &export.Manifest, nil
+}
+
+// readTreeExport reads an exported tree without building its graph
+func readTreeExport(path string) (*treeExport, error) {
+	r, closeFile, err := openExport(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	var export treeExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid tree file: %w", err)
+	}
+
+	if export.Manifest.Version != exportFormatVersion {
+		return nil, fmt.Errorf(
+			"unsupported export version %d, expected %d", export.Manifest.Version, exportFormatVersion,
+		)
+	}
+	return &export, nil
+}
+
+// importedGraph builds the graph of the chunks of an exported tree
+func importedGraph(chunks []tree.TxGraphChunk) (*tree.TxGraph, error) {
+	g, err := tree.NewTxGraph(chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// the graph is rebuilt from the psbts, make sure their txids match the exported ones
+	txids := make(map[string]struct{}, len(chunks))
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		txids[node.Root.UnsignedTx.TxID()] = struct{}{}
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		if _, ok := txids[chunk.Txid]; !ok {
+			return nil, fmt.Errorf("txid %s doesn't match its transaction", chunk.Txid)
+		}
+	}
+
+	return g, nil
+}
+
+// addIgnoreVersionFlag registers --ignore-version on cmd
+func addIgnoreVersionFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&ignoreVersion, "ignore-version", false, "Don't warn when the export was built with another ark version than the linked one")
+}
+
+// checkArkVersion returns a warning if the tree of manifest may have been
+// built with another ark version than the linked one, as its construction and
+// so its statistics may differ, or "" with --ignore-version
+func checkArkVersion(manifest *exportManifest) string {
+	linked, _ := arkDependencyVersion()
+	if ignoreVersion || linked == "unknown" || manifest.ArkVersion == linked {
+		return ""
+	}
+	if manifest.ArkVersion == "" {
+		return fmt.Sprintf("the export doesn't record its ark version, it may not have been built by the linked ark %s and its statistics may not be comparable (--ignore-version to silence)", linked)
+	}
+	return fmt.Sprintf("the tree was exported with ark %s, the linked ark is %s: its statistics may not be comparable (--ignore-version to silence)", manifest.ArkVersion, linked)
+}
+
+// checkManifest recomputes the shape of the imported tree and returns a warning
+// for every value not matching the manifest
+func checkManifest(g *tree.TxGraph, manifest *exportManifest) ([]string, error) {
+	nodeCount, err := arktree.NumberOfNodes(g)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]string, 0)
+	if leaves := len(g.Leaves()); leaves != manifest.Leaves {
+		warnings = append(warnings, fmt.Sprintf("tree has %d leaves, manifest expects %d", leaves, manifest.Leaves))
+	}
+	if nodeCount != manifest.NodeCount {
+		warnings = append(warnings, fmt.Sprintf("tree has %d nodes, manifest expects %d", nodeCount, manifest.NodeCount))
+	}
+	if depth := arktree.TreeDepth(g); depth != manifest.Depth {
+		warnings = append(warnings, fmt.Sprintf("tree has depth %d, manifest expects %d", depth, manifest.Depth))
+	}
+	return warnings, nil
+}
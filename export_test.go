@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// nodeTxids returns the sorted txids of every node of g
+func nodeTxids(t *testing.T, g *tree.TxGraph) []string {
+	t.Helper()
+	var txids []string
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		txids = append(txids, node.Root.UnsignedTx.TxID())
+		return true, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(txids)
+	return txids
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	txtree := seededTree(t, 7, 1).Tree
+	want := nodeTxids(t, txtree)
+
+	for _, test := range []struct {
+		name       string
+		compressed bool
+	}{
+		{"tree.json", false},
+		{"tree.json.gz", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := exportTree(path, txtree, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gzipped := bytes.HasPrefix(data, []byte{0x1f, 0x8b}); gzipped != test.compressed {
+				t.Errorf("gzip compressed: %t, expected %t", gzipped, test.compressed)
+			}
+
+			g, manifest, err := importTree(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodeTxids(t, g); !slices.Equal(got, want) {
+				t.Errorf("imported txids %v, expected %v", got, want)
+			}
+			if got, want := g.Root.UnsignedTx.TxID(), txtree.Root.UnsignedTx.TxID(); got != want {
+				t.Errorf("imported root %s, expected %s", got, want)
+			}
+			if manifest.Leaves != 7 || manifest.NodeCount != len(want) {
+				t.Errorf("manifest of %d leaves and %d nodes, expected 7 and %d", manifest.Leaves, manifest.NodeCount, len(want))
+			}
+		})
+	}
+}
+
+// truncateExport drops the leaves of a node whose children are all leaves,
+// leaving the node as a leaf, as a partial copy of the export would
+func truncateExport(t *testing.T, export *treeExport) {
+	t.Helper()
+	byTxid := make(map[string]tree.TxGraphChunk, len(export.Chunks))
+	for _, chunk := range export.Chunks {
+		byTxid[chunk.Txid] = chunk
+	}
+	for i, chunk := range export.Chunks {
+		if len(chunk.Children) == 0 {
+			continue
+		}
+		leafChildren := true
+		for _, child := range chunk.Children {
+			leafChildren = leafChildren && len(byTxid[child].Children) == 0
+		}
+		if !leafChildren {
+			continue
+		}
+		export.Chunks[i].Children = nil
+		export.Chunks = slices.DeleteFunc(export.Chunks, func(c tree.TxGraphChunk) bool {
+			for _, child := range chunk.Children {
+				if c.Txid == child {
+					return true
+				}
+			}
+			return false
+		})
+		return
+	}
+	t.Fatal("no node with only leaves as children")
+}
+
+func TestCheckManifestDetectsTampering(t *testing.T) {
+	txtree := seededTree(t, 7, 1).Tree
+	path := filepath.Join(t.TempDir(), "tree.json")
+	if err := exportTree(path, txtree, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		tamper func(*treeExport)
+		want   []string
+	}{
+		{"untouched", func(*treeExport) {}, nil},
+		{"leaf count", func(e *treeExport) { e.Manifest.Leaves++ }, []string{"tree has 7 leaves, manifest expects 8"}},
+		{"node count", func(e *treeExport) { e.Manifest.NodeCount-- }, []string{"tree has 13 nodes, manifest expects 12"}},
+		{"depth", func(e *treeExport) { e.Manifest.Depth = 2 }, []string{"tree has depth 4, manifest expects 2"}},
+		{"truncated tree", func(e *treeExport) { truncateExport(t, e) }, []string{
+			"tree has 6 leaves, manifest expects 7",
+			"tree has 11 nodes, manifest expects 13",
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var export treeExport
+			if err := json.Unmarshal(data, &export); err != nil {
+				t.Fatal(err)
+			}
+			test.tamper(&export)
+			tampered, err := json.Marshal(export)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tamperedPath := filepath.Join(t.TempDir(), "tree.json")
+			if err := os.WriteFile(tamperedPath, tampered, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			g, manifest, err := importTree(tamperedPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := checkManifest(g, manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(warnings, test.want) {
+				t.Errorf("warnings:\n%s\nexpected:\n%s", strings.Join(warnings, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckArkVersion(t *testing.T) {
+	linked, _ := arkDependencyVersion()
+	if linked == "unknown" {
+		t.Skip("no build info to compare the version with")
+	}
+	for _, test := range []struct {
+		name          string
+		version       string
+		ignoreVersion bool
+		warns         bool
+	}{
+		{"linked version", linked, false, false},
+		{"another version", "v0.0.1", false, true},
+		{"missing version", "", false, true},
+		{"another version ignored", "v0.0.1", true, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			setFlag(t, &ignoreVersion, test.ignoreVersion)
+			if warning := checkArkVersion(&exportManifest{ArkVersion: test.version}); (warning != "") != test.warns {
+				t.Errorf("warning %q, expected one: %t", warning, test.warns)
+			}
+		})
+	}
+}
+
+func TestCheckCosigners(t *testing.T) {
+	generation := seededTree(t, 3, 1)
+	stored, err := nodeCosigners(generation.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatches, err := checkCosigners(generation.Tree, stored); err != nil || len(mismatches) > 0 {
+		t.Fatalf("mismatches %v, error %v", mismatches, err)
+	}
+
+	root := generation.Tree.Root.UnsignedTx.TxID()
+	stored[root] = stored[root][1:]
+	stored["00"] = nil
+	mismatches, err := checkCosigners(generation.Tree, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		t.Errorf("%d mismatches, expected the root and the unknown node: %v", len(mismatches), mismatches)
+	}
+}
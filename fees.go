@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+// checkFunding checks that inputAmount sats pay for the leaves of txtree and
+// for broadcasting all its txs at --feerate, returning the line reporting the
+// headroom left. Generate already checked the leaves alone before building.
+func checkFunding(txtree *tree.TxGraph, leaves []tree.Leaf, inputAmount int64) (string, error) {
+	total, err := arktree.LeavesTotal(leaves)
+	if err != nil {
+		return "", err
+	}
+	vsize, err := arktree.TreeVsize(txtree, witnessModel())
+	if err != nil {
+		return "", err
+	}
+	fees := arktree.FeeForVsize(vsize, feerate)
+	headroom, err := arktree.FundingHeadroom(inputAmount, total, fees)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("💵 Funding headroom: %d sats (%d input - %d leaves - %d fees for %d vB at %g sat/vB)",
+		headroom, inputAmount, total, fees, vsize, feerate), nil
+}
+
+// printFeeBudget warns about the branches whose exit fee exceeds budget sats,
+// listing at most maxDetailRows of them, the most expensive first
+func printFeeBudget(costs []arktree.ExitCost, budget int64) {
+	over := arktree.OverBudget(costs, budget)
+
+	fmt.Printf("\n💰 FEE BUDGET (%d sats):\n", budget)
+	fmt.Println(strings.Repeat("─", 40))
+	if len(over) == 0 {
+		fmt.Println("✅ Every exit fee is within the budget")
+		return
+	}
+
+	fmt.Printf("⚠️  %d of %d exits exceed the budget\n", len(over), len(costs))
+	shown := over
+	if maxDetailRows > 0 && len(shown) > maxDetailRows {
+		shown = shown[:maxDetailRows]
+	}
+	short := txidShortener(os.Stdout, leafTxidsOf(costs))
+	t := newTable(os.Stdout, false, true)
+	for _, cost := range shown {
+		t.row(short(cost.LeafTxid), strconv.FormatInt(cost.Fee, 10), fmt.Sprintf("sats (%+d)", cost.Fee-budget))
+	}
+	if len(shown) < len(over) {
+		t.line("... and %d more", len(over)-len(shown))
+	}
+	t.flush()
+}
+
+// partialExitSeed seeds the draws of printPartialExit, so that the same tree
+// always gives the same estimate
+const partialExitSeed = 1
+
+// printPartialExit prints the expected number of txs broadcast when each user
+// exits unilaterally with probability fraction
+func printPartialExit(g *tree.TxGraph, fraction float64, samples int) {
+	exit, err := arktree.SimulatePartialExit(g, fraction, samples, mathrand.New(mathrand.NewPCG(partialExitSeed, partialExitSeed)))
+	if err != nil {
+		fmt.Printf("❌ Error: Failed to simulate partial cooperation: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n🤝 PARTIAL COOPERATION (%.0f%% of users exit):\n", fraction*100)
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.row("Expected tx on-chain:", fmt.Sprintf("%.1f", exit.Mean), fmt.Sprintf("± %.1f over %d draws", exit.StdDev, exit.Samples))
+	t.row("Range:", fmt.Sprintf("%d-%d", exit.Min, exit.Max), "tx")
+	t.row("Share of the tree:", fmt.Sprintf("%.1f%%", exit.Mean/float64(exit.Nodes)*100), fmt.Sprintf("of %d tx, shared ancestors broadcast once", exit.Nodes))
+	t.flush()
+}
+
+func printExitCosts(costs []arktree.ExitCost, sweep arktree.SweepCost, feerate float64, witness arktree.WitnessModel) {
+	if len(costs) == 0 {
+		return
+	}
+
+	var (
+		sumRatio float64
+		maxRatio float64
+		unviable []arktree.ExitCost
+	)
+	for _, cost := range costs {
+		ratio := cost.FeeRatio()
+		sumRatio += ratio
+		if ratio > maxRatio {
+			maxRatio = ratio
+		}
+		if !cost.Viable() {
+			unviable = append(unviable, cost)
+		}
+	}
+
+	fmt.Printf("\n💸 EXIT COST (%.2f sat/vB):\n", feerate)
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.rowf("Mean fee/value:", "%.2f%%", sumRatio/float64(len(costs))*100)
+	t.rowf("Max fee/value:", "%.2f%%", maxRatio*100)
+	t.row("Unviable exits:", strconv.Itoa(len(unviable)))
+	t.row("Max viable feerate:", fmt.Sprintf("%.2f", floorCents(arktree.MaxViableFeerate(costs))), "sat/vB (no exit costs more than its value)")
+	if sweep.Vsize > 0 {
+		t.row("Cooperative sweep:", fmt.Sprintf("%d vB, %d sats", sweep.Vsize, sweep.Fee), "(the root only)")
+		t.row("Whole tree:", fmt.Sprintf("%d vB, %d sats", sweep.TreeVsize, sweep.TreeFee), fmt.Sprintf("(%.1fx the cooperative sweep)", sweep.Ratio()))
+	}
+	if witness != arktree.DefaultWitnessModel {
+		t.row("Witness per input:", strconv.Itoa(witness.Base), fmt.Sprintf("bytes + %d per cosigner", witness.PerCosigner))
+	}
+	t.flush()
+
+	if len(unviable) == 0 {
+		return
+	}
+	// list at most maxDetailRows of them, the highest fee/value first
+	sort.SliceStable(unviable, func(i, j int) bool { return unviable[i].FeeRatio() > unviable[j].FeeRatio() })
+	shown := unviable
+	if maxDetailRows > 0 && len(shown) > maxDetailRows {
+		shown = shown[:maxDetailRows]
+	}
+	short := txidShortener(os.Stdout, leafTxidsOf(costs))
+	for _, cost := range shown {
+		fmt.Printf("⚠️  %s: exit fee %d sats > value %d sats\n", short(cost.LeafTxid), cost.Fee, cost.Value)
+	}
+	printHiddenGroups(len(unviable) - len(shown))
+}
+
+// leafTxidsOf returns the leaf txids of costs, in their order
+func leafTxidsOf(costs []arktree.ExitCost) []string {
+	txids := make([]string, 0, len(costs))
+	for _, cost := range costs {
+		txids = append(txids, cost.LeafTxid)
+	}
+	return txids
+}
+
+// floorCents rounds x down to 2 decimal places, not to print a feerate above
+// the one it bounds
+func floorCents(x float64) float64 {
+	return math.Floor(x*100) / 100
+}
+
+// printFeeMatrix prints the median and worst exit fees at each of feerates
+func printFeeMatrix(costs []arktree.ExitCost, feerates []float64) {
+	matrix, err := arktree.FeeMatrix(costs, feerates)
+	if err != nil {
+		fmt.Printf("❌ Error: Invalid --feerates: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\n💸 EXIT FEE BY FEERATE:")
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, true, true, true)
+	t.row("sat/vB", "median", "worst")
+	for _, fees := range matrix {
+		t.row(strconv.FormatFloat(fees.Feerate, 'f', -1, 64), fmt.Sprintf("%.0f", fees.Median), strconv.FormatInt(fees.Max, 10))
+	}
+	t.flush()
+}
+
+var (
+	witnessBase        int
+	witnessPerCosigner int
+)
+
+// addWitnessFlags registers the flags of the witness size model on cmd
+func addWitnessFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&witnessBase, "witness-bytes-base", arktree.DefaultWitnessModel.Base, "Estimated witness bytes of each tx input, 68 for a key path spend (see README)")
+	cmd.Flags().IntVar(&witnessPerCosigner, "witness-bytes-per-cosigner", arktree.DefaultWitnessModel.PerCosigner, "Estimated witness bytes added to each tx input per cosigner, 0 as MuSig2 aggregates them")
+}
+
+func witnessModel() arktree.WitnessModel {
+	return arktree.WitnessModel{Base: witnessBase, PerCosigner: witnessPerCosigner}
+}
+
+// printExitTimes prints the distribution of the time each user takes to exit
+func printExitTimes(stats *arktree.Report) {
+	if len(stats.ExitTimes) == 0 {
+		return
+	}
+
+	shortest, median, longest := arktree.ExitTimeRange(stats.ExitTimes)
+	start := arktree.LocktimeDuration(*stats.Expiry, stats.BlockInterval)
+
+	fmt.Printf("\n⏳ TIME TO EXIT (%s locktime + 1 block of %s per tx):\n", start, stats.BlockInterval)
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.row("Min:", shortest.String())
+	t.row("Median:", median.String())
+	t.row("Max:", longest.String())
+	t.flush()
+}
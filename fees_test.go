@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestMaxViableFeerate(t *testing.T) {
+	costs, err := arktree.ExitCostOfBranches(seededTree(t, 9, 1).Tree, 1, arktree.DefaultWitnessModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feerate := arktree.MaxViableFeerate(costs)
+	if feerate <= 0 {
+		t.Fatalf("max viable feerate %g", feerate)
+	}
+	// every exit is viable at the printed feerate, rounded down, not a cent above
+	below, above := 0, 0
+	for _, cost := range costs {
+		if arktree.FeeForVsize(cost.Vsize, floorCents(feerate)) > cost.Value {
+			below++
+		}
+		if arktree.FeeForVsize(cost.Vsize, feerate+0.01) > cost.Value {
+			above++
+		}
+	}
+	if below != 0 || above == 0 {
+		t.Errorf("at %g sat/vB, %d unviable exits below and %d above", feerate, below, above)
+	}
+}
+
+func TestUnviableExitsAreCapped(t *testing.T) {
+	// at 300 leaves of 1000 sats, most exits cost more than their value
+	stdout, stderr, code := runArktree(t, "generate", "300", "--seed", "1", "--max-detail-rows", "3")
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if warnings := strings.Count(stdout, "⚠️  "); warnings != 3 {
+		t.Errorf("%d unviable exits listed, expected 3", warnings)
+	}
+	if !strings.Contains(stdout, "… and 293 more (see --max-detail-rows)") {
+		t.Errorf("no count of the hidden exits in\n%s", stdout)
+	}
+}
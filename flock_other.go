@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+// lockFile is a no-op without file locks, appends are still done in a single write
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
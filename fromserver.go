@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var fromServerCmd = &cobra.Command{
+	Use:   "from-server",
+	Short: "Fetch the vtxo tree of a round from an Ark server and print its statistics",
+	Long: `Fetch the vtxo tree of a round from the REST API of an Ark server and print its statistics, as import does for an exported tree.
+
+The round is identified by its commitment txid. The topology of the tree comes from the indexer's batch tree endpoint and its transactions from the virtual tx endpoint. Requests failing on a network error, a 429 or a 5xx are retried with exponential backoff.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if serverURL == "" || serverRound == "" {
+			fmt.Printf("Error: --url and --round are required\n")
+			os.Exit(1)
+		}
+		if serverRetries < 0 {
+			fmt.Printf("Error: --retries must not be negative\n")
+			os.Exit(1)
+		}
+
+		// Progress output is silenced when only the CDF or the failed assertions are requested
+		out := io.Writer(os.Stdout)
+		if assertQuiet || cdf {
+			out = io.Discard
+		}
+
+		fmt.Fprintln(out, "🌳 Ark Tree Importer")
+		fmt.Fprintln(out, "="+strings.Repeat("=", 50))
+
+		client := &serverClient{
+			baseURL: strings.TrimSuffix(serverURL, "/"),
+			token:   serverToken,
+			retries: serverRetries,
+			http:    &http.Client{Timeout: serverTimeout},
+			sleep:   time.Sleep,
+		}
+
+		fmt.Fprintf(out, "📡 Fetching round %s from %s... ", serverRound, client.baseURL)
+		chunks, err := client.fetchVtxoTree(serverRound, serverVout)
+		if err != nil {
+			fmt.Printf("\n❌ Error: Failed to fetch tree: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "✅ (%d tx)\n", len(chunks))
+
+		txtree, err := importedGraph(chunks)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to build tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		if serverOut != "" {
+			fmt.Fprintf(out, "💾 Exporting tree to %s... ", serverOut)
+			if err := exportTree(serverOut, txtree, nil, nil, nil); err != nil {
+				fmt.Printf("\n❌ Error: Failed to export tree: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintln(out, "✅")
+		}
+
+		fmt.Fprint(out, "📈 Calculating tree statistics... ")
+		stats, err := analyze(txtree, arktree.AnalyzeOptions{
+			Workers:         workers,
+			WithAnchors:     withAnchors,
+			WeightBy:        arktree.WeightUnit(weightBy),
+			VerifyCosigners: verifyCosigners,
+			Feerate:         feerate,
+			BlockInterval:   blockInterval,
+			Witness:         witnessModel(),
+			ClampValue:      clampValue,
+			ExcludeNUMS:     excludeNUMS,
+			PerTxFee:        perTxFee,
+		})
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(out, "✅")
+
+		if cdf {
+			// the CDF is written on stdout, the gates report on stderr before it
+			if !stats.Partial {
+				checkGates(os.Stderr, txtree, stats)
+			}
+			if err := writeCDF(os.Stdout, reportedBranchSizes(stats)); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: Failed to write CDF: %s\n", err)
+				os.Exit(1)
+			}
+			exitIfPartial(stats)
+			return
+		}
+
+		if !assertQuiet {
+			printStats(stats)
+		}
+		exitIfPartial(stats)
+		checkGates(os.Stdout, txtree, stats)
+	},
+}
+
+var (
+	serverURL     string
+	serverRound   string
+	serverVout    uint32
+	serverToken   string
+	serverRetries int
+	serverTimeout time.Duration
+	serverOut     string
+)
+
+func init() {
+	fromServerCmd.Flags().StringVar(&serverURL, "url", "", "Base URL of the REST API of the Ark server, e.g. http://localhost:7070")
+	fromServerCmd.Flags().StringVar(&serverRound, "round", "", "Commitment txid of the round")
+	fromServerCmd.Flags().Uint32Var(&serverVout, "vout", 0, "Output of the commitment tx the vtxo tree spends")
+	fromServerCmd.Flags().StringVar(&serverToken, "token", "", "Token sent as a bearer Authorization header")
+	fromServerCmd.Flags().IntVar(&serverRetries, "retries", 3, "Number of retries of a request failing on a network error, a 429 or a 5xx")
+	fromServerCmd.Flags().DurationVar(&serverTimeout, "timeout", 30*time.Second, "Timeout of each request")
+	fromServerCmd.Flags().StringVar(&serverOut, "out", "", "Export the fetched tree to the given file, gzip compressed if it ends in .gz")
+	fromServerCmd.Flags().BoolVar(&includeCosigners, "include-cosigners", false, "Store the cosigner keys of every node in the export, checked against the tree on import")
+
+	addStatsFlags(fromServerCmd)
+
+	rootCmd.AddCommand(fromServerCmd)
+}
+
+// serverPageSize is the number of tree nodes asked per page, and of txs per
+// virtual tx request to keep URLs short
+const serverPageSize = 100
+
+// serverClient is a client of the REST API of an Ark server
+type serverClient struct {
+	baseURL string
+	token   string
+	retries int
+	http    *http.Client
+	// sleep waits between two attempts of a request, time.Sleep but in tests
+	sleep func(time.Duration)
+}
+
+// serverPage is the pagination of the indexer responses
+type serverPage struct {
+	Current int32 `json:"current"`
+	Next    int32 `json:"next"`
+	Total   int32 `json:"total"`
+}
+
+type serverTreeNode struct {
+	Txid     string            `json:"txid"`
+	Children map[string]string `json:"children"`
+}
+
+type serverTreeResponse struct {
+	VtxoTree []serverTreeNode `json:"vtxoTree"`
+	Page     *serverPage      `json:"page"`
+}
+
+type serverTxsResponse struct {
+	Txs []string `json:"txs"`
+}
+
+// retryableError is the error of a failed request worth retrying
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// fetchVtxoTree returns the vtxo tree of the round as chunks, as read from an
+// export
+func (c *serverClient) fetchVtxoTree(round string, vout uint32) ([]tree.TxGraphChunk, error) {
+	var nodes []serverTreeNode
+	for index := int32(0); ; {
+		query := url.Values{}
+		query.Set("page.size", strconv.Itoa(serverPageSize))
+		query.Set("page.index", strconv.Itoa(int(index)))
+
+		var resp serverTreeResponse
+		if err := c.get(fmt.Sprintf("/v1/indexer/batch/%s/%d/tree", url.PathEscape(round), vout), query, &resp); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, resp.VtxoTree...)
+
+		if resp.Page == nil || resp.Page.Next <= index || resp.Page.Next >= resp.Page.Total {
+			break
+		}
+		index = resp.Page.Next
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("round %s has no vtxo tree", round)
+	}
+
+	txs := make(map[string]string, len(nodes))
+	for start := 0; start < len(nodes); start += serverPageSize {
+		txids := make([]string, 0, serverPageSize)
+		for _, node := range nodes[start:min(start+serverPageSize, len(nodes))] {
+			txids = append(txids, url.PathEscape(node.Txid))
+		}
+
+		var resp serverTxsResponse
+		if err := c.get("/v1/indexer/virtualTx/"+strings.Join(txids, ","), nil, &resp); err != nil {
+			return nil, err
+		}
+		// the txs are matched by txid rather than trusting the response order
+		for _, b64 := range resp.Txs {
+			packet, err := psbt.NewFromRawBytes(strings.NewReader(b64), true)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tx: %w", err)
+			}
+			txs[packet.UnsignedTx.TxID()] = b64
+		}
+	}
+
+	chunks := make([]tree.TxGraphChunk, 0, len(nodes))
+	for _, node := range nodes {
+		tx, ok := txs[node.Txid]
+		if !ok {
+			return nil, fmt.Errorf("server returned no tx for node %s", node.Txid)
+		}
+
+		children := make(map[uint32]string, len(node.Children))
+		for output, child := range node.Children {
+			index, err := strconv.ParseUint(output, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: invalid output index %q", node.Txid, output)
+			}
+			children[uint32(index)] = child
+		}
+		chunks = append(chunks, tree.TxGraphChunk{Txid: node.Txid, Tx: tx, Children: children})
+	}
+	return chunks, nil
+}
+
+// get decodes the JSON response of the API endpoint path into v, retrying the
+// retryable failures with exponential backoff
+func (c *serverClient) get(path string, query url.Values, v any) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := c.getOnce(endpoint, v)
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt == c.retries {
+			return err
+		}
+		c.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (c *serverClient) getOnce(endpoint string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &retryableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("GET %s: %s: %s", req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return &retryableError{err}
+		}
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: invalid response: %w", req.URL.Path, err)
+	}
+	return nil
+}
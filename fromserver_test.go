@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+const (
+	testTreePath = "/v1/indexer/batch/round/0/tree"
+	testToken    = "secret"
+)
+
+// testServer serves the chunks of a tree as the indexer of an Ark server does,
+// pageSize nodes per page whatever the size asked
+type testServer struct {
+	t        *testing.T
+	chunks   []tree.TxGraphChunk
+	pageSize int
+	// failures maps request URIs to the statuses answering their first
+	// requests, before they are served
+	failures map[string][]int
+	// omitted is the txid of a node whose tx is never returned
+	omitted string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	chunks, err := seededTree(t, 7, 1).Tree.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testServer{t: t, chunks: chunks, pageSize: 5, failures: make(map[string][]int)}
+}
+
+// treePage returns the request URI of the page at index of the tree
+func treePage(index int) string {
+	return testTreePath + "?page.index=" + strconv.Itoa(index) + "&page.size=" + strconv.Itoa(serverPageSize)
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.RequestURI)
+	var status int
+	if statuses := s.failures[r.RequestURI]; len(statuses) > 0 {
+		status, s.failures[r.RequestURI] = statuses[0], statuses[1:]
+	}
+	s.mu.Unlock()
+
+	if auth := r.Header.Get("Authorization"); auth != "Bearer "+testToken {
+		s.t.Errorf("GET %s: Authorization %q", r.RequestURI, auth)
+	}
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	switch {
+	case r.URL.Path == testTreePath:
+		index, _ := strconv.Atoi(r.URL.Query().Get("page.index"))
+		pages := (len(s.chunks) + s.pageSize - 1) / s.pageSize
+		var nodes []serverTreeNode
+		for _, chunk := range s.chunks[index*s.pageSize : min((index+1)*s.pageSize, len(s.chunks))] {
+			children := make(map[string]string, len(chunk.Children))
+			for output, child := range chunk.Children {
+				children[strconv.Itoa(int(output))] = child
+			}
+			nodes = append(nodes, serverTreeNode{Txid: chunk.Txid, Children: children})
+		}
+		json.NewEncoder(w).Encode(serverTreeResponse{
+			VtxoTree: nodes,
+			Page:     &serverPage{Current: int32(index), Next: int32(index + 1), Total: int32(pages)},
+		})
+
+	case strings.HasPrefix(r.URL.Path, "/v1/indexer/virtualTx/"):
+		txids := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/indexer/virtualTx/"), ",")
+		var resp serverTxsResponse
+		// the txs are returned in the reverse order of the request
+		for _, txid := range slices.Backward(txids) {
+			for _, chunk := range s.chunks {
+				if chunk.Txid == txid && txid != s.omitted {
+					resp.Txs = append(resp.Txs, chunk.Tx)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fetch fetches the tree of round, recording the backoff delays instead of
+// sleeping them
+func (s *testServer) fetch(round string) ([]tree.TxGraphChunk, []time.Duration, error) {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &serverClient{
+		baseURL: server.URL,
+		token:   testToken,
+		retries: 3,
+		http:    server.Client(),
+		sleep:   func(d time.Duration) { delays = append(delays, d) },
+	}
+	chunks, err := client.fetchVtxoTree(round, 0)
+	return chunks, delays, err
+}
+
+func TestFetchVtxoTree(t *testing.T) {
+	server := newTestServer(t)
+	// the 13 nodes take 3 pages, the second answering 503 twice
+	server.failures[treePage(1)] = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	chunks, delays, err := server.fetch("round")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chunks) != len(server.chunks) {
+		t.Fatalf("%d chunks, expected %d", len(chunks), len(server.chunks))
+	}
+	for i, chunk := range chunks {
+		want := server.chunks[i]
+		if chunk.Txid != want.Txid || chunk.Tx != want.Tx || !maps.Equal(chunk.Children, want.Children) {
+			t.Errorf("chunk %d: %+v, expected %+v", i, chunk, want)
+		}
+	}
+	if want := []time.Duration{500 * time.Millisecond, time.Second}; !slices.Equal(delays, want) {
+		t.Errorf("backoff delays %v, expected %v", delays, want)
+	}
+	if pages := slices.DeleteFunc(slices.Clone(server.requests), func(uri string) bool {
+		return !strings.HasPrefix(uri, testTreePath)
+	}); !slices.Equal(pages, []string{treePage(0), treePage(1), treePage(1), treePage(1), treePage(2)}) {
+		t.Errorf("tree requests %q", pages)
+	}
+}
+
+func TestFetchVtxoTreeErrors(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		server := newTestServer(t)
+		_, delays, err := server.fetch("missing")
+		if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+			t.Errorf("got %v, expected a 404", err)
+		}
+		if len(server.requests) != 1 || len(delays) != 0 {
+			t.Errorf("a 404 was retried: %q", server.requests)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		server := newTestServer(t)
+		server.failures[treePage(0)] = slices.Repeat([]int{http.StatusBadGateway}, 4)
+		_, delays, err := server.fetch("round")
+		if err == nil || !strings.Contains(err.Error(), "502 Bad Gateway") || len(delays) != 3 {
+			t.Errorf("got %v after %d retries, expected a 502 after 3", err, len(delays))
+		}
+	})
+
+	t.Run("no tx for node", func(t *testing.T) {
+		server := newTestServer(t)
+		server.omitted = server.chunks[3].Txid
+		_, _, err := server.fetch("round")
+		if want := "server returned no tx for node " + server.omitted; err == nil || err.Error() != want {
+			t.Errorf("got %v, expected %s", err, want)
+		}
+	})
+}
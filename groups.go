@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// validateCosignerGroups checks --cosigner-groups against the other generation flags
+func validateCosignerGroups(groups, numLeaves int) error {
+	if len(leavesFiles) > 0 {
+		return fmt.Errorf("--cosigner-groups can't be used with --leaves-file")
+	}
+	if sharedCosigner {
+		return fmt.Errorf("--cosigner-groups can't be used with --shared-cosigner")
+	}
+	if groups < 1 || groups > numLeaves {
+		return fmt.Errorf("--cosigner-groups must be between 1 and the number of leaves (%d)", numLeaves)
+	}
+	return nil
+}
+
+// minCosignerSeedSize is the smallest --cosigner-seed accepted, in bytes
+const minCosignerSeedSize = 16
+
+// parseCosignerSeed decodes the hex --cosigner-seed, checking it against the
+// other generation flags
+func parseCosignerSeed(value string) ([]byte, error) {
+	if len(leavesFiles) > 0 {
+		return nil, fmt.Errorf("--cosigner-seed can't be used with --leaves-file")
+	}
+	seed, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --cosigner-seed: %w", err)
+	}
+	if len(seed) < minCosignerSeedSize {
+		return nil, fmt.Errorf("--cosigner-seed must be at least %d bytes, got %d", minCosignerSeedSize, len(seed))
+	}
+	return seed, nil
+}
+
+// cosignerGroupLabels returns the label of the group of each leaf, in the
+// order GenerateLeaves assigns them
+func cosignerGroupLabels(numLeaves, groups int) []string {
+	labels := make([]string, numLeaves)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("group %d", arktree.CosignerGroup(i, groups)+1)
+	}
+	return labels
+}
+
+// printCosignerGroups prints the broadcast weight of the branches of each
+// cosigner group, groups maps each leaf txid to its group label
+func printCosignerGroups(stats *arktree.Report, groups map[string]string) {
+	weights := make(map[string][]float64)
+	for i, txid := range stats.LeafTxids {
+		weights[groups[txid]] = append(weights[groups[txid]], stats.BranchWeights[i])
+	}
+
+	labels := make([]string, 0, len(weights))
+	for label := range weights {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		// "group 10" comes after "group 9"
+		if len(labels[i]) != len(labels[j]) {
+			return len(labels[i]) < len(labels[j])
+		}
+		return labels[i] < labels[j]
+	})
+
+	fmt.Println("\n👥 COSIGNER GROUPS:")
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true, true, true)
+	t.row("group", "leaves", "avg tx", "max tx")
+
+	lowest, highest := 0.0, 0.0
+	shown, hidden := labels, 0
+	if maxDetailRows > 0 && len(labels) > maxDetailRows {
+		shown, hidden = labels[:maxDetailRows], len(labels)-maxDetailRows
+	}
+	for i, label := range labels {
+		avg := arktree.CalculateAverageFloat(weights[label])
+		if i == 0 || avg < lowest {
+			lowest = avg
+		}
+		if i == 0 || avg > highest {
+			highest = avg
+		}
+		if i < len(shown) {
+			t.row(label, strconv.Itoa(len(weights[label])), fmt.Sprintf("%.2f", avg), fmt.Sprintf("%.2f", arktree.MaxFloat(weights[label])))
+		}
+	}
+	t.flush()
+	printHiddenGroups(hidden)
+
+	fmt.Println(strings.Repeat("─", 40))
+	fmt.Printf("Avg tx to broadcast spread across groups: %.2f\n", highest-lowest)
+}
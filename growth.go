@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	growthSteps      int
+	growthSeed       int64
+	growthRawScripts bool
+)
+
+var simulateGrowthCmd = &cobra.Command{
+	Use:   "simulate-growth [number-of-leaves]",
+	Short: "Print how the tree grows as a round fills up with leaves",
+	Long: `Build a tree at each of --steps evenly spaced leaf counts up to N, modelling a pool filling up over a round, and print a CSV line per step with its depth, number of txs and max branch weight.
+
+All the trees are built with the same --seed, so the leaves of a step are the first leaves of the next one: every step adds VTXOs to the round of the previous step. The tree is rebuilt from scratch at each step, as BuildVtxoTree does, so a step can move the leaves of the previous one to other branches.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		numLeaves, err := strconv.Atoi(args[0])
+		if err != nil || numLeaves <= 0 {
+			fmt.Printf("Error: Number of leaves must be a positive integer, got %s\n", args[0])
+			os.Exit(1)
+		}
+		if growthSteps < 1 || growthSteps > numLeaves {
+			fmt.Printf("Error: --steps must be between 1 and the %d leaves, got %d\n", numLeaves, growthSteps)
+			os.Exit(1)
+		}
+
+		if err := writeGrowth(os.Stdout, numLeaves, growthSteps, growthSeed, growthRawScripts); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	simulateGrowthCmd.Flags().IntVar(&growthSteps, "steps", 10, "Number of trees built, at evenly spaced leaf counts up to the number of leaves")
+	simulateGrowthCmd.Flags().Int64Var(&growthSeed, "seed", 1, "Seed of all the trees")
+	simulateGrowthCmd.Flags().BoolVar(&growthRawScripts, "raw-scripts", false, "Use 34 random bytes as leaf scripts instead of valid P2TR scripts (faster, the sizes are the same)")
+
+	rootCmd.AddCommand(simulateGrowthCmd)
+}
+
+// growthStep is the tree built at a step of simulate-growth
+type growthStep struct {
+	leaves, depth, nodes int
+	maxBranchWeight      float64
+}
+
+// growthLeafCounts returns the leaf counts of steps evenly spaced steps up
+// to numLeaves, rounded up so that the last one is numLeaves
+func growthLeafCounts(numLeaves, steps int) []int {
+	counts := make([]int, steps)
+	for i := range counts {
+		counts[i] = ((i+1)*numLeaves + steps - 1) / steps
+	}
+	return counts
+}
+
+// measureGrowth builds the tree of every step with seed and measures it
+func measureGrowth(numLeaves, steps int, seed int64, rawScripts bool) ([]growthStep, error) {
+	var series []growthStep
+	for _, count := range growthLeafCounts(numLeaves, steps) {
+		generation, err := arktree.Generate(arktree.GenerateOptions{
+			NumLeaves:  count,
+			RawScripts: rawScripts,
+			Seed:       &seed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", count, err)
+		}
+		nodes, err := arktree.NumberOfNodes(generation.Tree)
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", count, err)
+		}
+		weights, err := arktree.WeightOfBranches(generation.Tree, false)
+		if err != nil {
+			return nil, fmt.Errorf("tree of %d leaves: %w", count, err)
+		}
+		series = append(series, growthStep{
+			leaves:          count,
+			depth:           arktree.TreeDepth(generation.Tree),
+			nodes:           nodes,
+			maxBranchWeight: arktree.MaxFloat(weights),
+		})
+	}
+	return series, nil
+}
+
+// writeGrowth writes the series of simulate-growth as CSV, a line per step
+func writeGrowth(w io.Writer, numLeaves, steps int, seed int64, rawScripts bool) error {
+	series, err := measureGrowth(numLeaves, steps, seed, rawScripts)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"step", "leaves", "depth", "nodes", "max_branch_weight"}); err != nil {
+		return err
+	}
+	for i, step := range series {
+		if err := cw.Write([]string{
+			strconv.Itoa(i + 1),
+			strconv.Itoa(step.leaves),
+			strconv.Itoa(step.depth),
+			strconv.Itoa(step.nodes),
+			strconv.FormatFloat(step.maxBranchWeight, 'f', 4, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
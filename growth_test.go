@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestMeasureGrowth(t *testing.T) {
+	series, err := measureGrowth(6, 4, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int
+	for _, step := range series {
+		counts = append(counts, step.leaves)
+		if best, _ := arktree.BranchSizeBounds(step.leaves); step.nodes != 2*step.leaves-1 || step.depth != best {
+			t.Errorf("%d leaves: %d txs, depth %d", step.leaves, step.nodes, step.depth)
+		}
+		if step.maxBranchWeight <= 0 || step.maxBranchWeight > float64(step.depth) {
+			t.Errorf("%d leaves: max branch weight %g for depth %d", step.leaves, step.maxBranchWeight, step.depth)
+		}
+	}
+	// the steps end at the leaf count
+	if !slices.Equal(counts, []int{2, 3, 5, 6}) {
+		t.Errorf("leaf counts %v", counts)
+	}
+}
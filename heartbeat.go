@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// startHeartbeat prints the time elapsed since start to w every interval
+// until the returned function is called, so that a long BuildVtxoTree call,
+// which reports no progress, doesn't look stuck. interval <= 0 disables it.
+func startHeartbeat(w io.Writer, interval time.Duration, start time.Time) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fmt.Fprintf(w, "⏳ Still building... %s elapsed\n", time.Since(start).Round(min(interval, time.Second)))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// maxHTMLTreeNodes is the biggest tree drawn in the HTML report, bigger ones
+// would make an unreadable diagram
+const maxHTMLTreeNodes = 511
+
+// htmlMetric is a row of the statistics table of the HTML report
+type htmlMetric struct {
+	Name  string
+	Value string
+}
+
+// htmlBar is a bar of an HTML histogram, Width in percent of the longest
+type htmlBar struct {
+	Label string
+	Count int
+	Width float64
+}
+
+// htmlHistogram is a distribution drawn as horizontal SVG bars
+type htmlHistogram struct {
+	Title  string
+	Bars   []htmlBar
+	Height int
+}
+
+// htmlTree is the diagram of the tree, node coordinates in SVG units
+type htmlTree struct {
+	Width, Height float64
+	Nodes         []htmlTreeNode
+	Edges         []htmlTreeEdge
+}
+
+type htmlTreeNode struct {
+	X, Y float64
+	Txid string
+	Leaf bool
+	Fill string // overrides the color of the node class if set
+}
+
+type htmlTreeEdge struct {
+	X1, Y1, X2, Y2 float64
+}
+
+// htmlReport is the data of htmlTemplate
+type htmlReport struct {
+	Metrics    []htmlMetric
+	Histograms []htmlHistogram
+	Tree       *htmlTree // nil if the tree has more than maxHTMLTreeNodes nodes
+	TreeNodes  int
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"barY":     func(i int) int { return i * 18 },
+	"barWidth": func(percent float64) float64 { return percent * 4.5 },
+	"add":      func(a, b float64) float64 { return a + b },
+	"maxNodes": func() int { return maxHTMLTreeNodes },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Ark tree report</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; }
+td { padding: 0.2em 1em; border-bottom: 1px solid #ddd; }
+td.value { text-align: right; font-family: monospace; }
+svg text { font-family: monospace; font-size: 11px; }
+.bar { fill: #4a7ebb; }
+.edge { stroke: #999; }
+.node { fill: #4a7ebb; }
+.leaf { fill: #6aa84f; }
+</style>
+</head>
+<body>
+<h1>Ark tree report</h1>
+
+<h2>Statistics</h2>
+<table>
+{{- range .Metrics}}
+<tr><td>{{.Name}}</td><td class="value">{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{range .Histograms}}
+<h2>{{.Title}}</h2>
+<svg width="600" height="{{.Height}}" role="img">
+{{- range $i, $bar := .Bars}}
+<text x="0" y="{{barY $i}}" dy="12">{{$bar.Label}}</text>
+<rect class="bar" x="80" y="{{barY $i}}" width="{{$bar.Width | barWidth}}" height="14"><title>{{$bar.Label}}: {{$bar.Count}}</title></rect>
+<text x="{{$bar.Width | barWidth | add 85}}" y="{{barY $i}}" dy="12">{{$bar.Count}}</text>
+{{- end}}
+</svg>
+{{end}}
+<h2>Tree</h2>
+{{- with .Tree}}
+<svg width="{{.Width}}" height="{{.Height}}" role="img">
+{{- range .Edges}}
+<line class="edge" x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+{{- end}}
+{{- range .Nodes}}
+<circle class="{{if .Leaf}}leaf{{else}}node{{end}}" cx="{{.X}}" cy="{{.Y}}" r="5"{{with .Fill}} style="fill: {{.}}"{{end}}><title>{{.Txid}}</title></circle>
+{{- end}}
+</svg>
+{{- else}}
+<p>The tree has {{.TreeNodes}} transactions, too many to draw (at most {{maxNodes}}).</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// writeHTML writes a self-contained HTML report of the statistics and, if it
+// is small enough, a diagram of txtree whose nodes of fills are filled with
+// their color
+func writeHTML(w io.Writer, report statsReport, txtree *tree.TxGraph, fills map[*tree.TxGraph]string) error {
+	data := htmlReport{
+		Metrics: []htmlMetric{
+			{"Leaves", strconv.Itoa(report.Leaves)},
+			{"Total transactions", strconv.Itoa(report.TotalTransactions)},
+			{"Biggest branch", fmt.Sprintf("%.0f tx", report.BranchSizes.Max)},
+			{"Average branch size", fmt.Sprintf("%.1f tx", report.BranchSizes.Mean)},
+			{"Most tx to broadcast", fmt.Sprintf("%.2f", report.BroadcastWeights.Max)},
+			{"Average tx to broadcast", fmt.Sprintf("%.2f", report.BroadcastWeights.Mean)},
+			{"Balance", fmt.Sprintf("%.2f", report.Balance)},
+			{"Amortization", fmt.Sprintf("%.2f", report.Amortization)},
+			{"Branching factor", fmt.Sprintf("%.2f", report.BranchingFactor)},
+			{"Size on wire", fmt.Sprintf("%d bytes", report.SizeOnWire)},
+			{"Storage per VTXO", fmt.Sprintf("%.1f bytes", report.StoragePerVtxo.Total)},
+			{"Total value", fmt.Sprintf("%d sats", report.TotalValue)},
+		},
+		Histograms: []htmlHistogram{
+			newHTMLHistogram("Branch sizes", report.BranchSizes.Counts),
+			newHTMLHistogram("Tx to broadcast", report.BroadcastWeights.Counts),
+		},
+	}
+
+	var err error
+	data.TreeNodes, err = arktree.NumberOfNodes(txtree)
+	if err != nil {
+		return err
+	}
+	if data.TreeNodes <= maxHTMLTreeNodes {
+		data.Tree = newHTMLTree(txtree, fills)
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+// newHTMLHistogram returns the bars of the counts of a distribution, ordered
+// by value
+func newHTMLHistogram(title string, counts map[string]int) htmlHistogram {
+	labels := make([]string, 0, len(counts))
+	longest := 0
+	for label, count := range counts {
+		labels = append(labels, label)
+		longest = max(longest, count)
+	}
+	slices.SortFunc(labels, func(a, b string) int {
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	histogram := htmlHistogram{Title: title, Height: len(labels) * 18}
+	for _, label := range labels {
+		histogram.Bars = append(histogram.Bars, htmlBar{
+			Label: label,
+			Count: counts[label],
+			Width: float64(counts[label]) / float64(longest) * 100,
+		})
+	}
+	return histogram
+}
+
+// newHTMLTree lays out g top down: leaves are spread evenly in output index
+// order and each parent is centered above its children, filled with its color
+// in fills if any
+func newHTMLTree(g *tree.TxGraph, fills map[*tree.TxGraph]string) *htmlTree {
+	const (
+		margin   = 10.0
+		leafGap  = 16.0
+		levelGap = 40.0
+	)
+
+	diagram := &htmlTree{}
+	leaves := 0
+	var place func(node *tree.TxGraph, depth int) (x, y float64)
+	place = func(node *tree.TxGraph, depth int) (x, y float64) {
+		y = margin + float64(depth-1)*levelGap
+		if len(node.Children) == 0 {
+			x = margin + float64(leaves)*leafGap
+			leaves++
+		} else {
+			indexes := make([]uint32, 0, len(node.Children))
+			for index := range node.Children {
+				indexes = append(indexes, index)
+			}
+			slices.Sort(indexes)
+
+			edges := make([]htmlTreeEdge, 0, len(indexes))
+			for _, index := range indexes {
+				childX, childY := place(node.Children[index], depth+1)
+				edges = append(edges, htmlTreeEdge{X2: childX, Y2: childY})
+				x += childX
+			}
+			x /= float64(len(indexes))
+			for _, edge := range edges {
+				edge.X1, edge.Y1 = x, y
+				diagram.Edges = append(diagram.Edges, edge)
+			}
+		}
+
+		diagram.Nodes = append(diagram.Nodes, htmlTreeNode{X: x, Y: y, Txid: node.Root.UnsignedTx.TxID(), Leaf: len(node.Children) == 0, Fill: fills[node]})
+		diagram.Width = max(diagram.Width, x+margin)
+		diagram.Height = max(diagram.Height, y+margin)
+		return x, y
+	}
+	place(g, 1)
+
+	return diagram
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultInfluxMeasurement is the default --influx-measurement of the influx
+// output
+const defaultInfluxMeasurement = "arktree"
+
+var (
+	// influxMeasurement and influxTags are the --influx-measurement and
+	// --influx-tag flags of generate
+	influxMeasurement string
+	influxTags        []string
+)
+
+// influxTag is a tag of the influx output
+type influxTag struct {
+	key, value string
+}
+
+// parseInfluxTags checks --influx-measurement and parses the key=value
+// --influx-tag flags, which only apply to the influx output. leaves is set by
+// the output itself.
+func parseInfluxTags(measurement string, tags []string, changed bool) ([]influxTag, error) {
+	if (changed || len(tags) > 0) && !hasOutput(outputInflux) {
+		return nil, errors.New("--influx-measurement and --influx-tag require --output influx")
+	}
+	if measurement == "" {
+		return nil, errors.New("--influx-measurement can't be empty")
+	}
+
+	parsed := make([]influxTag, 0, len(tags))
+	seen := map[string]bool{"leaves": true}
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid --influx-tag %q (expected key=value)", tag)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("--influx-tag %s is given twice or set by the output", key)
+		}
+		seen[key] = true
+		parsed = append(parsed, influxTag{key, value})
+	}
+	return parsed, nil
+}
+
+var (
+	// influxMeasurementEscaper escapes a measurement, influxKeyEscaper a tag
+	// key, a tag value or a field key of the line protocol
+	influxMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	influxKeyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// writeInflux writes the headline statistics as a record of the InfluxDB line
+// protocol at timestamp, in nanoseconds: the measurement, the leaves then
+// tags as tags sorted by key, as InfluxDB prefers them, and a field per
+// statistic, the counts being integers
+func writeInflux(w io.Writer, report statsReport, measurement string, tags []influxTag, timestamp time.Time) error {
+	tags = append([]influxTag{{"leaves", strconv.Itoa(report.Leaves)}}, tags...)
+	sort.Slice(tags, func(i, j int) bool { return tags[i].key < tags[j].key })
+
+	integer := func(v int64) string { return strconv.FormatInt(v, 10) + "i" }
+	float := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	fields := [][2]string{
+		{"nodes", integer(int64(report.TotalTransactions))},
+		{"depth", integer(int64(report.BranchSizes.Max) + int64(report.ExcludedBranchTxs))},
+		{"mean_branch_size", float(report.BranchSizes.Mean)},
+		{"max_weight", float(report.BroadcastWeights.Max)},
+		{"mean_weight", float(report.BroadcastWeights.Mean)},
+		{"median_weight", float(report.BroadcastWeights.Median)},
+		{"balance", float(report.Balance)},
+		{"size_on_wire", integer(int64(report.SizeOnWire))},
+		{"total_value", integer(report.TotalValue)},
+		{"tree_fees", integer(report.TreeFees)},
+	}
+	if times := report.ExitTimes; times != nil {
+		fields = append(fields,
+			[2]string{"exit_time_min", float(times.Min)},
+			[2]string{"exit_time_median", float(times.Median)},
+			[2]string{"exit_time_max", float(times.Max)})
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(influxMeasurementEscaper.Replace(measurement))
+	for _, tag := range tags {
+		fmt.Fprintf(bw, ",%s=%s", influxKeyEscaper.Replace(tag.key), influxKeyEscaper.Replace(tag.value))
+	}
+	for i, field := range fields {
+		separator := ","
+		if i == 0 {
+			separator = " "
+		}
+		fmt.Fprintf(bw, "%s%s=%s", separator, field[0], field[1])
+	}
+	fmt.Fprintf(bw, " %d\n", timestamp.UnixNano())
+	return bw.Flush()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteInflux(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	var b strings.Builder
+	timestamp := time.Unix(1700000000, 5)
+	tags := []influxTag{{"zone", "eu west"}, {"env", "a,b"}}
+	if err := writeInflux(&b, newStatsReport(stats, nil), "ark trees", tags, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	line := b.String()
+	prefix := fmt.Sprintf(`ark\ trees,env=a\,b,leaves=%d,zone=eu\ west nodes=%di,depth=%di,`, stats.NumLeaves, stats.TotalSize, stats.BiggestBranch())
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, " 1700000000000000005\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("unexpected record %q, expected it to start with %q", line, prefix)
+	}
+}
+
+func TestParseInfluxTagsRejectsOutputTags(t *testing.T) {
+	setFlag(t, &outputs, []outputTarget{{format: outputInflux}})
+	if _, err := parseInfluxTags(defaultInfluxMeasurement, []string{"leaves=1"}, false); err == nil || !strings.Contains(err.Error(), "set by the output") {
+		t.Errorf("expected the leaves tag to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ark-network/ark/common"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var inspectLeaf string
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [tree-file]",
+	Short: "Break down the broadcast weight of an exported tree by level",
+	Long: `Import a tree exported with "generate --out" and print the broadcast weight contributed by each level of the tree, the root level first, to see whether the shared nodes at the top or the unshared ones at the bottom dominate the exit cost.
+
+With --leaf, the breakdown is the one of the branch of that leaf, with the txid of its node at each level. Otherwise it is averaged over all the branches, so that the levels add up to the average tx to broadcast.
+
+The exit delay is the relative locktime to wait before the first exit tx of the branch can be broadcast, the range over the branches without --leaf.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		leaves := arktree.LeafTxids(txtree)
+		expiries, err := arktree.BranchExpiries(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to get the exit locktimes: %s\n", err)
+			os.Exit(1)
+		}
+		if inspectLeaf != "" {
+			i := slices.Index(leaves, inspectLeaf)
+			if i < 0 {
+				fmt.Printf("Error: %s is not a leaf of the tree\n", inspectLeaf)
+				os.Exit(1)
+			}
+			leaves, expiries = leaves[i:i+1], expiries[i:i+1]
+		}
+
+		// levels[i] sums the weight of level i+1 over the branches, reached counts
+		// the branches deep enough to have a node at that level
+		var levels []float64
+		var reached []int
+		var path []string
+		for _, leaf := range leaves {
+			branch, err := txtree.SubGraph([]string{leaf})
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to extract branch: %s\n", err)
+				os.Exit(1)
+			}
+
+			weights, err := arktree.BroadcastWeightByLevel(branch, withAnchors, excludeNUMS)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to get weight by level: %s\n", err)
+				os.Exit(1)
+			}
+			for i, weight := range weights {
+				if i == len(levels) {
+					levels = append(levels, 0)
+					reached = append(reached, 0)
+				}
+				levels[i] += weight
+				reached[i]++
+			}
+
+			if inspectLeaf != "" {
+				for _, node := range branchPath(branch) {
+					path = append(path, node.Root.UnsignedTx.TxID())
+				}
+			}
+		}
+
+		var total float64
+		for i := range levels {
+			levels[i] /= float64(len(leaves))
+			total += levels[i]
+		}
+
+		if inspectLeaf != "" {
+			fmt.Printf("\n🔍 BROADCAST WEIGHT BY LEVEL (branch of %s):\n", inspectLeaf)
+		} else {
+			fmt.Printf("\n🔍 BROADCAST WEIGHT BY LEVEL (average of %d branches):\n", len(leaves))
+		}
+		fmt.Println(strings.Repeat("─", 40))
+		// txids are left aligned, branch counts right aligned
+		t := newTable(os.Stdout, true, inspectLeaf == "", true, true)
+		if inspectLeaf != "" {
+			t.row("Level", "Txid", "Weight", "Share")
+		} else {
+			t.row("Level", "Branches", "Weight", "Share")
+		}
+		short := txidShortener(os.Stdout, broadcastOrder(txtree))
+		for i, weight := range levels {
+			second := strconv.Itoa(reached[i])
+			if inspectLeaf != "" {
+				second = short(path[i])
+			}
+			t.row(strconv.Itoa(i+1), second, fmt.Sprintf("%.2f", weight), fmt.Sprintf("%.1f%%", weight/total*100))
+		}
+		t.flush()
+		fmt.Println(strings.Repeat("─", 40))
+		fmt.Printf("📡 Tx to Broadcast:       %8.2f\n", total)
+		fmt.Printf("⏱️ Exit Delay:            %s\n", describeExitDelays(expiries, blockInterval))
+	},
+}
+
+func init() {
+	inspectCmd.Flags().StringVar(&inspectLeaf, "leaf", "", "Txid of the leaf whose branch is broken down, all the branches if empty")
+	inspectCmd.Flags().BoolVar(&withAnchors, "with-anchors", false, "Include the CPFP child spending each tx's anchor output")
+	inspectCmd.Flags().BoolVar(&excludeNUMS, "exclude-nums", false, "Leave the NUMS point, an unspendable key no one signs with, out of the cosigners sharing the broadcast weights")
+	inspectCmd.Flags().DurationVar(&blockInterval, "block-interval", arktree.DefaultBlockInterval, "Time between two blocks used to convert a locktime in blocks to a delay")
+
+	rootCmd.AddCommand(inspectCmd)
+}
+
+// describeExitDelays describes the relative locktimes of branches before
+// their exit can start, with how long they last: the locktime shared by all
+// the branches, or the shortest and the longest
+func describeExitDelays(expiries []*common.RelativeLocktime, blockInterval time.Duration) string {
+	var shortest, longest *common.RelativeLocktime
+	for _, expiry := range expiries {
+		if expiry == nil {
+			return "none, some branches carry no locktime"
+		}
+		if shortest == nil || arktree.LocktimeDuration(*expiry, blockInterval) < arktree.LocktimeDuration(*shortest, blockInterval) {
+			shortest = expiry
+		}
+		if longest == nil || arktree.LocktimeDuration(*expiry, blockInterval) > arktree.LocktimeDuration(*longest, blockInterval) {
+			longest = expiry
+		}
+	}
+	if shortest == nil {
+		return "none"
+	}
+
+	describe := func(locktime common.RelativeLocktime) string {
+		return fmt.Sprintf("%d %ss (%s)", locktime.Value, locktimeTypeName(locktime), arktree.LocktimeDuration(locktime, blockInterval))
+	}
+	if *shortest == *longest {
+		return describe(*shortest)
+	}
+	return describe(*shortest) + " to " + describe(*longest)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// validateKeysOutput checks --keys-output against the other generation flags
+func validateKeysOutput() error {
+	if len(leavesFiles) > 0 {
+		return fmt.Errorf("--keys-output can't be used with --leaves-file, whose private keys aren't known")
+	}
+	return nil
+}
+
+// writeKeysFile writes the private keys as hex, one per line in leaf order, to
+// a file only readable by its owner
+func writeKeysFile(path string, keys []*secp256k1.PrivateKey) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// an existing file keeps its mode when opened
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(w, hex.EncodeToString(key.Serialize())); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readKeysFile reads private keys written by writeKeysFile, one hex key per
+// line, skipping blank lines
+func readKeysFile(path string) ([]*secp256k1.PrivateKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []*secp256k1.PrivateKey
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		b, err := hex.DecodeString(text)
+		if err != nil || len(b) != secp256k1.PrivKeyBytesLen {
+			return nil, fmt.Errorf("line %d: not a %d byte hex private key", line, secp256k1.PrivKeyBytesLen)
+		}
+		keys = append(keys, secp256k1.PrivKeyFromBytes(b))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no private key in %s", path)
+	}
+	return keys, nil
+}
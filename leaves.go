@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+// leafInput is the JSON representation of a leaf in a leaves file:
+//
+//	[{"script": "<hex>", "amount": 1000, "cosigners": ["<hex compressed pubkey>"], "weight": 1.5, "label": "alice", "round_id": "r1"}]
+//
+// weight, label and round_id are optional and don't affect the tree:
+// BuildVtxoTree doesn't place leaves by weight so it is only used to report
+// how the placement correlates with it, label is carried to the per-branch
+// output and round_id splits the leaves in one tree per round with
+// --partition-by round_id. The script, amount and cosigners can be read from
+// other keys, see leafFieldNames.
+type leafInput struct {
+	Script    string   `json:"script"`
+	Amount    uint64   `json:"amount"`
+	Cosigners []string `json:"cosigners"`
+	Weight    *float64 `json:"weight,omitempty"`
+	Label     string   `json:"label,omitempty"`
+	RoundID   string   `json:"round_id,omitempty"`
+}
+
+// leafFieldNames are the JSON keys holding the script, amount and cosigners
+// of the leaves of a leaves file, set by the --leaf-*-field flags for files
+// whose schema names them otherwise
+type leafFieldNames struct {
+	script, amount, cosigners string
+}
+
+// defaultLeafFields are the keys of leafInput
+var defaultLeafFields = leafFieldNames{script: "script", amount: "amount", cosigners: "cosigners"}
+
+// leafFields are the keys the leaves files are read with
+var leafFields = defaultLeafFields
+
+// addLeafFieldFlags registers the flags naming the keys of the leaves files on
+// cmd
+func addLeafFieldFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&leafFields.script, "leaf-script-field", defaultLeafFields.script, "JSON key of the script of the leaves in the leaves file")
+	cmd.Flags().StringVar(&leafFields.amount, "leaf-amount-field", defaultLeafFields.amount, "JSON key of the amount of the leaves in the leaves file, e.g. value_sats")
+	cmd.Flags().StringVar(&leafFields.cosigners, "leaf-cosigners-field", defaultLeafFields.cosigners, "JSON key of the cosigners of the leaves in the leaves file")
+}
+
+// leafField is a key of leafInput and the key of the leaves files mapped to
+// it, with the flag mapping them
+type leafField struct {
+	name, key, flag string
+}
+
+// mapped returns the keys of f that differ from the default ones
+func (f leafFieldNames) mapped() []leafField {
+	var fields []leafField
+	for _, field := range f.all() {
+		if field.key != field.name {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// all returns every field of f
+func (f leafFieldNames) all() []leafField {
+	return []leafField{
+		{name: defaultLeafFields.script, key: f.script, flag: "leaf-script-field"},
+		{name: defaultLeafFields.amount, key: f.amount, flag: "leaf-amount-field"},
+		{name: defaultLeafFields.cosigners, key: f.cosigners, flag: "leaf-cosigners-field"},
+	}
+}
+
+// validate checks that the keys are set and distinct
+func (f leafFieldNames) validate() error {
+	flags := make(map[string]string)
+	for _, field := range f.all() {
+		if field.key == "" {
+			return fmt.Errorf("--%s can't be empty", field.flag)
+		}
+		if flag, ok := flags[field.key]; ok {
+			return fmt.Errorf("--%s and --%s both name the field %q", flag, field.flag, field.key)
+		}
+		flags[field.key] = field.flag
+	}
+	return nil
+}
+
+// unmarshalLeafInputs decodes the JSON array of leaves of a leaves file, its
+// keys mapped by leafFields: each leaf must have the mapped keys, which
+// replace the default ones
+func unmarshalLeafInputs(data []byte) ([]leafInput, error) {
+	var inputs []leafInput
+	mapped := leafFields.mapped()
+	if len(mapped) == 0 {
+		if err := json.Unmarshal(data, &inputs); err != nil {
+			return nil, fmt.Errorf("invalid leaves file: %w", err)
+		}
+		return inputs, nil
+	}
+
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid leaves file: %w", err)
+	}
+	inputs = make([]leafInput, len(entries))
+	for i, entry := range entries {
+		// all the values are taken before any key is replaced, as a mapped key
+		// can be the default one of another field
+		values := make([]json.RawMessage, len(mapped))
+		for j, field := range mapped {
+			value, ok := entry[field.key]
+			if !ok {
+				return nil, &arktree.LeafError{Index: i, Err: fmt.Errorf("missing field %q (--%s)", field.key, field.flag)}
+			}
+			values[j] = value
+			delete(entry, field.key)
+		}
+		for j, field := range mapped {
+			entry[field.name] = values[j]
+		}
+
+		remapped, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(remapped, &inputs[i]); err != nil {
+			return nil, fmt.Errorf("invalid leaves file: %w", &arktree.LeafError{Index: i, Err: err})
+		}
+	}
+	return inputs, nil
+}
+
+// leafSet is the content of one or several leaves files, concatenated
+type leafSet struct {
+	leaves []tree.Leaf
+	// weights is nil if no leaf sets a weight, otherwise missing weights are 0
+	weights []float64
+	// labels is nil if no leaf sets a label
+	labels []string
+	// rounds is nil if no leaf sets a round id
+	rounds []string
+	// excluded is the number of leaves left out for their ignored amount
+	excluded int
+	// indexes is the position in the file of each leaf, excluded ones included,
+	// the files of a merged set counting as a single one
+	indexes []int
+	// files are the leaves files the set was read from, in order
+	files []leavesFileCount
+}
+
+// leavesFileCount is what a leaves file contributed to a leafSet
+type leavesFileCount struct {
+	source   string
+	entries  int // leaves of the file, excluded ones included
+	leaves   int
+	excluded int
+}
+
+// loadLeaves reads the JSON array of leaves at path, "-" reads it from stdin.
+// Leaves whose amount is ignoredAmount, if not nil, are left out without
+// being validated, indexes in errors still refer to the file.
+func loadLeaves(path string, ignoredAmount *uint64) (*leafSet, error) {
+	data, err := readLeavesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeLeaves(data, leavesSource(path), ignoredAmount)
+}
+
+// readLeavesFile reads the leaves file at path, "-" reads it from stdin
+func readLeavesFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// decodeLeaves decodes the JSON array of leaves of a leaves file, see
+// loadLeaves; source names the file in errors
+func decodeLeaves(data []byte, source string, ignoredAmount *uint64) (*leafSet, error) {
+	inputs, err := unmarshalLeafInputs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no leaves provided in %s, at least one is needed to build a tree", source)
+	}
+
+	set := &leafSet{
+		leaves:  make([]tree.Leaf, 0, len(inputs)),
+		weights: make([]float64, 0, len(inputs)),
+		labels:  make([]string, 0, len(inputs)),
+		rounds:  make([]string, 0, len(inputs)),
+	}
+	hasWeights, hasLabels, hasRounds := false, false, false
+	for i, input := range inputs {
+		if ignoredAmount != nil && input.Amount == *ignoredAmount {
+			set.excluded++
+			continue
+		}
+
+		if err := input.validate(); err != nil {
+			return nil, &arktree.LeafError{Index: i, Err: err}
+		}
+
+		weight := 0.0
+		if input.Weight != nil {
+			weight = *input.Weight
+			hasWeights = true
+		}
+		set.weights = append(set.weights, weight)
+
+		if input.Label != "" {
+			hasLabels = true
+		}
+		set.labels = append(set.labels, input.Label)
+
+		if input.RoundID != "" {
+			hasRounds = true
+		}
+		set.rounds = append(set.rounds, input.RoundID)
+
+		set.indexes = append(set.indexes, i)
+		set.leaves = append(set.leaves, tree.Leaf{
+			Script:              input.Script,
+			Amount:              input.Amount,
+			CosignersPublicKeys: input.Cosigners,
+		})
+	}
+
+	if len(set.leaves) == 0 && set.excluded > 0 {
+		return nil, fmt.Errorf("all %d leaves have the ignored amount %d, at least one must remain", set.excluded, *ignoredAmount)
+	}
+
+	set.files = []leavesFileCount{{source: source, entries: len(inputs), leaves: len(set.leaves), excluded: set.excluded}}
+
+	if !hasWeights {
+		set.weights = nil
+	}
+	if !hasLabels {
+		set.labels = nil
+	}
+	if !hasRounds {
+		set.rounds = nil
+	}
+
+	return set, nil
+}
+
+// loadLeavesFiles reads the leaves files at paths, see loadLeaves, and
+// concatenates their leaves in the order given
+func loadLeavesFiles(paths []string, ignoredAmount *uint64) (*leafSet, error) {
+	if err := checkStdinOnce(paths); err != nil {
+		return nil, err
+	}
+
+	sets := make([]*leafSet, 0, len(paths))
+	for _, path := range paths {
+		set, err := loadLeaves(path, ignoredAmount)
+		if err != nil {
+			if len(paths) > 1 {
+				return nil, fmt.Errorf("%s: %w", leavesSource(path), err)
+			}
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return mergeLeaves(sets), nil
+}
+
+// checkLeavesFiles is loadLeavesFiles reporting every invalid leaf of the
+// files instead of stopping at the first, each error naming its file. The set
+// is nil if there is any error.
+func checkLeavesFiles(paths []string, ignoredAmount *uint64) (*leafSet, []error) {
+	if err := checkStdinOnce(paths); err != nil {
+		return nil, []error{err}
+	}
+
+	var (
+		sets []*leafSet
+		errs []error
+	)
+	for _, path := range paths {
+		source := leavesSource(path)
+		data, err := readLeavesFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+
+		// a leaf missing a mapped field stops the check of its file: it means
+		// a wrong flag far more often than a bad leaf, and would be reported
+		// for every leaf
+		inputs, err := unmarshalLeafInputs(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		invalid := 0
+		for i, input := range inputs {
+			if ignoredAmount != nil && input.Amount == *ignoredAmount {
+				continue
+			}
+			if err := input.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", source, &arktree.LeafError{Index: i, Err: err}))
+				invalid++
+			}
+		}
+		if invalid > 0 {
+			continue
+		}
+
+		set, err := decodeLeaves(data, source, ignoredAmount)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		sets = append(sets, set)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return mergeLeaves(sets), nil
+}
+
+// checkStdinOnce fails if several of paths are stdin, which can only be read
+// once
+func checkStdinOnce(paths []string) error {
+	stdin := 0
+	for _, path := range paths {
+		if path == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return fmt.Errorf("stdin (\"-\") can only be read once, got it %d times", stdin)
+	}
+	return nil
+}
+
+// mergeLeaves concatenates sets. The weights, labels and rounds missing from
+// some of them are 0 and empty, and the indexes run on from a file to the next.
+func mergeLeaves(sets []*leafSet) *leafSet {
+	if len(sets) == 1 {
+		return sets[0]
+	}
+
+	merged := &leafSet{}
+	hasWeights, hasLabels, hasRounds := false, false, false
+	for _, set := range sets {
+		hasWeights = hasWeights || set.weights != nil
+		hasLabels = hasLabels || set.labels != nil
+		hasRounds = hasRounds || set.rounds != nil
+	}
+
+	offset := 0
+	for _, set := range sets {
+		merged.leaves = append(merged.leaves, set.leaves...)
+		if hasWeights {
+			merged.weights = append(merged.weights, orZeros(set.weights, len(set.leaves))...)
+		}
+		if hasLabels {
+			merged.labels = append(merged.labels, orZeros(set.labels, len(set.leaves))...)
+		}
+		if hasRounds {
+			merged.rounds = append(merged.rounds, orZeros(set.rounds, len(set.leaves))...)
+		}
+		for _, index := range set.indexes {
+			merged.indexes = append(merged.indexes, offset+index)
+		}
+		for _, file := range set.files {
+			offset += file.entries
+		}
+		merged.excluded += set.excluded
+		merged.files = append(merged.files, set.files...)
+	}
+	return merged
+}
+
+// orZeros returns values, or n zero values if it is nil
+func orZeros[T any](values []T, n int) []T {
+	if values == nil {
+		return make([]T, n)
+	}
+	return values
+}
+
+// sources names the files of the set, with the number of leaves of each if
+// there are several
+func (s *leafSet) sources() string {
+	if len(s.files) == 1 {
+		return s.files[0].source
+	}
+	names := make([]string, 0, len(s.files))
+	for _, file := range s.files {
+		names = append(names, fmt.Sprintf("%s (%d)", file.source, file.leaves))
+	}
+	return strings.Join(names, ", ")
+}
+
+// duplicateScripts returns the scripts shared by several leaves, indexed by
+// their position in the file
+func (s *leafSet) duplicateScripts() []arktree.DuplicateScript {
+	duplicates := arktree.DuplicateScripts(s.leaves)
+	for _, duplicate := range duplicates {
+		for i, index := range duplicate.Indexes {
+			duplicate.Indexes[i] = s.indexes[index]
+		}
+	}
+	return duplicates
+}
+
+// describeDuplicate describes duplicate, a duplicate script of the set, its
+// leaves named by file and position in the file if the set merges several
+func (s *leafSet) describeDuplicate(duplicate arktree.DuplicateScript) string {
+	if len(s.files) <= 1 {
+		return duplicate.String()
+	}
+
+	positions := make([]string, 0, len(duplicate.Indexes))
+	for _, index := range duplicate.Indexes {
+		for _, file := range s.files {
+			if index < file.entries {
+				positions = append(positions, fmt.Sprintf("%d of %s", index, file.source))
+				break
+			}
+			index -= file.entries
+		}
+	}
+	return fmt.Sprintf("leaves %s share the script %s", strings.Join(positions, ", "), duplicate.Script)
+}
+
+func (l leafInput) validate() error {
+	if _, err := hex.DecodeString(l.Script); err != nil {
+		return fmt.Errorf("invalid script: %w", err)
+	}
+
+	if l.Amount == 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	if len(l.Cosigners) == 0 {
+		return fmt.Errorf("missing cosigners")
+	}
+
+	for j, cosigner := range l.Cosigners {
+		key, err := hex.DecodeString(cosigner)
+		if err != nil {
+			return fmt.Errorf("cosigner %d: invalid hex: %w", j, err)
+		}
+		if len(key) != 33 {
+			return fmt.Errorf("cosigner %d: expected 33 bytes, got %d", j, len(key))
+		}
+		if _, err := secp256k1.ParsePubKey(key); err != nil {
+			return fmt.Errorf("cosigner %d: not a valid secp256k1 point: %w", j, err)
+		}
+	}
+
+	return nil
+}
+
+// leavesSource returns a human readable name of the leaves file path
+func leavesSource(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// offCurveKey is a compressed key whose x coordinate, 5, is not on secp256k1
+// since 5³ + 7 is not a square modulo p
+const offCurveKey = "020000000000000000000000000000000000000000000000000000000000000005"
+
+// testScripts are distinct leaf scripts
+var testScripts = [3]string{strings.Repeat("aa", 34), strings.Repeat("bb", 34), strings.Repeat("cc", 34)}
+
+// testCosigner returns the hex compressed public key of a fresh private key
+func testCosigner(t *testing.T) string {
+	t.Helper()
+	privkey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%x", privkey.PubKey().SerializeCompressed())
+}
+
+// leavesOf returns a leaf of 1000 sats per script
+func leavesOf(scripts ...string) []tree.Leaf {
+	leaves := make([]tree.Leaf, 0, len(scripts))
+	for _, script := range scripts {
+		leaves = append(leaves, tree.Leaf{Script: script, Amount: 1000})
+	}
+	return leaves
+}
+
+func TestLeafInputValidateCosigners(t *testing.T) {
+	key := testCosigner(t)
+	script := strings.Repeat("00", 34)
+	for _, test := range []struct {
+		name      string
+		cosigners []string
+		wantErr   string
+	}{
+		{"on-curve key", []string{key}, ""},
+		{"off-curve key", []string{key, offCurveKey}, "cosigner 1:"},
+	} {
+		err := leafInput{Script: script, Amount: 1000, Cosigners: test.cosigners}.validate()
+		if test.wantErr == "" && err != nil || test.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), test.wantErr)) {
+			t.Errorf("%s: error %v, expected %q", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestLeafSetDuplicateScripts(t *testing.T) {
+	a, b, c := testScripts[0], testScripts[1], testScripts[2]
+	// the leaf at index 2 of the file was excluded, and hex is case insensitive
+	set := &leafSet{
+		leaves:  leavesOf(a, b, strings.ToUpper(a), c, b),
+		indexes: []int{0, 1, 3, 4, 5},
+	}
+
+	var got []string
+	for _, duplicate := range set.duplicateScripts() {
+		got = append(got, duplicate.String())
+	}
+	want := []string{
+		"leaves 0, 3 share the script " + a,
+		"leaves 1, 5 share the script " + b,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("%q, expected %q", got, want)
+	}
+}
+
+func TestMergeLeaves(t *testing.T) {
+	a, b, c := testScripts[0], testScripts[1], testScripts[2]
+	first := &leafSet{
+		leaves:  leavesOf(a, b),
+		indexes: []int{0, 2}, // the leaf at index 1 was excluded
+		files:   []leavesFileCount{{source: "first.json", entries: 3, leaves: 2, excluded: 1}},
+	}
+	second := &leafSet{
+		leaves:  leavesOf(c, a),
+		indexes: []int{0, 1},
+		labels:  []string{"carol", "alice"},
+		files:   []leavesFileCount{{source: "second.json", entries: 2, leaves: 2}},
+	}
+	merged := mergeLeaves([]*leafSet{first, second})
+	if !slices.Equal(merged.indexes, []int{0, 2, 3, 4}) || !slices.Equal(merged.labels, []string{"", "", "carol", "alice"}) {
+		t.Errorf("indexes %v, labels %q", merged.indexes, merged.labels)
+	}
+	if sources := merged.sources(); sources != "first.json (2), second.json (2)" {
+		t.Errorf("sources %q", sources)
+	}
+	duplicates := merged.duplicateScripts()
+	if len(duplicates) != 1 {
+		t.Fatalf("%d duplicates, expected the script of the first and last leaves", len(duplicates))
+	}
+	if got, want := merged.describeDuplicate(duplicates[0]), "leaves 0 of first.json, 1 of second.json share the script "+a; got != want {
+		t.Errorf("%q, expected %q", got, want)
+	}
+}
+
+func TestDecodeLeavesEmpty(t *testing.T) {
+	_, err := decodeLeaves([]byte("[]"), "leaves.json", nil)
+	if err == nil || !strings.Contains(err.Error(), "no leaves provided in leaves.json") {
+		t.Errorf("got %v, expected no leaves provided in leaves.json", err)
+	}
+}
+
+func TestDecodeLeavesMappedFields(t *testing.T) {
+	a, b := testScripts[0], testScripts[1]
+	key := testCosigner(t)
+
+	// the amount is read from value_sats and the script from amount, which
+	// a default key would have read as the amount
+	setFlag(t, &leafFields, leafFieldNames{script: "amount", amount: "value_sats", cosigners: "keys"})
+	if err := leafFields.validate(); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`[{"amount": %q, "value_sats": 1000, "keys": [%q]}, {"amount": %q, "value_sats": 2000, "keys": [%q], "label": "bob"}]`, a, key, b, key)
+	set, err := decodeLeaves([]byte(data), "leaves.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.leaves) != 2 || set.leaves[0].Script != a || set.leaves[1].Amount != 2000 ||
+		!slices.Equal(set.leaves[1].CosignersPublicKeys, []string{key}) || set.labels[1] != "bob" {
+		t.Errorf("unexpected leaves %+v", set.leaves)
+	}
+
+	data = fmt.Sprintf(`[{"amount": %q, "value_sats": 1000, "keys": [%q]}, {"amount": %q, "sats": 2000, "keys": [%q]}]`, a, key, b, key)
+	var leafErr *arktree.LeafError
+	if _, err := decodeLeaves([]byte(data), "leaves.json", nil); !errors.As(err, &leafErr) || leafErr.Index != 1 ||
+		!strings.Contains(err.Error(), `missing field "value_sats" (--leaf-amount-field)`) {
+		t.Errorf("expected leaf 1 to miss value_sats, got %v", err)
+	}
+
+	shared := leafFieldNames{script: "script", amount: "script", cosigners: "cosigners"}
+	if err := shared.validate(); err == nil {
+		t.Error("expected the script and amount sharing a field to be rejected")
+	}
+}
+
+func TestCheckLeavesFiles(t *testing.T) {
+	a, b, c := testScripts[0], testScripts[1], testScripts[2]
+	key := testCosigner(t)
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	for path, content := range map[string]string{
+		valid:   fmt.Sprintf(`[{"script": %q, "amount": 1000, "cosigners": [%q]}]`, a, key),
+		invalid: fmt.Sprintf(`[{"script": "zz", "amount": 1000, "cosigners": [%q]}, {"script": %q, "amount": 0, "cosigners": [%q]}, {"script": %q, "amount": 1000, "cosigners": []}]`, key, b, key, c),
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if set, errs := checkLeavesFiles([]string{valid}, nil); len(errs) > 0 || len(set.leaves) != 1 {
+		t.Errorf("valid file: errors %v", errs)
+	}
+	set, errs := checkLeavesFiles([]string{valid, invalid}, nil)
+	if set != nil || len(errs) != 3 {
+		t.Fatalf("expected the 3 leaves of %s, got %v", invalid, errs)
+	}
+	for i, err := range errs {
+		var leafErr *arktree.LeafError
+		if !errors.As(err, &leafErr) || leafErr.Index != i || !strings.HasPrefix(err.Error(), invalid+": ") {
+			t.Errorf("error %d: %s", i, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ark-network/ark/common"
+)
+
+const (
+	locktimeTypeBlock  = "block"
+	locktimeTypeSecond = "second"
+)
+
+// parseLocktime returns the relative locktime for the --locktime-* flags.
+// BIP68 encodes seconds with a 512 seconds granularity: a value that isn't a
+// multiple of 512 is rejected, or rounded up to the next multiple if round is
+// set, in which case rounded reports it
+func parseLocktime(locktimeType string, value uint32, round bool) (locktime common.RelativeLocktime, rounded bool, err error) {
+	switch locktimeType {
+	case locktimeTypeBlock:
+		return common.RelativeLocktime{Value: value, Type: common.LocktimeTypeBlock}, false, nil
+	case locktimeTypeSecond:
+	default:
+		return common.RelativeLocktime{}, false, fmt.Errorf(
+			"unknown locktime type %q (expected %s or %s)", locktimeType, locktimeTypeBlock, locktimeTypeSecond,
+		)
+	}
+
+	if remainder := value % common.SECONDS_MOD; remainder != 0 {
+		if !round {
+			return common.RelativeLocktime{}, false, fmt.Errorf(
+				"locktime of %d seconds is not a multiple of %d, use --round-locktime to round it up to %d",
+				value, common.SECONDS_MOD, value+common.SECONDS_MOD-remainder,
+			)
+		}
+		value += common.SECONDS_MOD - remainder
+		rounded = true
+	}
+
+	locktime = common.RelativeLocktime{Value: value, Type: common.LocktimeTypeSecond}
+	if _, err := common.BIP68Sequence(locktime); err != nil {
+		return common.RelativeLocktime{}, false, err
+	}
+
+	return locktime, rounded, nil
+}
+
+// locktimeTypeName returns the --locktime-type of locktime
+func locktimeTypeName(locktime common.RelativeLocktime) string {
+	if locktime.Type == common.LocktimeTypeBlock {
+		return locktimeTypeBlock
+	}
+	return locktimeTypeSecond
+}
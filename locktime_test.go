@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestParseLocktimeSeconds(t *testing.T) {
+	for _, test := range []struct {
+		value       uint32
+		round       bool
+		want        uint32
+		wantRounded bool
+		wantErr     bool
+	}{
+		{512, false, 512, false, false},
+		{1024, false, 1024, false, false},
+		{1000, false, 0, false, true},
+		{1000, true, 1024, true, false},
+	} {
+		locktime, rounded, err := parseLocktime(locktimeTypeSecond, test.value, test.round)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%d seconds (round %t): error %v, expected one: %t", test.value, test.round, err, test.wantErr)
+			continue
+		}
+		if err == nil && (locktime.Value != test.want || rounded != test.wantRounded) {
+			t.Errorf("%d seconds (round %t): %d seconds (rounded %t), expected %d (%t)",
+				test.value, test.round, locktime.Value, rounded, test.want, test.wantRounded)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// runRecord is the one-line summary of a generate run appended by --log-json
+type runRecord struct {
+	Timestamp           time.Time         `json:"timestamp"`
+	Flags               map[string]string `json:"flags"`
+	Leaves              int               `json:"leaves"`
+	TotalTransactions   int               `json:"total_transactions"`
+	BiggestBranchSize   int               `json:"biggest_branch_size"`
+	AvgBranchSize       float64           `json:"avg_branch_size"`
+	MedianBranchSize    float64           `json:"median_branch_size"`
+	MostTxToBroadcast   float64           `json:"most_tx_to_broadcast"`
+	AvgTxToBroadcast    float64           `json:"avg_tx_to_broadcast"`
+	MedianTxToBroadcast float64           `json:"median_tx_to_broadcast"`
+	MostTxWithAnchors   float64           `json:"most_tx_with_anchors,omitempty"`
+	WeightCounts        map[string]int    `json:"weight_counts"`
+	SizeOnWire          int               `json:"size_on_wire"`
+	BuildTimeMs         float64           `json:"build_time_ms"`
+}
+
+// appendJSONLog appends the record as a single JSON line to the file at path,
+// creating it if needed. The file is locked while writing so that concurrent
+// runs logging to the same file don't interleave their lines.
+func appendJSONLog(path string, record runRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	if _, err := f.Write(line); err != nil {
+		return err
+	}
+	return f.Sync()
+}
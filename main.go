@@ -1,411 +1,1150 @@
 package main
 
 import (
-	"crypto/rand"
+	"cmp"
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/ark-network/ark/common"
 	"github.com/ark-network/ark/common/tree"
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/wire"
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/louisinger/arktree/pkg/arktree"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "arktree",
 	Short: "A CLI tool for generating Ark trees",
 	Long:  `Arktree is a command-line tool for generating and working with Ark trees.`,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		exitOnWarnings()
+	},
 }
 
 var generateCmd = &cobra.Command{
 	Use:   "generate [number-of-leaves]",
 	Short: "Generate an Ark tree with the specified number of leaves",
-	Long:  `Generate an Ark tree with the specified number of leaves. The number of leaves must be a positive integer.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Generate an Ark tree with the specified number of leaves. The number of leaves must be a positive integer.
+
+With --leaves-file, the leaves are loaded from a JSON file (or stdin with "-") instead of being generated randomly. Given several times, the leaves of all the files are concatenated in the order given, as when parties contribute their leaves to a joint round.
+With --ignore-amount, the leaves of the file with the given amount are left out of the tree.
+With --leaf-script-field, --leaf-amount-field and --leaf-cosigners-field, the script, amount and cosigners of the leaves are read from other keys of the file, which every leaf must have, e.g. --leaf-amount-field value_sats.
+With --validate-only, the leaves files are only checked: every invalid script, cosigner key and amount is reported, then the duplicate scripts, and the command exits with status 1 on any error, or on duplicates with --strict (or --werror), without building the tree.
+With --target-depth D, the number of leaves is that of the largest tree of depth D at most, 2^(D-1) as the tree is a balanced binary tree.
+With --time-budget, trees of 16 leaves, then 32, 64 and so on are built until the build time of the next one, extrapolated from the previous ones, would exceed the budget, all the builds counted: the statistics are those of the last tree built.`,
+	Args: cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
-		numLeaves, err := strconv.Atoi(args[0])
-		if err != nil {
-			fmt.Printf("Error: Invalid number of leaves: %s\n", args[0])
-			os.Exit(1)
-		}
+		var (
+			numLeaves    int
+			loadedLeaves *leafSet
+			samples      []uint64
+			err          error
+		)
 
-		if numLeaves <= 0 {
-			fmt.Println("Error: Number of leaves must be a positive integer")
-			os.Exit(1)
-		}
+		if len(leavesFiles) > 0 {
+			if cmd.Flags().Changed("target-depth") {
+				exitWithError(phaseValidation, errors.New("--target-depth can't be used with --leaves-file"),
+					"Error: --target-depth can't be used with --leaves-file\n")
+			}
+			if timeBudget != 0 {
+				exitWithError(phaseValidation, errors.New("--time-budget can't be used with --leaves-file"),
+					"Error: --time-budget can't be used with --leaves-file\n")
+			}
+			if len(args) > 0 {
+				exitWithError(phaseValidation, errors.New("number of leaves can't be used with --leaves-file"),
+					"Error: Number of leaves can't be used with --leaves-file\n")
+			}
+			if amountSamples != "" {
+				exitWithError(phaseValidation, errors.New("--amount-samples can't be used with --leaves-file, whose leaves have their amounts"),
+					"Error: --amount-samples can't be used with --leaves-file, whose leaves have their amounts\n")
+			}
+			if err := leafFields.validate(); err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
 
-		// Print header with styling
-		fmt.Println("🌳 Ark Tree Generator")
-		fmt.Println("=" + strings.Repeat("=", 50))
-		fmt.Printf("📊 Generating Ark tree with %d leaves...\n\n", numLeaves)
+			var ignoredAmount *uint64
+			if cmd.Flags().Changed("ignore-amount") {
+				ignoredAmount = &ignoreAmount
+			}
 
-		// Generate random data
-		fmt.Print("🔧 Initializing random data... ")
-		randomSweepTreeRoot := make([]byte, 32)
-		rand.Read(randomSweepTreeRoot)
+			if validateOnly {
+				var errs []error
+				loadedLeaves, errs = checkLeavesFiles(leavesFiles, ignoredAmount)
+				for _, err := range errs {
+					fmt.Printf("❌ %s\n", err)
+				}
+				if len(errs) > 0 {
+					exitWithError(phaseValidation, fmt.Errorf("%d error(s) in the leaves", len(errs)),
+						"❌ Error: %d error(s) in the leaves\n", len(errs))
+				}
+			} else {
+				loadedLeaves, err = loadLeavesFiles(leavesFiles, ignoredAmount)
+				if err != nil {
+					exitWithError(phaseLoad, err, "❌ Error: Failed to load leaves: %s\n", err)
+				}
+			}
+			numLeaves = len(loadedLeaves.leaves)
+
+			if duplicates := loadedLeaves.duplicateScripts(); len(duplicates) > 0 {
+				if strict {
+					err := fmt.Errorf("duplicate leaf script: %s", loadedLeaves.describeDuplicate(duplicates[0]))
+					if len(duplicates) > 1 {
+						err = fmt.Errorf("%w (and %d more duplicate scripts)", err, len(duplicates)-1)
+					}
+					exitWithError(phaseValidation, err, "Error: %s\n", err)
+				}
+				for _, duplicate := range duplicates {
+					warnf(os.Stderr, "%s", loadedLeaves.describeDuplicate(duplicate))
+				}
+			}
 
-		randomTxid := make([]byte, 32)
-		rand.Read(randomTxid)
-		fmt.Println("✅")
+			if validateOnly {
+				fmt.Printf("✅ %d valid leaves in %s\n", len(loadedLeaves.leaves), loadedLeaves.sources())
+				if loadedLeaves.excluded > 0 {
+					fmt.Printf("   %d left out for their amount of %d sats\n", loadedLeaves.excluded, ignoreAmount)
+				}
+				return
+			}
+		} else {
+			if cmd.Flags().Changed("ignore-amount") {
+				exitWithError(phaseValidation, errors.New("--ignore-amount requires --leaves-file"),
+					"Error: --ignore-amount requires --leaves-file\n")
+			}
+			if validateOnly {
+				exitWithError(phaseValidation, errors.New("--validate-only requires --leaves-file"),
+					"Error: --validate-only requires --leaves-file\n")
+			}
+			for _, field := range leafFields.all() {
+				if cmd.Flags().Changed(field.flag) {
+					err := fmt.Errorf("--%s requires --leaves-file", field.flag)
+					exitWithError(phaseValidation, err, "Error: %s\n", err)
+				}
+			}
 
-		// Generate leaves
-		fmt.Printf("🍃 Generating %d leaves... ", numLeaves)
-		leaves := make([]tree.Leaf, numLeaves)
+			if timeBudget != 0 {
+				var err error
+				switch {
+				case timeBudget < 0:
+					err = fmt.Errorf("--time-budget must be positive, got %s", timeBudget)
+				case len(args) > 0 || cmd.Flags().Changed("target-depth"):
+					err = errors.New("--time-budget picks the number of leaves, it can't be used with a number of leaves or --target-depth")
+				case cosignerGroups != 0 || seedsStdin || partitionBy != "":
+					err = errors.New("--time-budget builds a single tree, it can't be used with --cosigner-groups, --seeds-stdin or --partition-by")
+				}
+				if err != nil {
+					exitWithError(phaseValidation, err, "Error: %s\n", err)
+				}
+			} else if cmd.Flags().Changed("target-depth") {
+				if len(args) > 0 {
+					exitWithError(phaseValidation, errors.New("number of leaves can't be used with --target-depth"),
+						"Error: Number of leaves can't be used with --target-depth\n")
+				}
+				numLeaves, err = arktree.MaxLeavesForDepth(targetDepth)
+				if err != nil {
+					exitWithError(phaseValidation, err, "Error: %s\n", err)
+				}
+			} else {
+				if len(args) != 1 {
+					exitWithError(phaseValidation, errors.New("number of leaves is required"), "Error: Number of leaves is required\n")
+				}
 
-		for i := 0; i < numLeaves; i++ {
-			randomScript := make([]byte, 34)
-			rand.Read(randomScript)
+				numLeaves, err = strconv.Atoi(args[0])
+				if err != nil {
+					exitWithError(phaseValidation, fmt.Errorf("invalid number of leaves: %s", args[0]),
+						"Error: Invalid number of leaves: %s\n", args[0])
+				}
 
-			randomPrivkey, err := secp256k1.GeneratePrivateKey()
-			if err != nil {
-				fmt.Printf("\n❌ Error: Failed to generate private key: %s\n", err)
-				os.Exit(1)
+				if numLeaves <= 0 {
+					exitWithError(phaseValidation, errors.New("number of leaves must be a positive integer"),
+						"Error: Number of leaves must be a positive integer\n")
+				}
 			}
 
-			randomPubkey := randomPrivkey.PubKey()
+			if amountSamples != "" {
+				if cmd.Flags().Changed("amount") {
+					exitWithError(phaseValidation, errors.New("--amount can't be used with --amount-samples"),
+						"Error: --amount can't be used with --amount-samples\n")
+				}
+				samples, err = loadAmountSamples(amountSamples)
+				if err != nil {
+					exitWithError(phaseLoad, err, "❌ Error: Failed to load amount samples: %s\n", err)
+				}
+			}
+		}
 
-			leaves[i] = tree.Leaf{
-				Amount:              1000,
-				Script:              hex.EncodeToString(randomScript),
-				CosignersPublicKeys: []string{hex.EncodeToString(randomPubkey.SerializeCompressed())},
+		var leafGroups []string
+		if cosignerGroups != 0 {
+			if err := validateCosignerGroups(cosignerGroups, numLeaves); err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
 			}
+			leafGroups = cosignerGroupLabels(numLeaves, cosignerGroups)
 		}
-		fmt.Println("✅")
 
-		// Build tree
-		fmt.Print("🌿 Building Vtxo tree... ")
-		start := time.Now()
-		txtree, err := tree.BuildVtxoTree(
-			&wire.OutPoint{
-				Hash:  chainhash.Hash(randomTxid),
-				Index: 0,
-			},
-			leaves,
-			randomSweepTreeRoot,
-			common.RelativeLocktime{Value: 100, Type: common.LocktimeTypeBlock},
-		)
-		if err != nil {
-			fmt.Printf("\n❌ Error: Failed to build tree: %s\n", err)
-			os.Exit(1)
+		var cosignerSeedBytes []byte
+		if cmd.Flags().Changed("cosigner-seed") {
+			cosignerSeedBytes, err = parseCosignerSeed(cosignerSeed)
+			if err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
 		}
-		elapsed := time.Since(start)
-		fmt.Printf("✅ (%s)\n", elapsed)
 
-		// Calculate statistics
-		fmt.Print("📈 Calculating tree statistics... ")
-		totalSize, err := numberOfNodes(txtree)
-		if err != nil {
-			fmt.Printf("\n❌ Error: Failed to get total size: %s\n", err)
-			os.Exit(1)
+		var sweepTreeRoot []byte
+		if cmd.Flags().Changed("sweep-root") {
+			sweepTreeRoot, err = parseSweepRoot(sweepRoot)
+			if err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
 		}
 
-		branchSizes, err := sizeOfBranches(txtree)
-		if err != nil {
-			fmt.Printf("\n❌ Error: Failed to get size of branches: %s\n", err)
-			os.Exit(1)
+		if keysOutput != "" {
+			if err := validateKeysOutput(); err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
 		}
 
-		branchWeights, err := weightOfBranches(txtree)
+		outputs, err = parseOutputs(outputFormat, outPath)
 		if err != nil {
-			fmt.Printf("\n❌ Error: Failed to get weight of branches: %s\n", err)
-			os.Exit(1)
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
 		}
-		fmt.Println("✅")
-
-		// Find biggest branch
-		biggestBranch := 0
-		for _, size := range branchSizes {
-			if size > biggestBranch {
-				biggestBranch = size
-			}
+		if err := validateSeedsStdin(cmd.Flags().Changed("seed")); err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
 		}
-
-		// Find heaviest branch
-		heaviestBranch := 0.0
-		for _, weight := range branchWeights {
-			if weight > heaviestBranch {
-				heaviestBranch = weight
+		if err := validatePartitionBy(); err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
+		var baseline map[string]float64
+		if baselinePath != "" {
+			if !hasOutput(outputText) || partitionBy != "" || seedsStdin {
+				err := errors.New("--baseline only changes the text statistics of a single tree")
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
+			baseline, err = loadBaselineMetrics(baselinePath)
+			if err != nil {
+				exitWithError(phaseLoad, err, "❌ Error: Failed to load baseline: %s\n", err)
 			}
 		}
+		if inputAmount < 0 {
+			err := fmt.Errorf("--input-amount must be positive, got %d", inputAmount)
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
+		if timingAllocs && !showTimings {
+			exitWithError(phaseValidation, errors.New("--allocs requires --timings"), "Error: --allocs requires --timings\n")
+		}
+		if dotStats && !hasOutput(outputDOT) {
+			exitWithError(phaseValidation, errors.New("--dot-stats requires --output dot"), "Error: --dot-stats requires --output dot\n")
+		}
+		if err := validateColorBy(colorBy); err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
+		if err := validateSQLTable(sqlTable, cmd.Flags().Changed("table")); err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
+		tags, err := parseInfluxTags(influxMeasurement, influxTags, cmd.Flags().Changed("influx-measurement"))
+		if err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
 
-		// Print results with beautiful formatting
-		fmt.Println("\n" + strings.Repeat("─", 60))
-		fmt.Println("📊 TREE STATISTICS")
-		fmt.Println(strings.Repeat("─", 60))
+		if err := validateShape(shape); err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
 
-		fmt.Printf("🌳 Total Transactions:    %8d\n", totalSize)
-		fmt.Printf("🍃 Number of Leaves:      %8d\n", numLeaves)
-		fmt.Printf("📏 Biggest Branch Size:   %8d tx\n", biggestBranch)
+		locktime, roundedLocktime, err := parseLocktime(locktimeType, locktimeValue, roundLocktime)
+		if err != nil {
+			exitWithError(phaseValidation, err, "Error: %s\n", err)
+		}
 
-		// Calculate average and median branch size
-		if len(branchSizes) > 0 {
-			avgSize := calculateAverage(branchSizes)
-			fmt.Printf("📊 Average Branch Size:   %8.1f tx\n", avgSize)
+		if cmd.Flags().Changed("tx-version") {
+			if err := arktree.ValidateTxVersion(txVersion); err != nil {
+				exitWithError(phaseValidation, err, "Error: %s\n", err)
+			}
+		}
 
-			// Calculate median
-			medianSize := calculateMedian(branchSizes)
-			fmt.Printf("📊 Median Branch Size:    %8.1f tx\n", medianSize)
+		// Progress output is silenced when only the leaf txids, the broadcast order, a structured output,
+		// the CDF or the failed assertions are requested on stdout
+		out := io.Writer(os.Stdout)
+		if leafTxidsOnly || broadcastOrderOnly || stdoutFormat() != outputText || assertQuiet || cdf {
+			out = io.Discard
 		}
 
-		fmt.Printf("📡 Most Tx to Broadcast:    %8.2f\n", heaviestBranch)
+		// Print header with styling
+		fmt.Fprintln(out, "🌳 Ark Tree Generator")
+		fmt.Fprintln(out, "="+strings.Repeat("=", 50))
+		if timeBudget > 0 {
+			fmt.Fprintf(out, "📊 Generating the largest Ark tree built within %s...\n", timeBudget)
+		} else {
+			fmt.Fprintf(out, "📊 Generating Ark tree with %d leaves...\n", numLeaves)
+		}
+		if cmd.Flags().Changed("target-depth") {
+			fmt.Fprintf(out, "🎯 %d leaves is the largest tree of depth %d at most (2^%d)\n", numLeaves, targetDepth, targetDepth-1)
+		}
+		if partitionBy != "" {
+			fmt.Fprintf(out, "🔁 Building a tree per %s\n", partitionBy)
+		}
+		if roundedLocktime {
+			fmt.Fprintf(out, "⏱️  Locktime rounded up to %d seconds\n", locktime.Value)
+		}
+		if shape == shapeWorst {
+			fmt.Fprintln(out, "🧨 BuildVtxoTree takes no shape hints: the tree keeps its shape and is compared with the worst case")
+		}
+		fmt.Fprintln(out)
+
+		// Build the tree, reporting each phase once it's done
+		opts := arktree.GenerateOptions{
+			NumLeaves:      numLeaves,
+			SharedCosigner: sharedCosigner,
+			CosignerGroups: cosignerGroups,
+			CosignerSeed:   cosignerSeedBytes,
+			Amount:         amount,
+			AmountSamples:  samples,
+			RawScripts:     rawScripts,
+			Locktime:       &locktime,
+			SweepTreeRoot:  sweepTreeRoot,
+			InputAmount:    inputAmount,
+			AnalyzeOptions: arktree.AnalyzeOptions{PerTxFee: perTxFee, MeasureAllocs: timingAllocs},
+		}
+		if loadedLeaves != nil {
+			opts.Leaves = loadedLeaves.leaves
+		}
+		if cmd.Flags().Changed("seed") {
+			opts.Seed = &seed
+		}
+		if cmd.Flags().Changed("tx-version") {
+			opts.TxVersion = &txVersion
+		}
+		if cmd.Flags().Changed("tx-locktime") {
+			opts.TxLocktime = &txLocktime
+		}
+		analyzeOpts := arktree.AnalyzeOptions{
+			Workers:         workers,
+			WithAnchors:     withAnchors,
+			WeightBy:        arktree.WeightUnit(weightBy),
+			VerifyCosigners: verifyCosigners,
+			Feerate:         feerate,
+			BlockInterval:   blockInterval,
+			Witness:         witnessModel(),
+			ClampValue:      clampValue,
+			ExcludeNUMS:     excludeNUMS,
+			PerTxFee:        perTxFee,
+			MeasureAllocs:   timingAllocs,
+		}
 
-		// Calculate average and median branch weight
-		if len(branchWeights) > 0 {
-			avgWeight := calculateAverageFloat(branchWeights)
-			fmt.Printf("📊 Avg Tx to Broadcast:    %8.2f\n", avgWeight)
+		if seedsStdin {
+			if err := generateSeeds(os.Stdin, os.Stdout, opts, analyzeOpts); err != nil {
+				exitWithError(phaseBuild, err, "❌ Error: %s\n", err)
+			}
+			return
+		}
+		if partitionBy != "" {
+			if err := generatePartitions(loadedLeaves, opts, analyzeOpts); err != nil {
+				exitWithError(phaseBuild, err, "❌ Error: %s\n", err)
+			}
+			return
+		}
 
-			// Calculate median
-			medianWeight := calculateMedianFloat(branchWeights)
-			fmt.Printf("📊 Median Tx to Broadcast: %8.2f\n", medianWeight)
+		stopHeartbeat := startHeartbeat(out, heartbeat, time.Now())
+		var generation *arktree.Generation
+		if timeBudget > 0 {
+			generation, err = generateWithinBudget(out, opts, timeBudget)
+		} else {
+			generation, err = arktree.Generate(opts)
+		}
+		stopHeartbeat()
+		if err != nil {
+			exitWithError(phaseBuild, err, "\n❌ Error: %s\n", err)
+		}
+		if timeBudget > 0 {
+			numLeaves = len(generation.Leaves)
+			fmt.Fprintf(out, "🎯 %d leaves is the largest tree built within %s\n", numLeaves, timeBudget)
+		}
+		txtree, leaves, timings := generation.Tree, generation.Leaves, generation.Timings
+		elapsed := timings[len(timings)-1].Elapsed
+
+		fmt.Fprintln(out, "🔧 Initializing random data... ✅")
+		if loadedLeaves != nil {
+			fmt.Fprintf(out, "🍃 Using %d leaves from %s... ✅\n", numLeaves, loadedLeaves.sources())
+			if loadedLeaves.excluded > 0 {
+				fmt.Fprintf(out, "🚫 Excluded %d leaves with amount %d\n", loadedLeaves.excluded, ignoreAmount)
+			}
+		} else {
+			fmt.Fprintf(out, "🍃 Generating %d leaves... ✅\n", numLeaves)
+			if samples != nil {
+				fmt.Fprintf(out, "💰 Amounts drawn from %d samples of %s: %s\n", len(samples), amountSamples, summarizeAmounts(leaves))
+			}
+		}
+		fmt.Fprintf(out, "🌿 Building Vtxo tree... ✅ (%s)\n", elapsed)
+		if sweepTreeRoot != nil {
+			fmt.Fprintf(out, "🌱 Sweep tree root: %x (given)\n", sweepTreeRoot)
+		} else {
+			fmt.Fprintf(out, "🌱 Sweep tree root: %x (random)\n", generation.SweepTreeRoot)
+		}
+		if inputAmount > 0 {
+			funding, err := checkFunding(txtree, leaves, inputAmount)
+			if err != nil {
+				exitWithError(phaseBuild, err, "❌ Error: %s\n", err)
+			}
+			fmt.Fprintln(out, funding)
 		}
 
-		fmt.Println(strings.Repeat("─", 60))
+		var stats *arktree.Report
+		calculateStats := func() {
+			fmt.Fprint(out, "📈 Calculating tree statistics... ")
+			stats, err = analyze(txtree, analyzeOpts)
+			if err != nil {
+				exitWithError(phaseStats, err, "\n❌ Error: %s\n", err)
+			}
+			fmt.Fprintln(out, "✅")
+		}
 
-		// Group branches by size
-		sizeCount := make(map[int]int)
-		for _, size := range branchSizes {
-			sizeCount[size]++
+		if outPath != "" {
+			// the export records the metrics of the run, the baseline of later ones
+			calculateStats()
+			fmt.Fprintf(out, "💾 Exporting tree to %s... ", outPath)
+			var build *buildParams
+			if loadedLeaves == nil {
+				build = &buildParams{
+					Leaves:         numLeaves,
+					Seed:           opts.Seed,
+					Amount:         amount,
+					AmountSamples:  amountSamples,
+					LocktimeType:   locktimeType,
+					LocktimeValue:  locktime.Value,
+					SharedCosigner: sharedCosigner,
+					CosignerGroups: cosignerGroups,
+					CosignerSeed:   hex.EncodeToString(cosignerSeedBytes),
+					RawScripts:     rawScripts,
+					SweepTreeRoot:  hex.EncodeToString(sweepTreeRoot),
+					PerTxFee:       perTxFee,
+				}
+			}
+			if err := exportTree(outPath, txtree, build, generation.SweepTreeRoot, stats); err != nil {
+				exitWithError(phaseExport, err, "\n❌ Error: Failed to export tree: %s\n", err)
+			}
+			fmt.Fprintln(out, "✅")
 		}
 
-		// Print branch details grouped by size
-		fmt.Println("\n🌿 BRANCH SIZE DETAILS:")
-		fmt.Println(strings.Repeat("─", 40))
+		if keysOutput != "" {
+			fmt.Fprintf(out, "🔑 Writing the cosigner private keys to %s... ", keysOutput)
+			if err := writeKeysFile(keysOutput, generation.CosignerKeys); err != nil {
+				exitWithError(phaseExport, err, "\n❌ Error: Failed to write private keys: %s\n", err)
+			}
+			fmt.Fprintln(out, "✅")
+			fmt.Fprintf(os.Stderr, "⚠️  WARNING: %s holds unencrypted private keys, anyone who can read it can sign for the leaves of the tree. Keep it private, delete it once done and never use these keys with real funds\n", keysOutput)
+		}
 
-		// Sort sizes for consistent output
-		var sizes []int
-		for size := range sizeCount {
-			sizes = append(sizes, size)
+		if leafTxidsOnly {
+			for _, txid := range arktree.LeafTxids(txtree) {
+				fmt.Println(txid)
+			}
+			return
 		}
 
-		// Simple sort (bubble sort for small arrays)
-		for i := 0; i < len(sizes)-1; i++ {
-			for j := 0; j < len(sizes)-i-1; j++ {
-				if sizes[j] > sizes[j+1] {
-					sizes[j], sizes[j+1] = sizes[j+1], sizes[j]
+		if broadcastOrderOnly {
+			if err := writeBroadcastOrder(os.Stdout, txtree, stdoutFormat() == outputJSON); err != nil {
+				if stdoutFormat() == outputJSON {
+					exitWithError(phaseOutput, err, "")
 				}
+				fmt.Fprintf(os.Stderr, "❌ Error: Failed to write broadcast order: %s\n", err)
+				os.Exit(1)
 			}
+			return
 		}
 
-		for _, size := range sizes {
-			count := sizeCount[size]
-			if count == 1 {
-				fmt.Printf("%2d branch  with %2d tx\n", count, size)
-			} else {
-				fmt.Printf("%2d branches with %2d tx\n", count, size)
+		// The topology formats don't need the statistics
+		if err := writeOutput(out, outputYAML, "tree topology", func(w io.Writer) error { return writeYAML(w, txtree, leafCounts) }); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write YAML: %s\n", err)
+			os.Exit(1)
+		}
+		prefixLength := 0
+		if err := writeOutput(out, outputNewick, "Newick tree", func(w io.Writer) (err error) {
+			prefixLength, err = writeNewick(w, txtree)
+			return err
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write Newick: %s\n", err)
+			os.Exit(1)
+		}
+		if err := writeOutput(out, outputAdjacency, "adjacency list", func(w io.Writer) error { return writeAdjacency(w, txtree) }); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write adjacency list: %s\n", err)
+			os.Exit(1)
+		}
+		if err := writeOutput(out, outputSQL, "SQL inserts", func(w io.Writer) error { return writeSQL(w, txtree, sqlTable, witnessModel()) }); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write SQL: %s\n", err)
+			os.Exit(1)
+		}
+		if prefixLength > 0 {
+			// on stderr, not to mix with a Newick tree written to stdout
+			fmt.Fprintf(os.Stderr, "🔤 Newick labels are the first %d characters of the txids\n", prefixLength)
+			warnTxidLength(os.Stderr, prefixLength)
+		}
+		var fills map[*tree.TxGraph]string
+		if colorBy == colorBySubtreeSize {
+			fills = subtreeSizeFills(txtree, !noColor && os.Getenv("NO_COLOR") == "")
+		}
+		dotPrefixLength := 0
+		if err := writeOutput(out, outputDOT, "DOT graph", func(w io.Writer) (err error) {
+			var caption string
+			if dotStats {
+				if caption, err = dotCaption(txtree, workers); err != nil {
+					return err
+				}
 			}
+			dotPrefixLength, err = writeDOT(w, txtree, caption, fills)
+			return err
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write DOT: %s\n", err)
+			os.Exit(1)
+		}
+		if dotPrefixLength > 0 {
+			fmt.Fprintf(os.Stderr, "🔤 DOT labels are the first %d characters of the txids\n", dotPrefixLength)
+			warnTxidLength(os.Stderr, dotPrefixLength)
+		}
+		flamePrefixLength := 0
+		if err := writeOutput(out, outputFlame, "flamegraph stacks", func(w io.Writer) (err error) {
+			flamePrefixLength, err = writeFlamegraph(w, txtree, arktree.WeightUnit(weightBy), witnessModel())
+			return err
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to write flamegraph stacks: %s\n", err)
+			os.Exit(1)
+		}
+		if flamePrefixLength > 0 {
+			fmt.Fprintf(os.Stderr, "🔤 Flamegraph frames are the first %d characters of the txids\n", flamePrefixLength)
+			warnTxidLength(os.Stderr, flamePrefixLength)
+		}
+		if !hasOutput(outputText) && !hasOutput(outputJSON) && !hasOutput(outputProtobuf) && !hasOutput(outputHTML) && !hasOutput(outputMarkdown) && !hasOutput(outputOpenMetrics) && !hasOutput(outputInflux) &&
+			!assertionsEnabled() && minCosigners <= 1 && logJSONPath == "" {
+			return
 		}
 
-		// Group branches by weight (rounded to 2 decimal places)
-		weightCount := make(map[float64]int)
-		for _, weight := range branchWeights {
-			roundedWeight := float64(int(weight*100)) / 100 // Round to 2 decimal places
-			weightCount[roundedWeight]++
+		if stats == nil {
+			calculateStats()
 		}
 
-		// Print weight details grouped by weight
-		fmt.Println("\n📡 BROADCAST WEIGHT DETAILS:")
-		fmt.Println(strings.Repeat("─", 40))
+		// logRun appends the run to --log-json once it passed the gates
+		logRun := func() {
+			if logJSONPath == "" {
+				return
+			}
+			flags := make(map[string]string)
+			cmd.Flags().Visit(func(f *pflag.Flag) {
+				flags[f.Name] = f.Value.String()
+			})
+
+			record := runRecord{
+				Timestamp:           time.Now().UTC(),
+				Flags:               flags,
+				Leaves:              numLeaves,
+				TotalTransactions:   stats.TotalSize,
+				BiggestBranchSize:   stats.BiggestBranch(),
+				AvgBranchSize:       arktree.CalculateAverage(stats.BranchSizes),
+				MedianBranchSize:    arktree.CalculateMedian(stats.BranchSizes),
+				MostTxToBroadcast:   stats.HeaviestBranch(),
+				AvgTxToBroadcast:    arktree.CalculateAverageFloat(stats.BranchWeights),
+				MedianTxToBroadcast: arktree.CalculateMedianFloat(stats.BranchWeights),
+				MostTxWithAnchors:   arktree.MaxFloat(stats.AnchorWeights),
+				WeightCounts:        arktree.NewDistribution(stats.BranchWeights).Counts,
+				SizeOnWire:          stats.WireSize.Total(),
+				BuildTimeMs:         float64(elapsed.Microseconds()) / 1000,
+			}
+			if err := appendJSONLog(logJSONPath, record); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: Failed to append to JSON log: %s\n", err)
+				os.Exit(1)
+			}
+		}
 
-		// Sort weights for consistent output
-		var weights []float64
-		for weight := range weightCount {
-			weights = append(weights, weight)
+		// the text output reports the gates under its statistics, any other
+		// output on stdout has them report on stderr before it is written
+		if (cdf || !hasOutput(outputText)) && !stats.Partial {
+			checkGates(os.Stderr, txtree, stats)
+			logRun()
 		}
 
-		// Simple sort (bubble sort for small arrays)
-		for i := 0; i < len(weights)-1; i++ {
-			for j := 0; j < len(weights)-i-1; j++ {
-				if weights[j] > weights[j+1] {
-					weights[j], weights[j+1] = weights[j+1], weights[j]
-				}
+		if cdf {
+			if err := writeCDF(os.Stdout, reportedBranchSizes(stats)); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: Failed to write CDF: %s\n", err)
+				os.Exit(1)
 			}
+			exitIfPartial(stats)
+			return
 		}
 
-		for _, weight := range weights {
-			count := weightCount[weight]
-			if count == 1 {
-				fmt.Printf("%2d branch  with %.2f tx to broadcast\n", count, weight)
+		if hasOutput(outputJSON) || hasOutput(outputProtobuf) || hasOutput(outputHTML) || hasOutput(outputMarkdown) || hasOutput(outputOpenMetrics) || hasOutput(outputInflux) {
+			var leafNames []string
+			if loadedLeaves != nil {
+				leafNames = loadedLeaves.labels
 			} else {
-				fmt.Printf("%2d branches with %.2f tx to broadcast\n", count, weight)
+				leafNames = leafGroups
+			}
+
+			var labels map[string]string
+			if leafNames != nil {
+				labels, err = leafLabels(txtree, leaves, leafNames)
+				if err != nil {
+					exitWithError(phaseOutput, err, "❌ Error: Failed to label leaves: %s\n", err)
+				}
+			}
+
+			report := newStatsReport(stats, labels)
+			if loadedLeaves != nil {
+				report.ExcludedLeaves = loadedLeaves.excluded
+			}
+			if checksum {
+				report.Checksum = treeChecksum(txtree)
+			}
+			if sweepTreeRoot != nil {
+				report.SweepTreeRoot = hex.EncodeToString(sweepTreeRoot)
+			}
+			if shape == shapeWorst {
+				report.WorstCase = newWorstCaseReport(stats)
+			}
+			if cosignersPerNode && stats.CosignersPerNode.Max > 0 {
+				report.CosignersPerNode = &stats.CosignersPerNode
+			}
+			if arity {
+				report.Arity = arityJSON(arktree.ComputeFanOut(txtree).Arity)
+			}
+			if rawArrays {
+				report.RawArrays = newRawArraysReport(stats)
+			}
+			if includeNodeSizes {
+				report.NodeSizes, err = arktree.NodeVsizes(txtree, stats.Witness)
+				if err != nil {
+					exitWithError(phaseStats, err, "❌ Error: Failed to get node sizes: %s\n", err)
+				}
+			}
+
+			if err := writeOutput(out, outputJSON, "JSON statistics", func(w io.Writer) error {
+				if compactStats {
+					return writeJSON(w, newCompactStatsReport(report, stats), prettyJSON, canonicalJSON)
+				}
+				return writeJSON(w, report, prettyJSON, canonicalJSON)
+			}); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write JSON: %s\n", err)
+			}
+			if err := writeOutput(out, outputProtobuf, "protobuf statistics", func(w io.Writer) error { return writeProtobuf(w, report, branchDetails) }); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write protobuf: %s\n", err)
+			}
+			if err := writeOutput(out, outputHTML, "HTML report", func(w io.Writer) error { return writeHTML(w, report, txtree, fills) }); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write HTML: %s\n", err)
 			}
+			if err := writeOutput(out, outputMarkdown, "Markdown statistics", func(w io.Writer) error { return writeMarkdown(w, report) }); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write Markdown: %s\n", err)
+			}
+			if err := writeOutput(out, outputOpenMetrics, "OpenMetrics", func(w io.Writer) error { return writeOpenMetrics(w, report) }); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write OpenMetrics: %s\n", err)
+			}
+			if err := writeOutput(out, outputInflux, "InfluxDB line protocol", func(w io.Writer) error {
+				return writeInflux(w, report, influxMeasurement, tags, time.Now())
+			}); err != nil {
+				exitWithError(phaseOutput, err, "❌ Error: Failed to write InfluxDB line protocol: %s\n", err)
+			}
+		}
+		if !hasOutput(outputText) {
+			exitIfPartial(stats)
+			return
 		}
 
-		fmt.Println("\n" + strings.Repeat("=", 60))
-		fmt.Printf("🎉 Successfully generated Ark tree with %d leaves!\n", numLeaves)
-		fmt.Println(strings.Repeat("=", 60))
+		if !assertQuiet && baseline != nil {
+			printBaselineChanges(stats, baseline)
+		} else if !assertQuiet {
+			printStats(stats)
+			if loadedLeaves != nil && loadedLeaves.weights != nil {
+				correlation, err := weightDepthCorrelation(txtree, leaves, loadedLeaves.weights)
+				if err != nil {
+					fmt.Printf("\n❌ Error: Failed to correlate leaf weights: %s\n", err)
+					os.Exit(1)
+				}
+				printWeightPlacement(correlation)
+			}
+			if leafGroups != nil {
+				groups, err := leafLabels(txtree, leaves, leafGroups)
+				if err != nil {
+					fmt.Printf("\n❌ Error: Failed to group leaves: %s\n", err)
+					os.Exit(1)
+				}
+				printCosignerGroups(stats, groups)
+			}
+			if showTimings {
+				printTimings(append(timings, stats.Timings...), timingAllocs)
+			}
+		}
+		exitIfPartial(stats)
+		checkGates(os.Stdout, txtree, stats)
 
+		fmt.Fprintln(out, "\n"+strings.Repeat("=", 60))
+		fmt.Fprintf(out, "🎉 Successfully generated Ark tree with %d leaves!\n", numLeaves)
+		fmt.Fprintln(out, strings.Repeat("=", 60))
+
+		logRun()
 	},
 }
 
+var (
+	sharedCosigner     bool
+	amount             uint64
+	amountSamples      string
+	inputAmount        int64
+	cosignerGroups     int
+	cosignerSeed       string
+	sweepRoot          string
+	keysOutput         string
+	logJSONPath        string
+	leafTxidsOnly      bool
+	withAnchors        bool
+	verifyCosigners    bool
+	workers            int
+	outPath            string
+	minCosigners       int
+	feerate            float64
+	feerates           []float64
+	blockInterval      time.Duration
+	cdf                bool
+	checksum           bool
+	maxDetailRows      int
+	topBranches        int
+	heartbeat          time.Duration
+	clampValue         bool
+	feeBudget          int64
+	weightBy           string
+	explain            bool
+	exitFraction       float64
+	exitSamples        int
+	targetDepth        int
+	fanOut             bool
+	arity              bool
+	levelVsizes        bool
+	cosignersPerNode   bool
+	excludeNUMS        bool
+	perTxFee           int64
+	showTimings        bool
+	timingAllocs       bool
+	leavesFiles        []string
+	validateOnly       bool
+	partitionBy        string
+	branchDetails      bool
+	ignoreAmount       uint64
+	strict             bool
+	shape              string
+	outputFormat       string
+	seed               int64
+	leafCounts         bool
+	locktimeType       string
+	locktimeValue      uint32
+	roundLocktime      bool
+	txVersion          int32
+	txLocktime         uint32
+	rawScripts         bool
+	prettyJSON         bool
+	canonicalJSON      bool
+	compactStats       bool
+	seedsStdin         bool
+	dotStats           bool
+	includeNodeSizes   bool
+	rawArrays          bool
+	broadcastOrderOnly bool
+)
+
 func init() {
+	rootCmd.PersistentFlags().BoolVar(&werror, "werror", false, "Exit with status 1 once done if any warning was printed: duplicate leaf scripts, degenerate tree, export not matching its manifest or its stored cosigners or built with another ark version, unreachable nodes, unseeded rebuild or txids ambiguous at --txid-length")
+	rootCmd.PersistentFlags().BoolVar(&paginate, "paginate", false, "Page the output through $PAGER, less by default, when stdout is a terminal")
+	rootCmd.PersistentFlags().IntVar(&txidLength, "txid-length", defaultTxidLength, "Characters txids are shortened to in the tables, broadcast orders and diagrams (0 for whole txids), more with a warning if needed to tell them apart")
+
+	generateCmd.Flags().IntVar(&targetDepth, "target-depth", 0, "Generate the largest tree of at most this depth instead of giving the number of leaves")
+	generateCmd.Flags().DurationVar(&timeBudget, "time-budget", 0, "Generate the largest tree whose builds, of 16 leaves then doubling, complete within this duration instead of giving the number of leaves, e.g. 30s")
+	generateCmd.Flags().StringArrayVar(&leavesFiles, "leaves-file", nil, "Load the leaves from a JSON file instead of generating them (\"-\" reads stdin), repeat to concatenate the leaves of several files in the order given")
+	generateCmd.Flags().StringVar(&partitionBy, "partition-by", "", "Build a tree per value of this field of the leaves file, round_id, printing the statistics of each and of all of them")
+	generateCmd.Flags().StringVar(&baselinePath, "baseline", "", "Only print the metrics differing from those recorded in this export or manifest, with their deltas")
+	generateCmd.Flags().StringVar(&shape, "shape", shapeDefault, "Tree shape: default, or worst to compare the biggest branch with the theoretical worst case (the builder takes no shape hints)")
+	generateCmd.Flags().BoolVar(&strict, "strict", false, "Fail instead of warning when several leaves of the leaves file share a script")
+	generateCmd.Flags().BoolVar(&validateOnly, "validate-only", false, "Only check the leaves file, reporting every invalid leaf and the duplicate scripts, without building the tree")
+	generateCmd.Flags().Uint64Var(&ignoreAmount, "ignore-amount", 0, "Leave out the leaves of the leaves file with this amount, e.g. 0 for placeholders")
+	addLeafFieldFlags(generateCmd)
+	generateCmd.Flags().DurationVar(&heartbeat, "heartbeat", 5*time.Second, "Print the elapsed time at this interval while the tree builds (0 to disable)")
+	generateCmd.Flags().Int64Var(&seed, "seed", 0, "Seed the random leaves, keys and tree for a reproducible run")
+	generateCmd.Flags().StringVar(&locktimeType, "locktime-type", locktimeTypeBlock, "Unit of the sweep locktime: block or second")
+	generateCmd.Flags().Uint32Var(&locktimeValue, "locktime-value", arktree.DefaultLocktime.Value, "Sweep locktime, seconds must be a multiple of 512")
+	generateCmd.Flags().BoolVar(&roundLocktime, "round-locktime", false, "Round a locktime in seconds up to the next multiple of 512 instead of rejecting it")
+	generateCmd.Flags().Int32Var(&txVersion, "tx-version", arktree.MaxTxVersion, "Require this nVersion on every tx, failing if the builder doesn't use it")
+	generateCmd.Flags().Uint32Var(&txLocktime, "tx-locktime", 0, "Require this nLockTime on every tx, failing if the builder doesn't use it")
+	generateCmd.Flags().BoolVar(&rawScripts, "raw-scripts", false, "Use 34 random bytes as leaf scripts instead of valid P2TR scripts (faster, but the outputs are unspendable)")
+	generateCmd.Flags().Uint64Var(&amount, "amount", arktree.DefaultLeafAmount, "Amount in sats of each generated leaf")
+	generateCmd.Flags().Int64Var(&inputAmount, "input-amount", 0, "Sats of the input funding the tree: fail before building if the leaves exceed it, and after if the leaves plus the fees of the whole tree at --feerate do")
+	generateCmd.Flags().StringVar(&amountSamples, "amount-samples", "", "Draw the amount of each generated leaf, with replacement, from the amounts of this file, one positive integer of sats per line")
+	// The builder deduplicates cosigner keys, so with a shared key every node has a
+	// single cosigner and each branch's broadcast weight equals its size.
+	generateCmd.Flags().BoolVar(&sharedCosigner, "shared-cosigner", false, "Reuse a single cosigner key for every leaf")
+	generateCmd.Flags().StringVar(&cosignerSeed, "cosigner-seed", "", "Derive the cosigner keys with HKDF-SHA256 from this hex seed (at least 16 bytes) and their index, instead of generating them randomly")
+	generateCmd.Flags().StringVar(&sweepRoot, "sweep-root", "", "Hex sweep tree root of 32 bytes tweaking the outputs, e.g. of a real round, instead of a random one")
+	generateCmd.Flags().StringVar(&keysOutput, "keys-output", "", "Write the private cosigner key of each leaf as hex, one per line in leaf order, to this file (mode 0600)")
+	generateCmd.Flags().IntVar(&cosignerGroups, "cosigner-groups", 0, "Spread the leaves round robin over this many groups, each sharing one cosigner key")
+	generateCmd.Flags().BoolVar(&leafTxidsOnly, "leaf-txids-only", false, "Only print the leaf txids, one per line, sorted by txid")
+	generateCmd.Flags().BoolVar(&broadcastOrderOnly, "broadcast-order", false, "Only print the txids of all nodes in broadcast order, parents first (a JSON array with --output json)")
+	generateCmd.Flags().StringVarP(&outputFormat, "output", "o", outputText, "Output formats, comma separated: text, yaml (nested tree topology), newick (tree topology labelled by shortened txids), adjacency (a parent_txid child_txid line per edge), sql (INSERT statements of the nodes in a transaction, see --table), dot (Graphviz digraph labelled by shortened txids), flamegraph (folded stacks of the exit cost of every node, in txs or vB with --weight-by), json or protobuf (statistics, see proto/stats.proto), jsonl (a line of JSON statistics per seed, with --seeds-stdin), html (self-contained report with histograms and a tree diagram), markdown (GitHub-flavored tables of the statistics and branch sizes), openmetrics (gauges with units and a branch size histogram, for Prometheus), influx (a record of the InfluxDB line protocol, see --influx-measurement and --influx-tag). With several formats, all but text are written to files named after --out")
+	generateCmd.Flags().BoolVar(&branchDetails, "branch-details", false, "Include the per-branch statistics in the protobuf output, the json output always has them")
+	generateCmd.Flags().BoolVar(&prettyJSON, "pretty", false, "Indent the json output")
+	generateCmd.Flags().BoolVar(&canonicalJSON, "canonical", false, "Sort the keys of every object of the json output, so that the same tree always gives the same bytes")
+	generateCmd.Flags().StringVar(&influxMeasurement, "influx-measurement", defaultInfluxMeasurement, "Measurement of the influx output record")
+	generateCmd.Flags().StringArrayVar(&influxTags, "influx-tag", nil, "Tag key=value added to the influx output record along with leaves, repeatable, e.g. --influx-tag env=ci")
+	generateCmd.Flags().StringVar(&sqlTable, "table", defaultSQLTable, "Table the sql output creates if missing and inserts the nodes into: txid, parent_txid, depth, cosigner_count, vsize")
+	generateCmd.Flags().BoolVar(&dotStats, "dot-stats", false, "Caption the dot output with the leaves, depth, nodes and max branch weight of the tree")
+	generateCmd.Flags().StringVar(&colorBy, "color-by", "", "Shade the nodes of the dot and html diagrams: subtree-size, from light for a leaf to dark for the root by the leaves under them")
+	generateCmd.Flags().BoolVar(&noColor, "no-color", false, "Shade the nodes of --color-by in grays rather than colors, as the NO_COLOR environment variable does")
+	generateCmd.Flags().BoolVar(&seedsStdin, "seeds-stdin", false, "Build a tree for each seed read from stdin, one per line, and print a JSON line of statistics per seed, with --output jsonl")
+	generateCmd.Flags().BoolVar(&compactStats, "compact-stats", false, "Restrict the json output to a flat object of the headline numbers, without arrays or maps")
+	generateCmd.Flags().BoolVar(&includeNodeSizes, "include-node-sizes", false, "Include the estimated vsize of every node, keyed by txid, in the json output")
+	generateCmd.Flags().BoolVar(&rawArrays, "raw-arrays", false, "Include the branch sizes and weights as computed, arrays in leaf txid order, in the json output")
+	generateCmd.Flags().BoolVar(&leafCounts, "leaf-counts", false, "Annotate each node of the yaml output with the number of leaves of its subtree")
+	generateCmd.Flags().StringVar(&outPath, "out", "", "Export the tree to the given file, gzip compressed if it ends in .gz")
+	generateCmd.Flags().BoolVar(&includeCosigners, "include-cosigners", false, "Store the cosigner keys of every node in the export, checked against the tree on import")
+	generateCmd.Flags().BoolVar(&showTimings, "timings", false, "Print the elapsed time of each phase")
+	generateCmd.Flags().BoolVar(&timingAllocs, "allocs", false, "With --timings, also print the number and bytes of the heap allocations of each phase")
+	generateCmd.Flags().StringVar(&logJSONPath, "log-json", "", "Append a one-line JSON record of the run to the given file")
+
+	addStatsFlags(generateCmd)
+
 	rootCmd.AddCommand(generateCmd)
 }
 
-func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// addStatsFlags registers the flags of the statistics phase on cmd
+func addStatsFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&withAnchors, "with-anchors", false, "Also report broadcast weights including the CPFP child spending each tx's anchor output")
+	cmd.Flags().StringVar(&weightBy, "weight-by", string(arktree.WeightByCount), "What each tx counts for in the broadcast weights, split between its cosigners: count, or vsize to also report the vbytes to broadcast")
+	cmd.Flags().BoolVar(&verifyCosigners, "verify-cosigners", false, "Verify that every internal node's cosigner set is the union of its children's sets")
+	cmd.Flags().IntVar(&workers, "workers", 1, "Number of workers computing the branch statistics")
+	cmd.Flags().Float64Var(&feerate, "feerate", 1, "Feerate in sat/vB used to estimate exit fees")
+	cmd.Flags().Float64SliceVar(&feerates, "feerates", nil, "Also print the median and worst exit fees at each of these feerates in sat/vB, comma separated, e.g. 1,5,10,50")
+	addWitnessFlags(cmd)
+	cmd.Flags().DurationVar(&blockInterval, "block-interval", arktree.DefaultBlockInterval, "Time between two blocks used to estimate exit times")
+	cmd.Flags().Int64Var(&feeBudget, "fee-budget", 0, "Warn about the branches whose exit fee at --feerate exceeds this many sats, see --assert-fee-budget")
+	cmd.Flags().Float64Var(&exitFraction, "cooperation-fraction", 0, "Estimate the txs broadcast when each user exits unilaterally with this probability and the others cooperate (0 disables it)")
+	cmd.Flags().IntVar(&exitSamples, "cooperation-samples", 1000, "Number of random draws of the exiting users estimating --cooperation-fraction")
+	cmd.Flags().BoolVar(&clampValue, "clamp-value", false, "Clamp a total value overflowing int64 to its maximum and report it instead of failing")
+	cmd.Flags().IntVar(&minCosigners, "min-cosigners", 1, "Fail if any node has fewer cosigners than this")
+	cmd.Flags().IntVar(&topBranches, "top-branches", 0, "Print a table of this many branches with the most tx to broadcast in place of the detail sections")
+	cmd.Flags().BoolVar(&excludeNUMS, "exclude-nums", false, "Leave the NUMS point, an unspendable key no one signs with, out of the cosigners sharing the broadcast weights")
+	cmd.Flags().Int64Var(&perTxFee, "per-tx-fee", 0, "Fee in sats every tx pays out of the value it spends, deducted when building and checked by the value conservation, the exits paying only the rest of their fee")
+	cmd.Flags().BoolVar(&cosignersPerNode, "cosigners-per-node", false, "Print the min, mean and max number of cosigners of the nodes, also in the json output")
+	cmd.Flags().BoolVar(&fanOut, "fan-out", false, "Print the average fan-out and the fill factor (leaves over the capacity of a perfect tree of the same depth and max fan-out)")
+	cmd.Flags().BoolVar(&arity, "arity", false, "Print how many internal nodes have 1 child, 2 children and so on, also in the json output")
+	cmd.Flags().BoolVar(&levelVsizes, "level-vsizes", false, "Print the vsize of each level of the tree and its share of the whole tree")
+	cmd.Flags().IntVar(&maxDetailRows, "max-detail-rows", 25, "Maximum number of groups printed in each detail section, the biggest first (0 for unlimited)")
+	cmd.Flags().BoolVar(&checksum, "checksum", false, "Print the SHA256 of the txids of the tree in broadcast order, to compare trees between runs")
+	cmd.Flags().BoolVar(&explain, "explain", false, "Add a line explaining the meaning of each statistic under it")
+	cmd.Flags().BoolVar(&branchIncludeRoot, "branch-include-root", true, "Count the root in the branch sizes, as every branch goes through it; false counts only the txs of each branch that not every exit broadcasts, the root and the txs below it while they have a single child, the broadcast weights, balance and exit times still counting them")
+	cmd.Flags().BoolVar(&cdf, "cdf", false, "Only print the cumulative distribution of branch sizes as CSV")
+	addAssertFlags(cmd)
+}
+
+// analyze computes and validates the statistics of txtree, stopping with the
+// statistics gathered so far on SIGINT
+func analyze(txtree *tree.TxGraph, opts arktree.AnalyzeOptions) (*arktree.Report, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stats, err := arktree.AnalyzeContext(ctx, txtree, opts)
+	if err != nil {
+		return nil, err
 	}
+	if err := stats.Validate(); err != nil {
+		return nil, fmt.Errorf("inconsistent statistics: %w", err)
+	}
+	return stats, nil
 }
 
-func sizeOfBranches(g *tree.TxGraph) ([]int, error) {
-	leaves := g.Leaves()
+// exitIfPartial exits with the status of a SIGINT once partial statistics are printed
+func exitIfPartial(stats *arktree.Report) {
+	if stats.Partial {
+		os.Exit(130)
+	}
+}
 
-	branchSizes := make([]int, 0, len(leaves))
+// checkMinCosigners exits with an error listing to w the nodes with fewer than --min-cosigners cosigners
+func checkMinCosigners(w io.Writer, txtree *tree.TxGraph) {
+	if minCosigners <= 1 {
+		return
+	}
 
-	for _, leaf := range leaves {
-		branch, err := g.SubGraph([]string{leaf.UnsignedTx.TxID()})
-		if err != nil {
-			return nil, err
-		}
+	offending, err := arktree.NodesBelowMinCosigners(txtree, minCosigners)
+	if err != nil {
+		fmt.Fprintf(w, "\n❌ Error: Failed to check cosigners: %s\n", err)
+		os.Exit(1)
+	}
 
-		count, err := numberOfNodes(branch)
-		if err != nil {
-			return nil, err
+	if len(offending) > 0 {
+		fmt.Fprintf(w, "\n❌ Error: %d node(s) have fewer than %d cosigners:\n", len(offending), minCosigners)
+		for _, txid := range offending {
+			fmt.Fprintf(w, "   %s\n", txid)
 		}
-
-		branchSizes = append(branchSizes, count)
+		os.Exit(1)
 	}
+}
 
-	return branchSizes, nil
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
 
-func numberOfNodes(g *tree.TxGraph) (int, error) {
-	count := 0
-	if err := g.Apply(func(tx *tree.TxGraph) (bool, error) {
-		count++
-		return true, nil
-	}); err != nil {
-		return 0, err
+// printTimings prints the elapsed time of each phase and, if allocs, the
+// heap allocations measured with it
+func printTimings(timings []arktree.PhaseTiming, allocs bool) {
+	fmt.Println("\n⏱️  TIMINGS:")
+	fmt.Println(strings.Repeat("─", 40))
+
+	var (
+		total                   time.Duration
+		totalAllocs, totalBytes uint64
+	)
+	t := newTable(os.Stdout, false, true, true, true)
+	if allocs {
+		t.row("Phase", "Elapsed", "Allocs", "Bytes")
 	}
-	return count, nil
+	for _, timing := range timings {
+		if allocs {
+			t.row(timing.Name, timing.Elapsed.String(), strconv.FormatUint(timing.Allocs, 10), strconv.FormatUint(timing.AllocBytes, 10))
+		} else {
+			t.row(timing.Name, timing.Elapsed.String())
+		}
+		total += timing.Elapsed
+		totalAllocs += timing.Allocs
+		totalBytes += timing.AllocBytes
+	}
+	t.line(strings.Repeat("─", 40))
+	if allocs {
+		t.row("Total", total.String(), strconv.FormatUint(totalAllocs, 10), strconv.FormatUint(totalBytes, 10))
+	} else {
+		t.row("Total", total.String())
+	}
+	t.flush()
 }
 
-func calculateAverage(values []int) float64 {
-	if len(values) == 0 {
-		return 0
+func printStats(stats *arktree.Report) {
+	// Print results with beautiful formatting
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	if stats.Partial {
+		fmt.Printf("📊 TREE STATISTICS [partial results: %d/%d branches]\n", len(stats.BranchSizes), stats.NumLeaves)
+	} else {
+		fmt.Println("📊 TREE STATISTICS")
 	}
+	fmt.Println(strings.Repeat("─", 60))
 
-	sum := 0
-	for _, value := range values {
-		sum += value
+	t := newTable(os.Stdout, false, true)
+	if explain {
+		t.notes = statExplanations
 	}
-	return float64(sum) / float64(len(values))
-}
+	t.row("🌳 Total Transactions:", strconv.Itoa(stats.TotalSize))
+	t.row("🍃 Number of Leaves:", strconv.Itoa(stats.NumLeaves))
+	branchSizes, excluded, unit := reportedBranchSizes(stats), excludedBranchTxs(stats), "tx"
+	if excluded > 0 {
+		unit = fmt.Sprintf("tx (without the %d shared by every branch)", excluded)
+	}
+	t.row("📏 Biggest Branch Size:", strconv.Itoa(max(stats.BiggestBranch()-excluded, 0)), unit)
 
-func calculateMedian(values []int) float64 {
-	if len(values) == 0 {
-		return 0
+	// Calculate average and median branch size
+	if len(branchSizes) > 0 {
+		t.row("📊 Average Branch Size:", fmt.Sprintf("%.1f", arktree.CalculateAverage(branchSizes)), "tx")
+		t.row("📊 Median Branch Size:", fmt.Sprintf("%.1f", arktree.CalculateMedian(branchSizes)), "tx")
 	}
 
-	// Create a copy to avoid modifying the original slice
-	sorted := make([]int, len(values))
-	copy(sorted, values)
+	t.rowf("📡 Most Tx to Broadcast:", "%.2f", stats.HeaviestBranch())
 
-	// Sort the values
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := 0; j < len(sorted)-i-1; j++ {
-			if sorted[j] > sorted[j+1] {
-				sorted[j], sorted[j+1] = sorted[j+1], sorted[j]
-			}
-		}
+	// Calculate average and median branch weight
+	if len(stats.BranchWeights) > 0 {
+		t.rowf("📊 Avg Tx to Broadcast:", "%.2f", arktree.CalculateAverageFloat(stats.BranchWeights))
+		t.rowf("📊 Median Tx to Broadcast:", "%.2f", arktree.CalculateMedianFloat(stats.BranchWeights))
 	}
 
-	// Calculate median
-	n := len(sorted)
-	if n%2 == 0 {
-		// Even number of elements - average of two middle values
-		return float64(sorted[n/2-1]+sorted[n/2]) / 2.0
-	} else {
-		// Odd number of elements - middle value
-		return float64(sorted[n/2])
+	t.row("🔢 Distinct Weights:", strconv.Itoa(len(arktree.GroupWeights(stats.BranchWeights))))
+	t.rowf("⚖️ Balance:", "%.2f", arktree.BalanceScore(stats.BranchSizes))
+	if amortization := arktree.Amortization(stats.TotalSize, stats.BranchSizes); amortization > 0 {
+		t.row("🤝 Amortization:", fmt.Sprintf("%.2f", amortization),
+			fmt.Sprintf("(exiting alone broadcasts %.1fx the tx of a cooperative exit)", 1/amortization))
 	}
-}
+	if shared, naive, ratio := arktree.BroadcastSharing(stats.BranchSizes, stats.BranchWeights); naive > 0 {
+		t.row("📡 Total Tx to Broadcast:", fmt.Sprintf("%.2f", shared), fmt.Sprintf("shared, %.0f alone (ratio %.2f)", naive, ratio))
+	}
+	if stats.ValueClamped {
+		t.row("💰 Total Value:", strconv.FormatInt(stats.TotalValue, 10), "sats (clamped, the sum overflows int64)")
+	} else if len(stats.ExitCosts) > 0 { // not computed in interrupted runs
+		t.row("💰 Total Value:", strconv.FormatInt(stats.TotalValue, 10), "sats")
+	}
+	if stats.PerTxFee > 0 {
+		t.row("💸 Fees in Tree:", strconv.FormatInt(stats.TreeFees(), 10), fmt.Sprintf("sats (%d per tx, value conserved)", stats.PerTxFee))
+	}
+	t.row("🌲 Branching Factor:", fmt.Sprintf("%.2f", stats.BranchingFactor), "children per node")
+	t.row("🔷 Shape:", string(arktree.ClassifyShape(arktree.NodesPerLevel(stats.Tree), arktree.ComputeFanOut(stats.Tree).MaxChildren)))
 
-func weightOfBranches(g *tree.TxGraph) ([]float64, error) {
-	leaves := g.Leaves()
+	if len(stats.AnchorWeights) > 0 {
+		t.rowf("⚓ Most Tx w/ Anchors:", "%.2f", arktree.MaxFloat(stats.AnchorWeights))
+		t.rowf("⚓ Avg Tx w/ Anchors:", "%.2f", arktree.CalculateAverageFloat(stats.AnchorWeights))
+		t.rowf("⚓ Median Tx w/ Anchors:", "%.2f", arktree.CalculateMedianFloat(stats.AnchorWeights))
+	}
 
-	branchWeights := make([]float64, 0, len(leaves))
+	if len(stats.VsizeWeights) > 0 {
+		t.row("📦 Most vB to Broadcast:", fmt.Sprintf("%.1f", arktree.MaxFloat(stats.VsizeWeights)), "vB")
+		t.row("📦 Avg vB to Broadcast:", fmt.Sprintf("%.1f", arktree.CalculateAverageFloat(stats.VsizeWeights)), "vB")
+		t.row("📦 Median vB to Broadcast:", fmt.Sprintf("%.1f", arktree.CalculateMedianFloat(stats.VsizeWeights)), "vB")
+	}
 
-	for _, leaf := range leaves {
-		branch, err := g.SubGraph([]string{leaf.UnsignedTx.TxID()})
-		if err != nil {
-			return nil, err
-		}
+	churn := arktree.TotalChurn(stats.KeyChurn)
+	if churn.Parents > 0 {
+		t.row("🔑 Key Churn:", fmt.Sprintf("%.2f", float64(churn.KeysGained)/float64(churn.Parents)),
+			fmt.Sprintf("keys gained per parent, %d new", churn.NewKeys))
+	}
 
-		weight, err := computeBroadcastWeight(branch)
-		if err != nil {
-			return nil, err
+	if count := stats.CosignersPerNode; cosignersPerNode && count.Max > 0 { // not counted in interrupted runs
+		t.row("👥 Cosigners per Node:", fmt.Sprintf("%d/%.2f/%d", count.Min, count.Mean, count.Max), "min/mean/max")
+	}
+
+	if nums := stats.CosignersPerNode.NUMSNodes; nums > 0 {
+		note := "nodes cosigned by the unspendable NUMS point"
+		if excludeNUMS {
+			note += ", left out of the weights"
 		}
+		t.row("🫥 NUMS Cosigner:", strconv.Itoa(nums), note)
+	}
 
-		branchWeights = append(branchWeights, weight)
+	if stats.CosignersVerified {
+		t.row("🔑 Cosigner Sets:", "", "verified (each node = union of its children)")
 	}
 
-	return branchWeights, nil
-}
+	t.row("🧾 Tx Version:", strconv.Itoa(int(stats.TxVersion)))
+	t.row("🧾 Tx Locktime:", strconv.FormatUint(uint64(stats.TxLocktime), 10))
+	if checksum {
+		t.row("🔒 Checksum:", "", treeChecksum(stats.Tree))
+	}
+	if stats.WireSize.Total() > 0 { // not computed in interrupted runs
+		t.row("💾 Size on Wire:", strconv.Itoa(stats.WireSize.Total()), "bytes")
+		t.row("   ├ Non-witness:", strconv.Itoa(stats.WireSize.NonWitness), "bytes")
+		t.row("   ├ Witness (est.):", strconv.Itoa(stats.WireSize.Witness), "bytes")
+		t.row("   └ Metadata:", strconv.Itoa(stats.WireSize.Metadata), "bytes")
+
+		storage := stats.WireSize.PerVtxo(stats.NumLeaves)
+		t.row("🗄️ Storage per VTXO:", fmt.Sprintf("%.1f", storage.Total()),
+			fmt.Sprintf("bytes (%.1f non-witness, %.1f witness, %.1f metadata)", storage.NonWitness, storage.Witness, storage.Metadata))
+	}
+	t.flush()
 
-func calculateAverageFloat(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
+	fmt.Println(strings.Repeat("─", 60))
+
+	if stats.Degenerate() {
+		fmt.Println()
+		warnf(os.Stdout, "DEGENERATE TREE")
+		fmt.Printf("   The tree is a linear chain: depth %d for %d nodes, %.2f children per node.\n",
+			stats.Depth, stats.TotalSize, stats.BranchingFactor)
+		fmt.Println("   Every exit broadcasts the whole chain, so the averages above hide its cost.")
+		fmt.Println("   Check that the leaves are distinct and given to the builder in a single call.")
 	}
 
-	sum := 0.0
-	for _, value := range values {
-		sum += value
+	if topBranches > 0 {
+		printTopBranches(stats, topBranches)
+	} else {
+		printDetails(stats)
 	}
-	return sum / float64(len(values))
-}
 
-func calculateMedianFloat(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
+	printExitCosts(stats.ExitCosts, stats.CooperativeSweep, stats.Feerate, stats.Witness)
+	if len(feerates) > 0 && len(stats.ExitCosts) > 0 {
+		printFeeMatrix(stats.ExitCosts, feerates)
+	}
+	if feeBudget > 0 {
+		printFeeBudget(stats.ExitCosts, feeBudget)
 	}
+	if exitFraction != 0 {
+		printPartialExit(stats.Tree, exitFraction, exitSamples)
+	}
+	printExitTimes(stats)
+	if shape == shapeWorst {
+		printWorstCase(stats)
+	}
+	if fanOut {
+		printFanOut(stats)
+	}
+	if arity {
+		printArity(stats)
+	}
+	if levelVsizes {
+		printLevelVsizes(stats)
+	}
+}
 
-	// Create a copy to avoid modifying the original slice
-	sorted := make([]float64, len(values))
-	copy(sorted, values)
+// topGroups returns the values of the n groups with the most branches, sorted
+// by value, and the number of groups left out. n <= 0 keeps all the groups
+func topGroups[V cmp.Ordered](counts map[V]int, n int) ([]V, int) {
+	values := make([]V, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
 
-	// Sort the values
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := 0; j < len(sorted)-i-1; j++ {
-			if sorted[j] > sorted[j+1] {
-				sorted[j], sorted[j+1] = sorted[j+1], sorted[j]
+	if n > 0 && len(values) > n {
+		// keep the biggest groups, ties broken by value for a stable output
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
 			}
-		}
+			return values[i] < values[j]
+		})
+		values = values[:n]
 	}
 
-	// Calculate median
-	n := len(sorted)
-	if n%2 == 0 {
-		// Even number of elements - average of two middle values
-		return (sorted[n/2-1] + sorted[n/2]) / 2.0
-	} else {
-		// Odd number of elements - middle value
-		return sorted[n/2]
-	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values, len(counts) - len(values)
 }
 
-// weight = the part of the tx a user has to broadcast
-// if a tx is shared by 3 cosigners, each cosigner has to broadcast 1/3 of the tx
-func computeBroadcastWeight(branch *tree.TxGraph) (float64, error) {
-	var totalWeight float64
-	if err := branch.Apply(func(g *tree.TxGraph) (bool, error) {
-		cosignerKeys, err := tree.GetCosignerKeys(g.Root.Inputs[0])
-		if err != nil {
-			return false, err
-		}
+// printDetails prints the branches grouped by size, then by weight
+func printDetails(stats *arktree.Report) {
+	// Group branches by size
+	sizeCount := make(map[int]int)
+	for _, size := range reportedBranchSizes(stats) {
+		sizeCount[size]++
+	}
 
-		totalWeight += 1 / float64(len(cosignerKeys))
-		return true, nil
-	}); err != nil {
-		return 0, err
+	// Print branch details grouped by size
+	fmt.Println("\n🌿 BRANCH SIZE DETAILS:")
+	fmt.Println(strings.Repeat("─", 40))
+
+	// the counts and sizes are right aligned in their columns, whatever their
+	// number of digits
+	sizes, hiddenSizes := topGroups(sizeCount, maxDetailRows)
+	rows := newTable(os.Stdout, true, false, false, true)
+	for _, size := range sizes {
+		count := sizeCount[size]
+		rows.row(strconv.Itoa(count), branchesWord(count), "with", strconv.Itoa(size), "tx")
 	}
+	rows.flush()
+	printHiddenGroups(hiddenSizes)
+
+	weightCount := arktree.GroupWeights(stats.BranchWeights)
+
+	// Print weight details grouped by weight
+	fmt.Println("\n📡 BROADCAST WEIGHT DETAILS:")
+	fmt.Println(strings.Repeat("─", 40))
 
-	return totalWeight, nil
+	weights, hiddenWeights := topGroups(weightCount, maxDetailRows)
+	for _, weight := range weights {
+		count := weightCount[weight]
+		rows.row(strconv.Itoa(count), branchesWord(count), "with", fmt.Sprintf("%.2f", weight), "tx to broadcast")
+	}
+	rows.flush()
+	printHiddenGroups(hiddenWeights)
+}
+
+// branchesWord returns branch or branches depending on count
+func branchesWord(count int) string {
+	if count == 1 {
+		return "branch"
+	}
+	return "branches"
+}
+
+func printHiddenGroups(hidden int) {
+	if hidden > 0 {
+		fmt.Printf("… and %d more (see --max-detail-rows)\n", hidden)
+	}
 }
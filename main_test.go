@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// argsEnv holds the arguments, separated by newlines, of a test re-executing
+// itself as the binary, see runArktree
+const argsEnv = "ARKTREE_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(argsEnv); ok {
+		os.Args = append(os.Args[:1], strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runArktree runs the binary with args in a subprocess, since the commands
+// exit, and returns its stdout, its stderr and its exit code
+func runArktree(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), argsEnv+"="+strings.Join(args, "\n"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// testLeaves are the numbers of leaves of the seeded trees of the tests,
+// small enough to build in a few seconds and covering odd and power of two sizes
+var testLeaves = []int{1, 2, 3, 7, 16, 33}
+
+var (
+	testReportsOnce sync.Once
+	testReports     []*arktree.Report
+	testReportsErr  error
+)
+
+// forEachTestTree runs fn in a subtest for the statistics of the seeded tree
+// of each of testLeaves, built once for all the tests. fn mustn't modify them.
+func forEachTestTree(t *testing.T, fn func(t *testing.T, seed int64, stats *arktree.Report)) {
+	t.Helper()
+	testReportsOnce.Do(func() {
+		for i, numLeaves := range testLeaves {
+			seed := int64(i + 1)
+			stats, err := arktree.GenerateAndAnalyze(arktree.GenerateOptions{
+				NumLeaves: numLeaves,
+				Seed:      &seed,
+				AnalyzeOptions: arktree.AnalyzeOptions{
+					Workers:         1,
+					WithAnchors:     true,
+					VerifyCosigners: true,
+					Feerate:         1,
+				},
+			})
+			if err != nil {
+				testReportsErr = fmt.Errorf("%d leaves: %w", numLeaves, err)
+				return
+			}
+			testReports = append(testReports, stats)
+		}
+	})
+	if testReportsErr != nil {
+		t.Fatal(testReportsErr)
+	}
+	for i, stats := range testReports {
+		t.Run(fmt.Sprintf("%d leaves", stats.NumLeaves), func(t *testing.T) {
+			fn(t, int64(i+1), stats)
+		})
+	}
+}
+
+// seededTree builds the seeded tree of numLeaves leaves
+func seededTree(t *testing.T, numLeaves int, seed int64) *arktree.Generation {
+	t.Helper()
+	generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: numLeaves, Seed: &seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return generation
+}
+
+// seededReport builds the seeded tree of numLeaves leaves and computes its
+// statistics at 1 sat/vB, with anchors
+func seededReport(t *testing.T, numLeaves int, seed int64) *arktree.Report {
+	t.Helper()
+	stats, err := arktree.GenerateAndAnalyze(arktree.GenerateOptions{
+		NumLeaves:      numLeaves,
+		Seed:           &seed,
+		AnalyzeOptions: arktree.AnalyzeOptions{Workers: 1, WithAnchors: true, Feerate: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+// setFlag sets the flag variable at p to value for the rest of the test
+func setFlag[T any](t *testing.T, p *T, value T) {
+	t.Helper()
+	saved := *p
+	*p = value
+	t.Cleanup(func() { *p = saved })
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	marginalSeed       int64
+	marginalRawScripts bool
+)
+
+var marginalCmd = &cobra.Command{
+	Use:   "marginal [number-of-leaves]",
+	Short: "Measure what one more leaf adds to a tree",
+	Long: `Build a tree of N leaves and one of N+1 leaves with the same --seed, the first N leaves of both being the same, and print the change in number of txs, depth and total vsize the extra leaf brings.
+
+The depth only grows when the leaf count crosses a power of two, so the cost of a leaf is a step function: most leaves add a couple of txs, while the leaf crossing a level boundary also adds a level every exit of its part of the tree waits for.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		numLeaves, err := strconv.Atoi(args[0])
+		if err != nil || numLeaves <= 0 {
+			fmt.Printf("Error: Number of leaves must be a positive integer, got %s\n", args[0])
+			os.Exit(1)
+		}
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("🌿 Building Vtxo trees with %d and %d leaves... ", numLeaves, numLeaves+1)
+		start := time.Now()
+		m, err := measureMarginal(numLeaves, marginalSeed, marginalRawScripts, witnessModel())
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ (%s)\n", time.Since(start))
+
+		fmt.Printf("\n➕ MARGINAL COST OF LEAF %d:\n", numLeaves+1)
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, false, true, true, true)
+		t.row("", strconv.Itoa(numLeaves)+" leaves", strconv.Itoa(numLeaves+1)+" leaves", "delta")
+		for _, row := range []struct {
+			name   string
+			values [2]int
+		}{
+			{"Transactions:", m.nodes},
+			{"Depth:", m.depths},
+			{"Tree vsize (vB):", m.vsizes},
+		} {
+			t.row(row.name, strconv.Itoa(row.values[0]), strconv.Itoa(row.values[1]), fmt.Sprintf("%+d", row.values[1]-row.values[0]))
+		}
+		t.flush()
+		fmt.Println(strings.Repeat("─", 40))
+
+		if m.deepens() {
+			fmt.Printf("📏 The extra leaf deepens the tree from %d to %d levels\n", m.depths[0], m.depths[1])
+		} else {
+			fmt.Printf("✅ The extra leaf fits in the %d levels of the tree\n", m.depths[0])
+		}
+	},
+}
+
+func init() {
+	marginalCmd.Flags().Int64Var(&marginalSeed, "seed", 1, "Seed of both trees")
+	marginalCmd.Flags().BoolVar(&marginalRawScripts, "raw-scripts", false, "Use 34 random bytes as leaf scripts instead of valid P2TR scripts (faster, the sizes are the same)")
+	addWitnessFlags(marginalCmd)
+
+	rootCmd.AddCommand(marginalCmd)
+}
+
+// marginal is the size of the trees of N and N+1 leaves
+type marginal struct {
+	nodes, depths, vsizes [2]int
+}
+
+func (m marginal) deepens() bool {
+	return m.depths[1] > m.depths[0]
+}
+
+// measureMarginal builds the trees of numLeaves and numLeaves+1 leaves with
+// seed and measures them
+func measureMarginal(numLeaves int, seed int64, rawScripts bool, witness arktree.WitnessModel) (marginal, error) {
+	var m marginal
+	for i := range 2 {
+		generation, err := arktree.Generate(arktree.GenerateOptions{
+			NumLeaves:  numLeaves + i,
+			RawScripts: rawScripts,
+			Seed:       &seed,
+		})
+		if err != nil {
+			return marginal{}, err
+		}
+		m.nodes[i], err = arktree.NumberOfNodes(generation.Tree)
+		if err != nil {
+			return marginal{}, err
+		}
+		m.depths[i] = arktree.TreeDepth(generation.Tree)
+		m.vsizes[i], err = arktree.TreeVsize(generation.Tree, witness)
+		if err != nil {
+			return marginal{}, err
+		}
+	}
+	return m, nil
+}
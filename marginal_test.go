@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestMeasureMarginal(t *testing.T) {
+	// the fifth leaf deepens the tree, the sixth doesn't
+	for numLeaves, deepens := range map[int]bool{4: true, 5: false} {
+		m, err := measureMarginal(numLeaves, 1, true, arktree.DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.deepens() != deepens {
+			t.Errorf("leaf %d: depth %d then %d", numLeaves+1, m.depths[0], m.depths[1])
+		}
+		if m.nodes[1] <= m.nodes[0] || m.vsizes[1] <= m.vsizes[0] {
+			t.Errorf("leaf %d: %d then %d txs, %d then %d vB", numLeaves+1, m.nodes[0], m.nodes[1], m.vsizes[0], m.vsizes[1])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeMarkdown writes the statistics as GitHub-flavored Markdown tables,
+// ready to paste into an issue or a pull request: the headline metrics, then
+// the number of branches of each size
+func writeMarkdown(w io.Writer, report statsReport) error {
+	metrics := []htmlMetric{
+		{"Leaves", strconv.Itoa(report.Leaves)},
+		{"Total transactions", strconv.Itoa(report.TotalTransactions)},
+		{"Biggest branch", fmt.Sprintf("%.0f tx", report.BranchSizes.Max)},
+		{"Average branch size", fmt.Sprintf("%.1f tx", report.BranchSizes.Mean)},
+		{"Median branch size", fmt.Sprintf("%.1f tx", report.BranchSizes.Median)},
+		{"Most tx to broadcast", fmt.Sprintf("%.2f", report.BroadcastWeights.Max)},
+		{"Average tx to broadcast", fmt.Sprintf("%.2f", report.BroadcastWeights.Mean)},
+		{"Balance", fmt.Sprintf("%.2f", report.Balance)},
+		{"Amortization", fmt.Sprintf("%.2f", report.Amortization)},
+		{"Branching factor", fmt.Sprintf("%.2f", report.BranchingFactor)},
+		{"Size on wire", fmt.Sprintf("%d bytes", report.SizeOnWire)},
+		{"Storage per VTXO", fmt.Sprintf("%.1f bytes", report.StoragePerVtxo.Total)},
+		{"Total value", fmt.Sprintf("%d sats", report.TotalValue)},
+	}
+
+	if _, err := fmt.Fprint(w, "| Metric | Value |\n| --- | ---: |\n"); err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "| %s | %s |\n", m.Name, m.Value); err != nil {
+			return err
+		}
+	}
+
+	histogram := newHTMLHistogram("Branch sizes", report.BranchSizes.Counts)
+	if _, err := fmt.Fprint(w, "\n| Branch size | Branches |\n| ---: | ---: |\n"); err != nil {
+		return err
+	}
+	for _, bar := range histogram.Bars {
+		// the distribution labels sizes as floats, they are whole tx counts
+		size, err := strconv.ParseFloat(bar.Label, 64)
+		if err != nil {
+			return fmt.Errorf("invalid branch size %q: %w", bar.Label, err)
+		}
+		if _, err := fmt.Fprintf(w, "| %.0f | %d |\n", size, bar.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdownHistogram(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	var b strings.Builder
+	if err := writeMarkdown(&b, newStatsReport(stats, nil)); err != nil {
+		t.Fatal(err)
+	}
+	_, histogram, ok := strings.Cut(b.String(), "| Branch size | Branches |\n| ---: | ---: |\n")
+	if !ok {
+		t.Fatalf("no branch size table in %q", b.String())
+	}
+	branches := 0
+	for _, row := range strings.Split(strings.TrimSuffix(histogram, "\n"), "\n") {
+		var size, count int
+		if _, err := fmt.Sscanf(row, "| %d | %d |", &size, &count); err != nil {
+			t.Fatalf("row %q: %s", row, err)
+		}
+		branches += count
+	}
+	if branches != stats.NumLeaves {
+		t.Errorf("%d branches for %d leaves", branches, stats.NumLeaves)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// openMetricsFamily is a metric family of the OpenMetrics output: its
+// samples are written under its TYPE, UNIT and HELP lines. The name ends with
+// the unit, as the format requires, if there is one.
+type openMetricsFamily struct {
+	name, unit, help string
+	samples          []openMetricsSample
+}
+
+// openMetricsSample is a gauge sample, with its labels already formatted
+type openMetricsSample struct {
+	labels string
+	value  float64
+}
+
+// statSamples returns a sample per statistic of a distribution, labelled stat
+func statSamples(max, mean, median float64) []openMetricsSample {
+	return []openMetricsSample{
+		{`{stat="max"}`, max},
+		{`{stat="mean"}`, mean},
+		{`{stat="median"}`, median},
+	}
+}
+
+// writeOpenMetrics writes the headline statistics in the OpenMetrics text
+// format, for scrapers validating it strictly: a gauge family per statistic
+// with its unit, and the branch sizes as a histogram whose buckets carry the
+// leaf txid of one of their branches as exemplar, then the terminating # EOF
+func writeOpenMetrics(w io.Writer, report statsReport) error {
+	families := []openMetricsFamily{
+		{"arktree_leaves", "", "Leaves of the tree, one per VTXO", []openMetricsSample{{"", float64(report.Leaves)}}},
+		{"arktree_tree_size_transactions", "transactions", "Transactions of the tree", []openMetricsSample{{"", float64(report.TotalTransactions)}}},
+		{"arktree_broadcast_weight_transactions", "transactions", "Transactions a user broadcasts to exit, each shared between its cosigners",
+			statSamples(report.BroadcastWeights.Max, report.BroadcastWeights.Mean, report.BroadcastWeights.Median)},
+		{"arktree_balance_ratio", "ratio", "Balance of the tree, 1 when every leaf is at the same depth", []openMetricsSample{{"", report.Balance}}},
+		{"arktree_size_on_wire_bytes", "bytes", "Serialized size of the tree with its witnesses and metadata", []openMetricsSample{{"", float64(report.SizeOnWire)}}},
+		{"arktree_total_value_satoshis", "satoshis", "Value owned by the leaves", []openMetricsSample{{"", float64(report.TotalValue)}}},
+		{"arktree_tree_fees_satoshis", "satoshis", "Per-tx fees paid by the transactions of the tree", []openMetricsSample{{"", float64(report.TreeFees)}}},
+	}
+	if times := report.ExitTimes; times != nil {
+		families = append(families, openMetricsFamily{"arktree_exit_time_seconds", "seconds", "Time a user takes to exit, from the start of the exit to the confirmation of their branch", []openMetricsSample{
+			{`{stat="min"}`, times.Min},
+			{`{stat="median"}`, times.Median},
+			{`{stat="max"}`, times.Max},
+		}})
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, family := range families {
+		writeOpenMetricsHeader(bw, "gauge", family.name, family.unit, family.help)
+		for _, sample := range family.samples {
+			fmt.Fprintf(bw, "%s%s %s\n", family.name, sample.labels, formatOpenMetricsValue(sample.value))
+		}
+	}
+	writeBranchSizeHistogram(bw, report.Branches)
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+func writeOpenMetricsHeader(w io.Writer, kind, name, unit, help string) {
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	if unit != "" {
+		fmt.Fprintf(w, "# UNIT %s %s\n", name, unit)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+}
+
+// writeBranchSizeHistogram writes the branch sizes as a histogram with a
+// bucket per size, each bucket's exemplar being the first leaf by txid whose
+// branch has that size
+func writeBranchSizeHistogram(w io.Writer, branches []arktree.BranchSummary) {
+	const name = "arktree_branch_size_transactions"
+	writeOpenMetricsHeader(w, "histogram", name, "transactions", "Transactions of the branch of each leaf, broadcast by its user to exit alone")
+
+	counts := make(map[int]int)
+	exemplars := make(map[int]string)
+	sum := 0
+	for _, branch := range branches {
+		counts[branch.Size]++
+		if txid, ok := exemplars[branch.Size]; !ok || branch.LeafTxid < txid {
+			exemplars[branch.Size] = branch.LeafTxid
+		}
+		sum += branch.Size
+	}
+	sizes := make([]int, 0, len(counts))
+	for size := range counts {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	cumulative := 0
+	for _, size := range sizes {
+		cumulative += counts[size]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d # {leaf_txid=\"%s\"} %d\n",
+			name, strconv.FormatFloat(float64(size), 'f', 1, 64), cumulative, exemplars[size], size)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, cumulative)
+	fmt.Fprintf(w, "%s_count %d\n", name, cumulative)
+	fmt.Fprintf(w, "%s_sum %d\n", name, sum)
+}
+
+// formatOpenMetricsValue formats v as the shortest float parsing back to it
+func formatOpenMetricsValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	var b strings.Builder
+	if err := writeOpenMetrics(&b, newStatsReport(stats, nil)); err != nil {
+		t.Fatal(err)
+	}
+	text, ok := strings.CutSuffix(b.String(), "# EOF\n")
+	if !ok {
+		t.Fatalf("no trailing # EOF in %q", b.String())
+	}
+	// every sample follows the header of its family
+	family := ""
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			family, _, _ = strings.Cut(name, " ")
+			continue
+		}
+		if strings.HasPrefix(line, "# ") {
+			continue
+		}
+		if family == "" || !strings.HasPrefix(line, family) {
+			t.Errorf("sample %q outside of its family %q", line, family)
+		}
+	}
+	inf := fmt.Sprintf("arktree_branch_size_transactions_bucket{le=\"+Inf\"} %d\n", stats.NumLeaves)
+	if !strings.Contains(text, inf) {
+		t.Errorf("no +Inf bucket of the %d leaves", stats.NumLeaves)
+	}
+}
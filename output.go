@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputText        = "text"
+	outputYAML        = "yaml"
+	outputJSON        = "json"
+	outputNewick      = "newick"
+	outputProtobuf    = "protobuf"
+	outputHTML        = "html"
+	outputMarkdown    = "markdown"
+	outputAdjacency   = "adjacency"
+	outputSQL         = "sql"
+	outputDOT         = "dot"
+	outputFlame       = "flamegraph"
+	outputOpenMetrics = "openmetrics"
+	outputInflux      = "influx"
+	outputJSONL       = "jsonl" // with --seeds-stdin
+)
+
+// validateOutputFormat checks a format of --output
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputYAML, outputJSON, outputNewick, outputProtobuf, outputHTML, outputMarkdown, outputOpenMetrics, outputInflux, outputAdjacency, outputSQL, outputDOT, outputFlame, outputJSONL:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q (expected %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s or %s)",
+			format, outputText, outputYAML, outputJSON, outputNewick, outputProtobuf, outputHTML, outputMarkdown, outputOpenMetrics, outputInflux, outputAdjacency, outputSQL, outputDOT, outputFlame, outputJSONL)
+	}
+}
+
+// outputTarget is where one format of --output is written, stdout if path is empty
+type outputTarget struct {
+	format string
+	path   string
+}
+
+// outputs are the targets of --output, set once it's parsed
+var outputs []outputTarget
+
+// parseOutputs parses the comma separated formats of --output. A single
+// format is written to stdout. With several, text is written to stdout and
+// the other formats to files named after exportPath, which is then required
+// so that no two formats are written to stdout.
+func parseOutputs(value, exportPath string) ([]outputTarget, error) {
+	formats := strings.Split(value, ",")
+	targets := make([]outputTarget, 0, len(formats))
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		format = strings.TrimSpace(format)
+		if err := validateOutputFormat(format); err != nil {
+			return nil, err
+		}
+		if seen[format] {
+			return nil, fmt.Errorf("output format %s is given twice", format)
+		}
+		seen[format] = true
+		targets = append(targets, outputTarget{format: format})
+	}
+
+	if len(targets) == 1 {
+		return targets, nil
+	}
+
+	for i, target := range targets {
+		if target.format == outputText {
+			continue
+		}
+		if exportPath == "" {
+			return nil, fmt.Errorf("output formats %s would all be written to stdout, set --out to write all but text to files", value)
+		}
+		targets[i].path = derivedOutputPath(exportPath, target.format)
+	}
+	return targets, nil
+}
+
+// derivedOutputPath names the file format is written to after the path the
+// tree is exported to: tree.json.gz gives tree.stats.json for json
+func derivedOutputPath(exportPath, format string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(exportPath, ".gz"), ".json")
+	switch format {
+	case outputJSON:
+		return base + ".stats.json"
+	case outputNewick:
+		return base + ".nwk"
+	case outputProtobuf:
+		return base + ".pb"
+	case outputAdjacency:
+		return base + ".edges"
+	case outputMarkdown:
+		return base + ".md"
+	case outputFlame:
+		return base + ".folded"
+	case outputOpenMetrics:
+		return base + ".om"
+	case outputInflux:
+		return base + ".lp"
+	default:
+		return base + "." + format
+	}
+}
+
+// stdoutFormat returns the format written to stdout, empty if all of them
+// are written to files. Before --output is parsed it returns its raw value.
+func stdoutFormat() string {
+	if outputs == nil {
+		return outputFormat
+	}
+	for _, target := range outputs {
+		if target.path == "" {
+			return target.format
+		}
+	}
+	return ""
+}
+
+// hasOutput reports whether format is one of the formats of --output
+func hasOutput(format string) bool {
+	for _, target := range outputs {
+		if target.format == format {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOutput writes to the target of format with write, doing nothing if
+// format isn't one of the formats of --output. Writing to a file is reported
+// on out with description.
+func writeOutput(out io.Writer, format, description string, write func(io.Writer) error) error {
+	for _, target := range outputs {
+		if target.format != format {
+			continue
+		}
+		if target.path == "" {
+			return write(os.Stdout)
+		}
+
+		fmt.Fprintf(out, "💾 Writing %s to %s... ", description, target.path)
+		f, err := os.Create(target.path)
+		if err != nil {
+			return err
+		}
+		if err := write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "✅")
+		return nil
+	}
+	return nil
+}
+
+// treeNode is the nested representation of a tree used by the structured outputs
+type treeNode struct {
+	Txid      string      `json:"txid" yaml:"txid"`
+	Cosigners []string    `json:"cosigners" yaml:"cosigners"`
+	Leaves    int         `json:"leaves,omitempty" yaml:"leaves,omitempty"`
+	Children  []*treeNode `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// nestedTree converts the graph to its nested representation,
+// children are ordered by the output index they spend.
+// With withLeafCounts, each node is annotated with the number of leaves of its subtree
+func nestedTree(g *tree.TxGraph, withLeafCounts bool) (*treeNode, error) {
+	node, leaves, err := nestedSubtree(g)
+	if err != nil {
+		return nil, err
+	}
+
+	if withLeafCounts {
+		if expected := len(g.Leaves()); leaves != expected {
+			return nil, fmt.Errorf("root has %d leaves in its subtree, expected %d", leaves, expected)
+		}
+	} else {
+		clearLeafCounts(node)
+	}
+
+	return node, nil
+}
+
+// nestedSubtree converts g in a single post-order traversal and returns the
+// number of leaves of its subtree
+func nestedSubtree(g *tree.TxGraph) (*treeNode, int, error) {
+	keys, err := tree.GetCosignerKeys(g.Root.Inputs[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	node := &treeNode{
+		Txid:      g.Root.UnsignedTx.TxID(),
+		Cosigners: make([]string, 0, len(keys)),
+	}
+	for _, key := range keys {
+		node.Cosigners = append(node.Cosigners, hex.EncodeToString(key.SerializeCompressed()))
+	}
+
+	outputIndexes := make([]int, 0, len(g.Children))
+	for index := range g.Children {
+		outputIndexes = append(outputIndexes, int(index))
+	}
+	sort.Ints(outputIndexes)
+
+	for _, index := range outputIndexes {
+		child, leaves, err := nestedSubtree(g.Children[uint32(index)])
+		if err != nil {
+			return nil, 0, err
+		}
+		node.Children = append(node.Children, child)
+		node.Leaves += leaves
+	}
+
+	if len(node.Children) == 0 {
+		node.Leaves = 1
+	}
+
+	return node, node.Leaves, nil
+}
+
+func clearLeafCounts(node *treeNode) {
+	node.Leaves = 0
+	for _, child := range node.Children {
+		clearLeafCounts(child)
+	}
+}
+
+// broadcastOrder returns the txids of all the nodes in an order they can be
+// broadcast in: level by level from the root, each level ordered by parent
+// then by the output index the node spends
+func broadcastOrder(g *tree.TxGraph) []string {
+	var order []string
+	level := []*tree.TxGraph{g}
+	for len(level) > 0 {
+		var next []*tree.TxGraph
+		for _, node := range level {
+			order = append(order, node.Root.UnsignedTx.TxID())
+
+			outputIndexes := make([]int, 0, len(node.Children))
+			for index := range node.Children {
+				outputIndexes = append(outputIndexes, int(index))
+			}
+			sort.Ints(outputIndexes)
+			for _, index := range outputIndexes {
+				next = append(next, node.Children[uint32(index)])
+			}
+		}
+		level = next
+	}
+	return order
+}
+
+// treeChecksum returns the hex SHA256 of the txids of all the nodes in
+// broadcast order, one per line. Txids commit to the whole tx and the order
+// doesn't depend on map iteration, so identical trees have identical checksums.
+func treeChecksum(g *tree.TxGraph) string {
+	h := sha256.New()
+	for _, txid := range broadcastOrder(g) {
+		io.WriteString(h, txid+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeBroadcastOrder writes the broadcast order as a numbered list, or as a
+// JSON array if asJSON
+func writeBroadcastOrder(w io.Writer, g *tree.TxGraph, asJSON bool) error {
+	order := broadcastOrder(g)
+	if asJSON {
+		return json.NewEncoder(w).Encode(order)
+	}
+
+	for i, txid := range order {
+		if _, err := fmt.Fprintf(w, "%d. %s\n", i+1, txid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeYAML writes the nested tree topology as YAML
+func writeYAML(w io.Writer, g *tree.TxGraph, withLeafCounts bool) error {
+	root, err := nestedTree(g, withLeafCounts)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// defaultTxidLength is the default of --txid-length
+const defaultTxidLength = 8
+
+// txidLength is the global --txid-length flag: the number of characters txids
+// are shortened to in the tables and diagrams, unless more are needed to tell
+// them apart, 0 to keep them whole
+var txidLength int
+
+// txidPrefixLength returns the shortest length, at least txidLength, for
+// which the prefixes of txids are all distinct, the longest txid with a
+// txidLength of 0
+func txidPrefixLength(txids []string) int {
+	longest := 0
+	for _, txid := range txids {
+		longest = max(longest, len(txid))
+	}
+	if txidLength == 0 {
+		return longest
+	}
+
+	for length := txidLength; length < longest; length++ {
+		prefixes := make(map[string]bool, len(txids))
+		for _, txid := range txids {
+			prefixes[txid[:min(length, len(txid))]] = true
+		}
+		if len(prefixes) == len(txids) {
+			return length
+		}
+	}
+	return max(longest, txidLength)
+}
+
+// warnTxidLength warns on w when txids were shortened to length characters
+// to tell them apart, more than an explicit --txid-length, which would have
+// made them ambiguous
+func warnTxidLength(w io.Writer, length int) {
+	if rootCmd.PersistentFlags().Changed("txid-length") && txidLength > 0 && length > txidLength {
+		warnf(w, "%d-character txids are ambiguous, showing %d characters", txidLength, length)
+	}
+}
+
+// txidShortener returns a function shortening txids to txidPrefixLength of
+// txids, the set they must be told apart in, see warnTxidLength
+func txidShortener(w io.Writer, txids []string) func(string) string {
+	length := txidPrefixLength(txids)
+	warnTxidLength(w, length)
+	return func(txid string) string {
+		return txid[:min(len(txid), length)]
+	}
+}
+
+// writeNewick writes the tree topology as a Newick string labelled by txids
+// shortened by txidPrefixLength, children ordered by the output index they
+// spend. It returns the length of the labels.
+func writeNewick(w io.Writer, g *tree.TxGraph) (int, error) {
+	root, err := nestedTree(g, false)
+	if err != nil {
+		return 0, err
+	}
+	prefixLength := txidPrefixLength(broadcastOrder(g))
+
+	var b strings.Builder
+	appendNewick(&b, root, prefixLength)
+	b.WriteString(";\n")
+	_, err = io.WriteString(w, b.String())
+	return prefixLength, err
+}
+
+func appendNewick(b *strings.Builder, node *treeNode, prefixLength int) {
+	if len(node.Children) > 0 {
+		b.WriteByte('(')
+		for i, child := range node.Children {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			appendNewick(b, child, prefixLength)
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString(newickLabel(node.Txid[:min(len(node.Txid), prefixLength)]))
+}
+
+// writeAdjacency writes the edges of the tree as an adjacency list, one
+// "parent_txid child_txid" line per edge, parents before their children and
+// siblings by output index. A tree without edges is written as the line of its
+// root alone, so that no node is lost.
+func writeAdjacency(w io.Writer, g *tree.TxGraph) error {
+	bw := bufio.NewWriter(w)
+	if len(g.Children) == 0 {
+		fmt.Fprintln(bw, g.Root.UnsignedTx.TxID())
+	}
+	if err := arktree.Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		if parent != nil {
+			_, err := fmt.Fprintf(bw, "%s %s\n", parent.Root.UnsignedTx.TxID(), node.Root.UnsignedTx.TxID())
+			return err
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeDOT writes the tree as a Graphviz digraph, parents pointing to their
+// children, its nodes labelled by txids shortened by txidPrefixLength. A
+// non-empty caption is set as the label of the graph, rendered under it, and
+// the nodes of fills are filled with their color. It returns the length of
+// the labels.
+func writeDOT(w io.Writer, g *tree.TxGraph, caption string, fills map[*tree.TxGraph]string) (int, error) {
+	prefixLength := txidPrefixLength(broadcastOrder(g))
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph arktree {")
+	fmt.Fprintln(bw, `  node [shape=box, fontname="monospace"];`)
+	if caption != "" {
+		fmt.Fprintf(bw, "  label=%s;\n  labelloc=b;\n", strconv.Quote(caption))
+	}
+	if err := arktree.Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		txid := node.Root.UnsignedTx.TxID()
+		if fill, ok := fills[node]; ok {
+			fmt.Fprintf(bw, "  %q [label=%q, style=filled, fillcolor=%q];\n", txid, txid[:min(len(txid), prefixLength)], fill)
+		} else {
+			fmt.Fprintf(bw, "  %q [label=%q];\n", txid, txid[:min(len(txid), prefixLength)])
+		}
+		if parent != nil {
+			fmt.Fprintf(bw, "  %q -> %q;\n", parent.Root.UnsignedTx.TxID(), txid)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	fmt.Fprintln(bw, "}")
+	return prefixLength, bw.Flush()
+}
+
+// writeFlamegraph writes the aggregate exit cost of the tree as folded
+// stacks, the input of flamegraph renderers such as flamegraph.pl: a line per
+// node, its path from the root as txids shortened by txidPrefixLength joined
+// by ";", then its cost times the number of leaves under it, the exits
+// broadcasting it. The cost of a node is 1 or its vsize with unit, so that the
+// width of a subtree is what the unilateral exits of every user spend on its
+// txs. It returns the length of the labels.
+func writeFlamegraph(w io.Writer, g *tree.TxGraph, unit arktree.WeightUnit, witness arktree.WitnessModel) (int, error) {
+	prefixLength := txidPrefixLength(broadcastOrder(g))
+
+	leaves := arktree.LeavesUnder(g)
+
+	bw := bufio.NewWriter(w)
+	stacks := make(map[*tree.TxGraph]string)
+	if err := arktree.Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		txid := node.Root.UnsignedTx.TxID()
+		stack := txid[:min(len(txid), prefixLength)]
+		if parent != nil {
+			stack = stacks[parent] + ";" + stack
+		}
+		stacks[node] = stack
+
+		cost := 1
+		switch unit {
+		case "", arktree.WeightByCount:
+		case arktree.WeightByVsize:
+			vsize, err := witness.Vsize(node.Root)
+			if err != nil {
+				return err
+			}
+			cost = vsize
+		default:
+			return fmt.Errorf("unknown weight unit %q, expected %s or %s", unit, arktree.WeightByCount, arktree.WeightByVsize)
+		}
+		_, err := fmt.Fprintf(bw, "%s %d\n", stack, cost*leaves[node])
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	return prefixLength, bw.Flush()
+}
+
+// dotCaption returns the headline statistics of g captioning its DOT graph
+// with --dot-stats: leaves, depth, nodes and the biggest broadcast weight
+func dotCaption(g *tree.TxGraph, workers int) (string, error) {
+	nodes, err := arktree.NumberOfNodes(g)
+	if err != nil {
+		return "", err
+	}
+	_, weights, err := arktree.BranchStatsParallel(g, workers)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d leaves, depth %d, %d nodes, max branch weight %.2f",
+		len(arktree.LeafTxids(g)), arktree.TreeDepth(g), nodes, arktree.MaxFloat(weights)), nil
+}
+
+// newickLabel quotes label if it contains characters with a meaning in
+// Newick, doubling its single quotes
+func newickLabel(label string) string {
+	if !strings.ContainsAny(label, " \t\n()[]':;,_") {
+		return label
+	}
+	return "'" + strings.ReplaceAll(label, "'", "''") + "'"
+}
+
+// writeCDF writes the empirical CDF of the branch sizes as CSV: each distinct
+// size in ascending order with the fraction of branches at or below it
+func writeCDF(w io.Writer, branchSizes []int) error {
+	sizeCount := make(map[int]int)
+	for _, size := range branchSizes {
+		sizeCount[size]++
+	}
+
+	sizes := make([]int, 0, len(sizeCount))
+	for size := range sizeCount {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"branch_size", "count", "cumulative_fraction"}); err != nil {
+		return err
+	}
+
+	cumulative := 0
+	for _, size := range sizes {
+		cumulative += sizeCount[size]
+		if err := cw.Write([]string{
+			strconv.Itoa(size),
+			strconv.Itoa(sizeCount[size]),
+			strconv.FormatFloat(float64(cumulative)/float64(len(branchSizes)), 'f', 4, 64),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestTxidPrefixLength(t *testing.T) {
+	txid := func(prefix string) string {
+		return prefix + strings.Repeat("0", 64-len(prefix))
+	}
+	for _, test := range []struct {
+		name       string
+		txidLength int
+		txids      []string
+		want       int
+	}{
+		{"distinct 8-char prefixes are kept", defaultTxidLength, []string{txid("aaaaaaaa"), txid("aaaaaaab"), txid("b")}, defaultTxidLength},
+		{"colliding 8-char prefixes are lengthened just enough", defaultTxidLength, []string{txid("aaaaaaaa11"), txid("aaaaaaaa12"), txid("b")}, 10},
+		{"identical txids are kept whole", defaultTxidLength, []string{txid("a"), txid("a")}, 64},
+		{"a txid length of 0 keeps txids whole", 0, []string{txid("a"), txid("b")}, 64},
+		{"a longer txid length is kept", 12, []string{txid("aaaa1"), txid("aaaa2"), txid("b")}, 12},
+		{"a shorter txid length is lengthened", 2, []string{txid("aaaa1"), txid("aaaa2"), txid("b")}, 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			setFlag(t, &txidLength, test.txidLength)
+			if length := txidPrefixLength(test.txids); length != test.want {
+				t.Errorf("%d characters, expected %d", length, test.want)
+			}
+		})
+	}
+}
+
+func TestTxidShortener(t *testing.T) {
+	setFlag(t, &txidLength, 2)
+	txids := []string{"aaaa1" + strings.Repeat("0", 59), "aaaa2" + strings.Repeat("0", 59)}
+	if short := txidShortener(io.Discard, txids)(txids[0]); short != "aaaa1" {
+		t.Errorf("shortened to %q, expected aaaa1", short)
+	}
+}
+
+func TestWriteFlamegraph(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	wantCount, wantVsize := 0, 0
+	for i, size := range stats.BranchSizes {
+		wantCount += size
+		wantVsize += stats.ExitCosts[i].Vsize
+	}
+	for unit, want := range map[arktree.WeightUnit]int{arktree.WeightByCount: wantCount, arktree.WeightByVsize: wantVsize} {
+		var sb strings.Builder
+		if _, err := writeFlamegraph(&sb, stats.Tree, unit, arktree.DefaultWitnessModel); err != nil {
+			t.Fatal(err)
+		}
+		total := 0
+		for _, line := range strings.Split(strings.TrimSpace(sb.String()), "\n") {
+			stack, count, ok := strings.Cut(line, " ")
+			if !ok || strings.Count(stack, ";") >= stats.Depth {
+				t.Fatalf("invalid line %q", line)
+			}
+			n, err := strconv.Atoi(count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += n
+		}
+		if total != want {
+			t.Errorf("stacks by %s add up to %d, expected %d", unit, total, want)
+		}
+	}
+}
+
+func TestWriteAdjacency(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *arktree.Report) {
+		var b strings.Builder
+		if err := writeAdjacency(&b, stats.Tree); err != nil {
+			t.Fatal(err)
+		}
+		edges := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		if len(edges) != max(1, stats.TotalSize-1) {
+			t.Errorf("%d lines for %d txs", len(edges), stats.TotalSize)
+		}
+	})
+}
+
+func TestWriteDOT(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *arktree.Report) {
+		caption, err := dotCaption(stats.Tree, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if _, err := writeDOT(&b, stats.Tree, caption, nil); err != nil {
+			t.Fatal(err)
+		}
+		dot := b.String()
+		if nodes, edges := strings.Count(dot, "[label="), strings.Count(dot, " -> "); nodes != stats.TotalSize || edges != stats.TotalSize-1 {
+			t.Errorf("%d nodes and %d edges for %d txs", nodes, edges, stats.TotalSize)
+		}
+		if want := fmt.Sprintf("label=\"%d leaves, depth %d,", stats.NumLeaves, stats.Depth); !strings.Contains(dot, want) {
+			t.Errorf("caption %q doesn't start with %s", caption, want)
+		}
+	})
+}
+
+func TestWriteNewick(t *testing.T) {
+	g := seededTree(t, 3, 1).Tree
+	var b strings.Builder
+	prefixLength, err := writeNewick(&b, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := func(node *tree.TxGraph) string { return node.Root.UnsignedTx.TxID()[:prefixLength] }
+
+	// the seeded tree of 3 leaves splits its first output into 2 leaves
+	first, second := g.Children[0], g.Children[1]
+	if first == nil || second == nil || len(first.Children) != 2 || len(second.Children) != 0 {
+		t.Fatal("unexpected shape of the seeded tree")
+	}
+	want := fmt.Sprintf("((%s,%s)%s,%s)%s;\n",
+		label(first.Children[0]), label(first.Children[1]), label(first), label(second), label(g))
+	if got := b.String(); got != want {
+		t.Errorf("%q, expected %q", got, want)
+	}
+}
+
+func TestNewickLabel(t *testing.T) {
+	for _, test := range []struct {
+		label, want string
+	}{
+		{"a96eaa9f", "a96eaa9f"},
+		{"two words", "'two words'"},
+		{"it's", "'it''s'"},
+		{"a:b", "'a:b'"},
+		{"f(x)", "'f(x)'"},
+		{"leaf_1", "'leaf_1'"},
+	} {
+		if got := newickLabel(test.label); got != test.want {
+			t.Errorf("newickLabel(%q) = %q, expected %q", test.label, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+)
+
+// paginate is the global --paginate flag, piping stdout through $PAGER when
+// it is a terminal
+var paginate bool
+
+// defaultPager is run when $PAGER is empty
+const defaultPager = "less"
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runPaged runs the command again with its stdout piped to the pager and
+// exits with its status once the pager quits. The command runs in a process
+// of its own as it may exit anywhere, which would leave the pager without a
+// parent holding the terminal; its stdout being the pipe, it doesn't page
+// again. Stderr isn't paged, so that errors and warnings stay visible.
+func runPaged() error {
+	pagerCommand := strings.Fields(os.Getenv("PAGER"))
+	if len(pagerCommand) == 0 {
+		pagerCommand = []string{defaultPager}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	pager := exec.Command(pagerCommand[0], pagerCommand[1:]...)
+	pager.Stdin, pager.Stdout, pager.Stderr = r, os.Stdout, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// as git does: quit if the output fits on one screen, keep the
+		// colors and don't clear the screen on exit
+		pager.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := pager.Start(); err != nil {
+		return fmt.Errorf("failed to start pager %q: %w", pagerCommand[0], err)
+	}
+
+	command := exec.Command(self, os.Args[1:]...)
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, w, os.Stderr
+	// Ctrl-C reaches both processes, only the command and the pager handle
+	// it. The signal isn't ignored, which the command would inherit.
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+	err = command.Start()
+	r.Close()
+	if err == nil {
+		err = command.Wait()
+	}
+	w.Close()
+	pager.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(max(exitErr.ExitCode(), 1))
+	}
+	if err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// partitionRoundID partitions the leaves of a leaves file by their round_id
+const partitionRoundID = "round_id"
+
+// validatePartitionBy checks --partition-by against the other generate flags:
+// every partition builds its own tree, so only their statistics are printed
+func validatePartitionBy() error {
+	if partitionBy == "" {
+		return nil
+	}
+
+	switch {
+	case partitionBy != partitionRoundID:
+		return fmt.Errorf("unknown partition field %q, expected %s", partitionBy, partitionRoundID)
+	case len(leavesFiles) == 0:
+		return errors.New("--partition-by requires --leaves-file")
+	case stdoutFormat() != outputText || len(outputs) > 1:
+		return errors.New("--partition-by only prints text statistics, it can't be used with --output")
+	case outPath != "" || keysOutput != "":
+		return errors.New("--partition-by can't be used with --out or --keys-output")
+	case leafTxidsOnly || broadcastOrderOnly || cdf:
+		return errors.New("--partition-by can't be used with --leaf-txids-only, --broadcast-order or --cdf")
+	}
+	return nil
+}
+
+// leafPartition is the leaves of a leaves file sharing a partition key
+type leafPartition struct {
+	key    string
+	leaves *leafSet
+}
+
+// partitionLeaves splits set by round id, the partitions in the order their
+// first leaf comes in the file. Every leaf needs a round id.
+func partitionLeaves(set *leafSet) ([]leafPartition, error) {
+	var partitions []leafPartition
+	byKey := make(map[string]int)
+	for i, leaf := range set.leaves {
+		key := ""
+		if set.rounds != nil {
+			key = set.rounds[i]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("leaf %d has no %s", set.indexes[i], partitionRoundID)
+		}
+
+		p, ok := byKey[key]
+		if !ok {
+			p = len(partitions)
+			byKey[key] = p
+			partitions = append(partitions, leafPartition{key: key, leaves: &leafSet{}})
+		}
+		part := partitions[p].leaves
+		part.leaves = append(part.leaves, leaf)
+		part.indexes = append(part.indexes, set.indexes[i])
+		if set.weights != nil {
+			part.weights = append(part.weights, set.weights[i])
+		}
+		if set.labels != nil {
+			part.labels = append(part.labels, set.labels[i])
+		}
+	}
+	return partitions, nil
+}
+
+// generatePartitions builds a tree with opts for every partition of set and
+// prints its statistics in a section of its own, then the statistics of all
+// the trees together, their branches pooled as aggregate does
+func generatePartitions(set *leafSet, opts arktree.GenerateOptions, analyzeOpts arktree.AnalyzeOptions) error {
+	partitions, err := partitionLeaves(set)
+	if err != nil {
+		return err
+	}
+
+	var (
+		transactions int
+		value        int64
+		sizes        = newPooledDistribution()
+		weights      = newPooledDistribution()
+	)
+	for _, partition := range partitions {
+		opts.Leaves = partition.leaves.leaves
+		opts.NumLeaves = len(opts.Leaves)
+		generation, err := arktree.Generate(opts)
+		if err != nil {
+			return fmt.Errorf("round %s: %w", partition.key, err)
+		}
+		stats, err := analyze(generation.Tree, analyzeOpts)
+		if err != nil {
+			return fmt.Errorf("round %s: %w", partition.key, err)
+		}
+
+		fmt.Printf("\n🔁 ROUND %s: %d leaves\n", partition.key, len(opts.Leaves))
+		printStats(stats)
+		exitIfPartial(stats)
+
+		report := newStatsReport(stats, nil)
+		if err := sizes.add(report.BranchSizes); err != nil {
+			return fmt.Errorf("round %s: branch sizes: %w", partition.key, err)
+		}
+		if err := weights.add(report.BroadcastWeights); err != nil {
+			return fmt.Errorf("round %s: broadcast weights: %w", partition.key, err)
+		}
+		transactions += stats.TotalSize
+		if value, err = arktree.SumValues([]int64{value, stats.TotalValue}); err != nil {
+			return fmt.Errorf("total value of the rounds: %w", err)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("─", 60))
+	fmt.Println("📊 ALL ROUNDS")
+	fmt.Println(strings.Repeat("─", 60))
+
+	t := newTable(os.Stdout, false, true)
+	t.row("🔁 Rounds:", strconv.Itoa(len(partitions)))
+	t.row("🍃 Total Leaves:", strconv.Itoa(len(set.leaves)))
+	t.row("🌳 Total Transactions:", strconv.Itoa(transactions))
+	t.row("💰 Total Value:", strconv.FormatInt(value, 10), "sats")
+	t.flush()
+
+	printPooled("🌿 BRANCH SIZE", sizes)
+	printPooled("📡 TX TO BROADCAST", weights)
+
+	fmt.Println(strings.Repeat("─", 60))
+	return nil
+}
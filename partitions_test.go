@@ -0,0 +1,31 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPartitionLeaves(t *testing.T) {
+	a, b, c := testScripts[0], testScripts[1], testScripts[2]
+	set := &leafSet{
+		leaves:  leavesOf(a, b, c, a),
+		indexes: []int{0, 2, 3, 4},
+		rounds:  []string{"r2", "r1", "r2", "r1"},
+	}
+	partitions, err := partitionLeaves(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 2 || partitions[0].key != "r2" || partitions[1].key != "r1" {
+		t.Fatalf("expected rounds r2 then r1, got %d partitions", len(partitions))
+	}
+	if got := partitions[0].leaves.indexes; !slices.Equal(got, []int{0, 3}) {
+		t.Errorf("round r2 has the leaves %v, expected [0 3]", got)
+	}
+
+	set.rounds[2] = ""
+	if _, err := partitionLeaves(set); err == nil || !strings.Contains(err.Error(), "leaf 3 has no round_id") {
+		t.Errorf("expected leaf 3 to be missing its round, got %v", err)
+	}
+}
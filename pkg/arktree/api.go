@@ -0,0 +1,160 @@
+// Package arktree builds Ark vtxo trees and computes their statistics. It is
+// the core of the arktree CLI, which only parses flags and formats the Report.
+package arktree
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// GenerateOptions holds the settings of a generation. The zero value of each
+// field falls back to the default of the generate command.
+type GenerateOptions struct {
+	NumLeaves      int
+	Leaves         []tree.Leaf // built as given instead of generating NumLeaves leaves
+	Amount         uint64      // sats of each generated leaf, DefaultLeafAmount if zero
+	AmountSamples  []uint64    // amounts the generated leaves draw theirs from, takes precedence over Amount
+	SharedCosigner bool        // same as CosignerGroups 1
+	CosignerGroups int         // leaves cosigned by one key per group, see CosignerGroup
+	RawScripts     bool
+	Locktime       *common.RelativeLocktime // DefaultLocktime if nil
+	Seed           *int64                   // crypto/rand if nil
+	Rand           io.Reader                // source of randomness, takes precedence over Seed
+	CosignerSeed   []byte                   // cosigner keys derived from it if not nil, see DeriveCosignerKey
+	SweepTreeRoot  []byte                   // SweepTreeRootSize bytes tweaking the outputs, random if nil
+	TxVersion      *int32                   // nVersion required of every tx, unchecked if nil
+	TxLocktime     *uint32                  // nLockTime required of every tx, unchecked if nil
+	InputAmount    int64                    // sats of the funding input the leaves must fit in before building, unchecked if zero
+
+	AnalyzeOptions
+}
+
+// SweepTreeRootSize is the size of a sweep tree root, the taproot merkle root
+// of the sweep script tweaking the outputs of a tree
+const SweepTreeRootSize = 32
+
+// Generation is a built tree along with the leaves it was built from
+type Generation struct {
+	Tree          *tree.TxGraph
+	Leaves        []tree.Leaf
+	CosignerKeys  []*secp256k1.PrivateKey // private cosigner key of each leaf, nil if the Leaves were given
+	SweepTreeRoot []byte                  // taproot tweak of the outputs, needed to sign the tree
+	Timings       []PhaseTiming
+}
+
+// Generate builds the tree described by opts. The sweep tree root and the root
+// input are random, read before the leaves so that a seed always gives the
+// same tree whatever the leaves. A given sweep tree root replaces the random
+// one, which is still read for the same reason.
+func Generate(opts GenerateOptions) (*Generation, error) {
+	var timings []PhaseTiming
+
+	phase := startPhase(opts.MeasureAllocs)
+	rnd := opts.Rand
+	if rnd == nil {
+		rnd = RandomSource(0, false)
+		if opts.Seed != nil {
+			rnd = RandomSource(*opts.Seed, true)
+		}
+	}
+
+	if opts.SweepTreeRoot != nil && len(opts.SweepTreeRoot) != SweepTreeRootSize {
+		return nil, fmt.Errorf("sweep tree root must be %d bytes, got %d", SweepTreeRootSize, len(opts.SweepTreeRoot))
+	}
+
+	sweepTreeRoot := make([]byte, SweepTreeRootSize)
+	if _, err := io.ReadFull(rnd, sweepTreeRoot); err != nil {
+		return nil, fmt.Errorf("failed to generate sweep tree root: %w", err)
+	}
+	if opts.SweepTreeRoot != nil {
+		sweepTreeRoot = opts.SweepTreeRoot
+	}
+
+	randomTxid := make([]byte, 32)
+	if _, err := io.ReadFull(rnd, randomTxid); err != nil {
+		return nil, fmt.Errorf("failed to generate root txid: %w", err)
+	}
+	timings = append(timings, phase.end("Random data init"))
+
+	leaves := opts.Leaves
+	var cosignerKeys []*secp256k1.PrivateKey
+	if leaves == nil {
+		amount := opts.Amount
+		if amount == 0 {
+			amount = DefaultLeafAmount
+		}
+
+		groups := opts.CosignerGroups
+		if opts.SharedCosigner {
+			if groups > 1 {
+				return nil, fmt.Errorf("a shared cosigner can't be split in %d groups", groups)
+			}
+			groups = 1
+		}
+
+		phase = startPhase(opts.MeasureAllocs)
+		var err error
+		leaves, cosignerKeys, err = generateLeaves(opts.NumLeaves, amount, groups, opts.RawScripts, rnd, opts.CosignerSeed)
+		if err != nil {
+			return nil, err
+		}
+		if opts.AmountSamples != nil {
+			if err := drawAmounts(leaves, opts.AmountSamples, rnd); err != nil {
+				return nil, err
+			}
+		}
+		timings = append(timings, phase.end("Leaf generation"))
+	}
+
+	if opts.InputAmount > 0 {
+		total, err := LeavesTotal(leaves)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := FundingHeadroom(opts.InputAmount, total, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	locktime := DefaultLocktime
+	if opts.Locktime != nil {
+		locktime = *opts.Locktime
+	}
+
+	phase = startPhase(opts.MeasureAllocs)
+	txtree, err := BuildTree(leaves, sweepTreeRoot, randomTxid, locktime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tree: %w", err)
+	}
+	timings = append(timings, phase.end("BuildVtxoTree"))
+
+	if err := checkTxFields(txtree, opts.TxVersion, opts.TxLocktime); err != nil {
+		return nil, err
+	}
+	if err := ApplyPerTxFee(txtree, opts.PerTxFee); err != nil {
+		return nil, err
+	}
+
+	return &Generation{Tree: txtree, Leaves: leaves, CosignerKeys: cosignerKeys, SweepTreeRoot: sweepTreeRoot, Timings: timings}, nil
+}
+
+// GenerateAndAnalyze builds the tree described by opts and computes its
+// statistics, the Report timings covering both phases
+func GenerateAndAnalyze(opts GenerateOptions) (*Report, error) {
+	generation, err := Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := Analyze(generation.Tree, opts.AnalyzeOptions)
+	if err != nil {
+		return nil, err
+	}
+	report.Timings = append(generation.Timings, report.Timings...)
+
+	return report, nil
+}
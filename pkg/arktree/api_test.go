@@ -0,0 +1,138 @@
+package arktree
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestGenerateIsReproducible(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, seed int64, stats *Report) {
+		want := stats.Tree.Root.UnsignedTx.TxID()
+		for _, opts := range []GenerateOptions{
+			{NumLeaves: stats.NumLeaves, Seed: &seed},
+			{NumLeaves: stats.NumLeaves, Rand: RandomSource(seed, true)},
+		} {
+			again, err := Generate(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := again.Tree.Root.UnsignedTx.TxID(); got != want {
+				t.Errorf("root txid %s, expected %s", got, want)
+			}
+		}
+	})
+}
+
+func TestGenerateFailingRandomSource(t *testing.T) {
+	failing := iotest.ErrReader(errors.New("no entropy"))
+	if _, err := Generate(GenerateOptions{NumLeaves: 2, Rand: failing}); err == nil {
+		t.Error("a failing random source is not reported")
+	}
+}
+
+func TestGenerateEdgeCaseLeaves(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 1, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := generation.Leaves[0]
+
+	for _, test := range []struct {
+		name    string
+		leaves  []tree.Leaf
+		wantErr bool
+	}{
+		{"no leaves", []tree.Leaf{}, true},
+		{"one leaf", []tree.Leaf{leaf}, false},
+		{"same leaf twice", []tree.Leaf{leaf, leaf}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("panic: %v", r)
+				}
+			}()
+			generation, err := Generate(GenerateOptions{Leaves: test.leaves})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, expected one: %t", err, test.wantErr)
+			}
+			if err == nil && (generation.Tree == nil || generation.Tree.Root == nil) {
+				t.Error("no tree and no error")
+			}
+		})
+	}
+}
+
+func TestGenerateSweepTreeRoot(t *testing.T) {
+	seed := int64(5)
+	root := bytes.Repeat([]byte{0xab}, SweepTreeRootSize)
+	random, err := Generate(GenerateOptions{NumLeaves: 4, RawScripts: true, Seed: &seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	given, err := Generate(GenerateOptions{NumLeaves: 4, RawScripts: true, Seed: &seed, SweepTreeRoot: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSweepTreeRoot(given.Tree, root); err != nil {
+		t.Error(err)
+	}
+	if given.Tree.Root.UnsignedTx.TxIn[0].PreviousOutPoint != random.Tree.Root.UnsignedTx.TxIn[0].PreviousOutPoint {
+		t.Error("the given sweep root changed the root input")
+	}
+	if _, err := Generate(GenerateOptions{NumLeaves: 4, RawScripts: true, SweepTreeRoot: root[:31]}); err == nil {
+		t.Error("31 byte sweep root accepted")
+	}
+}
+
+func TestGenerateAmountSamples(t *testing.T) {
+	seed := int64(3)
+	samples := []uint64{330, 5000, 100000}
+	opts := GenerateOptions{NumLeaves: 16, RawScripts: true, Seed: &seed, AmountSamples: samples}
+	drawn, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.AmountSamples = nil
+	fixed, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, leaf := range drawn.Leaves {
+		if !slices.Contains(samples, leaf.Amount) {
+			t.Errorf("leaf %d: amount %d isn't a sample", i, leaf.Amount)
+		}
+		if leaf.Amount != again.Leaves[i].Amount {
+			t.Errorf("leaf %d: amount %d then %d with the same seed", i, leaf.Amount, again.Leaves[i].Amount)
+		}
+		if leaf.Script != fixed.Leaves[i].Script {
+			t.Errorf("leaf %d: the samples changed the script", i)
+		}
+	}
+}
+
+func TestSerializationRoundTrip(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		chunks, err := stats.Tree.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := tree.NewTxGraph(chunks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := LeafTxids(decoded), LeafTxids(stats.Tree); !slices.Equal(got, want) {
+			t.Errorf("leaf txids %v after the round trip, expected %v", got, want)
+		}
+	})
+}
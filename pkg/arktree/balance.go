@@ -0,0 +1,144 @@
+package arktree
+
+import (
+	"fmt"
+	"math"
+)
+
+// BalanceScore rates how balanced a tree is from its branch sizes, between 0
+// and 1:
+//
+//	balance = 1 - (stddev(branch sizes) - best) / (worst - best)
+//
+// where best is the stddev of the branch sizes of the most balanced binary
+// tree with the same number of leaves, all its leaves at two adjacent depths,
+// and worst the one of the chain, the most unbalanced tree, each internal node
+// having a leaf as one of its two children, so its branch sizes are 2, 3, ...,
+// N, N. The most balanced tree scores 1, the chain scores 0. Stddevs are
+// population stddevs.
+func BalanceScore(branchSizes []int) float64 {
+	best, worst := balancedStddev(len(branchSizes)), chainStddev(len(branchSizes))
+	if worst <= best {
+		return 1
+	}
+
+	sizes := make([]float64, 0, len(branchSizes))
+	for _, size := range branchSizes {
+		sizes = append(sizes, float64(size))
+	}
+
+	score := 1 - (CalculateStddevFloat(sizes)-best)/(worst-best)
+	return math.Min(1, math.Max(0, score))
+}
+
+// balancedStddev returns the stddev of the branch sizes of the most balanced
+// binary tree of numLeaves leaves: with 2^d <= N < 2^(d+1) and e = N - 2^d,
+// 2^d - e leaves at depth d and 2e at depth d+1, so that its branch sizes are
+// d+1 and d+2
+func balancedStddev(numLeaves int) float64 {
+	if numLeaves < 2 {
+		return 0
+	}
+
+	width, depth := 1, 0
+	for width*2 <= numLeaves {
+		width *= 2
+		depth++
+	}
+	extra := numLeaves - width
+
+	sizes := make([]float64, 0, numLeaves)
+	for range width - extra {
+		sizes = append(sizes, float64(depth+1))
+	}
+	for range 2 * extra {
+		sizes = append(sizes, float64(depth+2))
+	}
+
+	return CalculateStddevFloat(sizes)
+}
+
+// chainStddev returns the stddev of the branch sizes of a chain of numLeaves leaves
+func chainStddev(numLeaves int) float64 {
+	if numLeaves < 2 {
+		return 0
+	}
+
+	sizes := make([]float64, 0, numLeaves)
+	for size := 2; size <= numLeaves; size++ {
+		sizes = append(sizes, float64(size))
+	}
+	sizes = append(sizes, float64(numLeaves))
+
+	return CalculateStddevFloat(sizes)
+}
+
+// BranchSizeBounds returns the smallest and largest possible biggest branch
+// of a tree of numLeaves leaves: ceil(log2 N) + 1 for the most balanced binary
+// tree, N for the chain (see BalanceScore)
+func BranchSizeBounds(numLeaves int) (best, worst int) {
+	if numLeaves < 1 {
+		return 0, 0
+	}
+
+	best = 1
+	for width := 1; width < numLeaves; width *= 2 {
+		best++
+	}
+	return best, max(best, numLeaves)
+}
+
+// MaxTargetDepth is the deepest depth MaxLeavesForDepth accepts, 2^30 leaves
+const MaxTargetDepth = 31
+
+// MaxLeavesForDepth returns the number of leaves of the largest tree of at
+// most depth levels. BuildVtxoTree builds balanced binary trees whose depth is
+// ceil(log2 N) + 1 (see BranchSizeBounds), so it is 2^(depth-1).
+func MaxLeavesForDepth(depth int) (int, error) {
+	if depth < 1 || depth > MaxTargetDepth {
+		return 0, fmt.Errorf("target depth must be between 1 and %d, got %d", MaxTargetDepth, depth)
+	}
+	return 1 << (depth - 1), nil
+}
+
+// WorstCaseCloseness locates the biggest branch of a tree of numLeaves leaves
+// between the best (0) and worst (1) possible ones
+func WorstCaseCloseness(biggestBranch, numLeaves int) float64 {
+	best, worst := BranchSizeBounds(numLeaves)
+	if worst == best {
+		return 0
+	}
+	return float64(biggestBranch-best) / float64(worst-best)
+}
+
+// Amortization is the number of nodes of a tree divided by the sum of its
+// branch sizes, i.e. the transactions broadcast by a cooperative exit over
+// those broadcast when every user exits alone. It is 1 for a single leaf and
+// shrinks as branches share more nodes.
+func Amortization(totalSize int, branchSizes []int) float64 {
+	sum := 0
+	for _, size := range branchSizes {
+		sum += size
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float64(totalSize) / float64(sum)
+}
+
+// BroadcastSharing compares the total broadcast weight of the branches, which
+// splits each node among its cosigners, with the total of a naive model where
+// every leaf broadcasts its whole branch alone. ratio = shared / naive, the
+// lower the more cosigner sharing saves.
+func BroadcastSharing(branchSizes []int, branchWeights []float64) (shared, naive, ratio float64) {
+	for _, weight := range branchWeights {
+		shared += weight
+	}
+	for _, size := range branchSizes {
+		naive += float64(size)
+	}
+	if naive == 0 {
+		return shared, naive, 0
+	}
+	return shared, naive, shared / naive
+}
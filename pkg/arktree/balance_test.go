@@ -0,0 +1,55 @@
+package arktree
+
+import "testing"
+
+func TestBranchSizeBounds(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		best, worst := BranchSizeBounds(stats.NumLeaves)
+		if biggest := stats.BiggestBranch(); biggest != best {
+			t.Errorf("biggest branch %d, best possible %d", biggest, best)
+		}
+		if worst != stats.NumLeaves && stats.NumLeaves > 1 {
+			t.Errorf("theoretical max %d for %d leaves", worst, stats.NumLeaves)
+		}
+	})
+}
+
+func TestMaxLeavesForDepth(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		depth := TreeDepth(stats.Tree)
+		if fit, err := MaxLeavesForDepth(depth); err != nil || fit < stats.NumLeaves {
+			t.Errorf("depth %d fits %d leaves (%v), built with %d", depth, fit, err, stats.NumLeaves)
+		}
+		if depth > 1 {
+			if fit, _ := MaxLeavesForDepth(depth - 1); fit >= stats.NumLeaves {
+				t.Errorf("depth %d fits %d leaves, built with %d at depth %d", depth-1, fit, stats.NumLeaves, depth)
+			}
+		}
+	})
+}
+
+func TestBalanceScore(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		branchSizes []int
+		want        float64
+	}{
+		{"single leaf", []int{1}, 1},
+		{"perfect tree of 8 leaves", []int{4, 4, 4, 4, 4, 4, 4, 4}, 1},
+		{"most balanced tree of 5 leaves", []int{3, 3, 3, 4, 4}, 1},
+		{"most balanced tree of 6 leaves", []int{3, 3, 4, 4, 4, 4}, 1},
+		{"most balanced tree of 12 leaves", []int{4, 4, 4, 4, 5, 5, 5, 5, 5, 5, 5, 5}, 1},
+		{"chain of 3 leaves, also the most balanced", []int{2, 3, 3}, 1},
+		{"chain of 5 leaves", []int{2, 3, 4, 5, 5}, 0},
+		{"chain of 8 leaves", []int{2, 3, 4, 5, 6, 7, 8, 8}, 0},
+	} {
+		if score := BalanceScore(test.branchSizes); !floatsClose(score, test.want) {
+			t.Errorf("%s: balance %g, expected %g", test.name, score, test.want)
+		}
+	}
+
+	// a tree of 5 leaves with a leaf at depth 1 is between the two
+	if score := BalanceScore([]int{2, 4, 4, 4, 4}); score <= 0 || score >= 1 {
+		t.Errorf("balance %g of 5 leaves with a leaf at depth 1, expected it within (0, 1)", score)
+	}
+}
@@ -0,0 +1,76 @@
+package arktree
+
+import (
+	"github.com/ark-network/ark/common/tree"
+)
+
+// LevelChurn is how the cosigner sets of the parents of a level grow from
+// their children's, level 1 being the root
+type LevelChurn struct {
+	Level   int `json:"level"`
+	Parents int `json:"parents"`
+	// KeysGained sums, over the parents, the keys they have on top of their largest child
+	KeysGained int `json:"keys_gained"`
+	// NewKeys sums, over the parents, the keys that none of their children have
+	NewKeys int `json:"new_keys"`
+}
+
+// KeyChurn returns the cosigner key churn between each level of internal
+// nodes and their children
+func KeyChurn(g *tree.TxGraph) ([]LevelChurn, error) {
+	var levels []LevelChurn
+
+	if err := Walk(g, func(node, _ *tree.TxGraph, level int) error {
+		if len(node.Children) == 0 {
+			return nil
+		}
+
+		keys, err := CosignerKeySet(node)
+		if err != nil {
+			return err
+		}
+
+		childrenKeys := make(map[string]struct{})
+		largestChild := 0
+		for _, child := range node.Children {
+			childKeys, err := CosignerKeySet(child)
+			if err != nil {
+				return err
+			}
+			largestChild = max(largestChild, len(childKeys))
+			for key := range childKeys {
+				childrenKeys[key] = struct{}{}
+			}
+		}
+
+		newKeys := 0
+		for key := range keys {
+			if _, ok := childrenKeys[key]; !ok {
+				newKeys++
+			}
+		}
+
+		for len(levels) < level {
+			levels = append(levels, LevelChurn{Level: len(levels) + 1})
+		}
+		levels[level-1].Parents++
+		levels[level-1].KeysGained += len(keys) - largestChild
+		levels[level-1].NewKeys += newKeys
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// TotalChurn sums the churn of all levels
+func TotalChurn(levels []LevelChurn) LevelChurn {
+	var total LevelChurn
+	for _, level := range levels {
+		total.Parents += level.Parents
+		total.KeysGained += level.KeysGained
+		total.NewKeys += level.NewKeys
+	}
+	return total
+}
@@ -0,0 +1,142 @@
+package arktree
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// ValueDiscrepancy is a tx of a tree whose outputs and fee don't add up to
+// the parent output it spends
+type ValueDiscrepancy struct {
+	Txid    string
+	Input   int64 // sats of the spent parent output
+	Outputs int64 // sats of all the outputs, anchor included
+	Fee     int64 // sats the tx was expected to pay, see ApplyPerTxFee
+}
+
+// Discrepancy is the value lost (positive) or created (negative) by the tx
+// on top of its fee, in sats. Tree txs pay their fees through their anchor
+// unless a per-tx fee is modelled, so it should be 0.
+func (d ValueDiscrepancy) Discrepancy() int64 {
+	return d.Input - d.Outputs - d.Fee
+}
+
+func (d ValueDiscrepancy) String() string {
+	if d.Fee != 0 {
+		return fmt.Sprintf("tx %s spends %d sats but its outputs sum to %d with a fee of %d (discrepancy %d sats)",
+			d.Txid, d.Input, d.Outputs, d.Fee, d.Discrepancy())
+	}
+	return fmt.Sprintf("tx %s spends %d sats but its outputs sum to %d (discrepancy %d sats)",
+		d.Txid, d.Input, d.Outputs, d.Discrepancy())
+}
+
+// ValueConservation walks g bottom-up and returns the txs that don't conserve
+// the value of the parent output they spend, deepest first and siblings by
+// output index. The root is skipped since the value of the output it spends
+// isn't part of the tree.
+func ValueConservation(g *tree.TxGraph) ([]ValueDiscrepancy, error) {
+	return ValueConservationWithFee(g, 0)
+}
+
+// ValueConservationWithFee is ValueConservation for a tree whose txs each pay
+// fee sats out of the value they spend, see ApplyPerTxFee
+func ValueConservationWithFee(g *tree.TxGraph, fee int64) ([]ValueDiscrepancy, error) {
+	var discrepancies []ValueDiscrepancy
+
+	var walk func(node *tree.TxGraph) error
+	walk = func(node *tree.TxGraph) error {
+		indexes := make([]uint32, 0, len(node.Children))
+		for index := range node.Children {
+			indexes = append(indexes, index)
+		}
+		slices.Sort(indexes)
+
+		for _, index := range indexes {
+			child := node.Children[index]
+			if err := walk(child); err != nil {
+				return err
+			}
+
+			if int(index) >= len(node.Root.UnsignedTx.TxOut) {
+				return fmt.Errorf("tx %s spends output %d of %s, which has %d outputs",
+					child.Root.UnsignedTx.TxID(), index, node.Root.UnsignedTx.TxID(), len(node.Root.UnsignedTx.TxOut))
+			}
+
+			var outputs int64
+			for _, out := range child.Root.UnsignedTx.TxOut {
+				outputs += out.Value
+			}
+
+			input := node.Root.UnsignedTx.TxOut[index].Value
+			if input != outputs+fee {
+				discrepancies = append(discrepancies, ValueDiscrepancy{
+					Txid:    child.Root.UnsignedTx.TxID(),
+					Input:   input,
+					Outputs: outputs,
+					Fee:     fee,
+				})
+			}
+		}
+		return nil
+	}
+
+	if err := walk(g); err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+// ApplyPerTxFee makes every tx of g pay fee sats out of the value it spends,
+// as real trees do instead of relying on their anchors alone: each output
+// spent by a child is raised by the fee of the child on top of what the
+// child sends on, so that the leaves keep their value and the fees of the
+// whole tree are locked in the output the root spends, fee times the number
+// of txs, the root paying its own out of the batch output. The txids change
+// with the outputs, so the children are relinked to their new parent txid
+// from the root down. g must be unsigned.
+func ApplyPerTxFee(g *tree.TxGraph, fee int64) error {
+	if fee < 0 {
+		return fmt.Errorf("per-tx fee must not be negative, got %d", fee)
+	}
+	if fee == 0 {
+		return nil
+	}
+
+	// the value each node needs in its input, its outputs raised first
+	var raise func(node *tree.TxGraph) (int64, error)
+	raise = func(node *tree.TxGraph) (int64, error) {
+		outputs := node.Root.UnsignedTx.TxOut
+		for index, child := range node.Children {
+			if int(index) >= len(outputs) {
+				return 0, fmt.Errorf("tx %s spends output %d of %s, which has %d outputs",
+					child.Root.UnsignedTx.TxID(), index, node.Root.UnsignedTx.TxID(), len(outputs))
+			}
+			needed, err := raise(child)
+			if err != nil {
+				return 0, err
+			}
+			outputs[index].Value = needed
+		}
+		values := make([]int64, 0, len(outputs)+1)
+		for _, out := range outputs {
+			values = append(values, out.Value)
+		}
+		return SumValues(append(values, fee))
+	}
+	if _, err := raise(g); err != nil {
+		return err
+	}
+
+	var relink func(node *tree.TxGraph)
+	relink = func(node *tree.TxGraph) {
+		hash := node.Root.UnsignedTx.TxHash()
+		for _, child := range node.Children {
+			child.Root.UnsignedTx.TxIn[0].PreviousOutPoint.Hash = hash
+			relink(child)
+		}
+	}
+	relink(g)
+	return nil
+}
@@ -0,0 +1,88 @@
+package arktree
+
+import (
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestValueConservation(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		discrepancies, err := ValueConservation(stats.Tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(discrepancies) > 0 {
+			t.Errorf("%d tx(s) don't conserve value, first %s", len(discrepancies), discrepancies[0])
+		}
+	})
+}
+
+func TestValueConservationTamperedOutput(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 4, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := generation.Tree.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := tree.NewTxGraph(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := g.Children[0]
+	child.Root.UnsignedTx.TxOut[0].Value += 1000
+
+	discrepancies, err := ValueConservation(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the tampered tx creates 1000 sats, which its children then lose
+	txid := child.Root.UnsignedTx.TxID()
+	for _, discrepancy := range discrepancies {
+		if discrepancy.Txid == txid && discrepancy.Discrepancy() == -1000 {
+			return
+		}
+	}
+	t.Errorf("expected a discrepancy of -1000 sats for %s, got %v", txid, discrepancies)
+}
+
+func TestPerTxFee(t *testing.T) {
+	seed := int64(1)
+	opts := GenerateOptions{NumLeaves: 5, Amount: 1000, RawScripts: true, Seed: &seed}
+	opts.PerTxFee = 100
+	generation, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := generation.Tree
+	if discrepancies, err := ValueConservationWithFee(g, 100); err != nil || len(discrepancies) > 0 {
+		t.Errorf("with the fee: %v, %v", discrepancies, err)
+	}
+	if discrepancies, _ := ValueConservation(g); len(discrepancies) != 8 {
+		t.Errorf("%d txs don't conserve value without the fee, expected every tx but the root", len(discrepancies))
+	}
+
+	stats, err := Analyze(g, opts.AnalyzeOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rootOutputs int64
+	for _, out := range g.Root.UnsignedTx.TxOut {
+		rootOutputs += out.Value
+	}
+	if rootOutputs+100 != stats.TotalValue+stats.TreeFees() || stats.TreeFees() != 900 {
+		t.Errorf("root outputs %d, leaves %d, tree fees %d", rootOutputs, stats.TotalValue, stats.TreeFees())
+	}
+	for _, cost := range stats.ExitCosts {
+		if cost.Fee != cost.FeeAt(stats.Feerate) || cost.Prepaid == 0 {
+			t.Errorf("exit %+v doesn't count its prepaid fees", cost)
+		}
+	}
+
+	opts.PerTxFee = 99
+	if _, err := Analyze(g, opts.AnalyzeOptions); err == nil {
+		t.Error("a per-tx fee of 99 sats accepted on a tree paying 100")
+	}
+}
@@ -0,0 +1,133 @@
+package arktree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"sort"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// PartialExit is the number of txs broadcast when each user exits
+// unilaterally with probability Fraction and the others cooperate, estimated
+// over Samples random draws of the exiting users
+type PartialExit struct {
+	Fraction float64
+	Samples  int
+	Mean     float64
+	StdDev   float64
+	Min, Max int
+	Nodes    int // txs of the tree, broadcast when every user exits
+}
+
+// SimulatePartialExit draws samples times which leaves exit, each with
+// probability fraction, and counts the txs their exits broadcast: the union of
+// their branches, as an ancestor shared by several exiting leaves is only
+// broadcast once.
+func SimulatePartialExit(g *tree.TxGraph, fraction float64, samples int, rnd *mathrand.Rand) (PartialExit, error) {
+	if fraction < 0 || fraction > 1 {
+		return PartialExit{}, fmt.Errorf("exit fraction must be within [0, 1], got %g", fraction)
+	}
+	if samples < 1 {
+		return PartialExit{}, fmt.Errorf("number of samples must be positive, got %d", samples)
+	}
+
+	// parents[i] is the index of the parent of the i-th node, -1 for the root
+	var parents []int
+	var leaves []int
+	indexes := make(map[*tree.TxGraph]int)
+	if err := Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		indexes[node] = len(parents)
+		if parent == nil {
+			parents = append(parents, -1)
+		} else {
+			parents = append(parents, indexes[parent])
+		}
+		if len(node.Children) == 0 {
+			leaves = append(leaves, indexes[node])
+		}
+		return nil
+	}); err != nil {
+		return PartialExit{}, err
+	}
+
+	exit := PartialExit{Fraction: fraction, Samples: samples, Min: len(parents), Nodes: len(parents)}
+	// broadcast[i] is the last sample broadcasting the i-th node, so that it
+	// needs no reset between samples
+	broadcast := make([]int, len(parents))
+	var sum, sumSquares float64
+	for sample := 1; sample <= samples; sample++ {
+		count := 0
+		for _, leaf := range leaves {
+			if rnd.Float64() >= fraction {
+				continue
+			}
+			for node := leaf; node >= 0 && broadcast[node] != sample; node = parents[node] {
+				broadcast[node] = sample
+				count++
+			}
+		}
+
+		sum += float64(count)
+		sumSquares += float64(count) * float64(count)
+		exit.Min = min(exit.Min, count)
+		exit.Max = max(exit.Max, count)
+	}
+
+	exit.Mean = sum / float64(samples)
+	exit.StdDev = math.Sqrt(max(0, sumSquares/float64(samples)-exit.Mean*exit.Mean))
+	return exit, nil
+}
+
+// LeafValues returns the value of every leaf of g, ordered by leaf txid as
+// LeafTxids, see LeafValue
+func LeafValues(g *tree.TxGraph) []int64 {
+	leaves := g.Leaves()
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].UnsignedTx.TxID() < leaves[j].UnsignedTx.TxID()
+	})
+
+	values := make([]int64, 0, len(leaves))
+	for _, leaf := range leaves {
+		values = append(values, LeafValue(leaf.UnsignedTx))
+	}
+	return values
+}
+
+// ExpectedExitSize returns the expected number of txs a random user
+// broadcasts to exit alone, the others cooperating: the mean of the branch
+// sizes, every leaf drawn with the same probability. With values, the value
+// of each leaf in the order of sizes, a leaf is drawn in proportion to its
+// value instead, as a random sat would be, which differs from the mean as
+// soon as the values differ between branches of different sizes.
+func ExpectedExitSize(sizes []int, values []int64) (float64, error) {
+	if len(sizes) == 0 {
+		return 0, errors.New("no branch")
+	}
+	if values == nil {
+		total := 0
+		for _, size := range sizes {
+			total += size
+		}
+		return float64(total) / float64(len(sizes)), nil
+	}
+
+	if len(values) != len(sizes) {
+		return 0, fmt.Errorf("%d values for %d branches", len(values), len(sizes))
+	}
+	// summed as floats, the products of sizes and values may overflow int64
+	var weighted, total float64
+	for i, value := range values {
+		if value < 0 {
+			return 0, fmt.Errorf("negative value %d", value)
+		}
+		weighted += float64(sizes[i]) * float64(value)
+		total += float64(value)
+	}
+	if total == 0 {
+		return 0, errors.New("the leaves hold no value")
+	}
+	return weighted / total, nil
+}
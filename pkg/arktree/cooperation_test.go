@@ -0,0 +1,61 @@
+package arktree
+
+import (
+	"math"
+	mathrand "math/rand/v2"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestSimulatePartialExit(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		all, err := SimulatePartialExit(stats.Tree, 1, 10, mathrand.New(mathrand.NewPCG(1, 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if all.Min != stats.TotalSize || all.Max != stats.TotalSize {
+			t.Errorf("%d-%d txs when every user exits, %d in the tree", all.Min, all.Max, stats.TotalSize)
+		}
+
+		// a node is broadcast unless none of the leaves below it exits
+		const fraction = 0.3
+		var expected float64
+		if err := Walk(stats.Tree, func(node, _ *tree.TxGraph, _ int) error {
+			expected += 1 - math.Pow(1-fraction, float64(len(node.Leaves())))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		exit, err := SimulatePartialExit(stats.Tree, fraction, 2000, mathrand.New(mathrand.NewPCG(1, 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(exit.Mean-expected) > 4*exit.StdDev/math.Sqrt(float64(exit.Samples)) {
+			t.Errorf("%.2f txs on average, %.2f expected", exit.Mean, expected)
+		}
+	})
+}
+
+func TestExpectedExitSize(t *testing.T) {
+	stats := analyzeSeeded(t, GenerateOptions{NumLeaves: 7, RawScripts: true}, 1)
+	mean, err := ExpectedExitSize(stats.BranchSizes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := CalculateAverage(stats.BranchSizes); mean != want {
+		t.Errorf("%g per leaf, mean branch size %g", mean, want)
+	}
+	byAmount, err := ExpectedExitSize(stats.BranchSizes, LeafValues(stats.Tree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every generated leaf holds the same amount
+	if !floatsClose(byAmount, mean) {
+		t.Errorf("%g by amount with equal amounts, expected %g", byAmount, mean)
+	}
+
+	if weighted, err := ExpectedExitSize([]int{2, 4}, []int64{3, 1}); err != nil || weighted != 2.5 {
+		t.Errorf("sizes 2 and 4 worth 3 and 1 give %g (%v), expected 2.5", weighted, err)
+	}
+}
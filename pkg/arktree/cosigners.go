@@ -0,0 +1,134 @@
+package arktree
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// VerifyCosignerSets checks how the builder propagates cosigner keys upward:
+// a leaf tx is cosigned by the keys of its tree.Leaf, and every internal node is
+// cosigned by the deduplicated union of its children's cosigners. The root is
+// therefore cosigned by every distinct key of the tree.
+func VerifyCosignerSets(g *tree.TxGraph) error {
+	return g.Apply(func(node *tree.TxGraph) (bool, error) {
+		if len(node.Children) == 0 {
+			return true, nil
+		}
+
+		keys, err := CosignerKeySet(node)
+		if err != nil {
+			return false, err
+		}
+
+		childrenKeys := make(map[string]struct{})
+		for _, child := range node.Children {
+			childKeys, err := CosignerKeySet(child)
+			if err != nil {
+				return false, err
+			}
+			for key := range childKeys {
+				childrenKeys[key] = struct{}{}
+			}
+		}
+
+		txid := node.Root.UnsignedTx.TxID()
+		if len(keys) != len(childrenKeys) {
+			return false, fmt.Errorf(
+				"node %s has %d cosigners, expected the %d of its children", txid, len(keys), len(childrenKeys),
+			)
+		}
+		for key := range childrenKeys {
+			if _, ok := keys[key]; !ok {
+				return false, fmt.Errorf("node %s is missing cosigner %s of its children", txid, key)
+			}
+		}
+
+		return true, nil
+	})
+}
+
+// CosignerCount summarizes the number of cosigners of the nodes of a tree,
+// which the broadcast weights are split by
+type CosignerCount struct {
+	Min  int     `json:"min"`
+	Mean float64 `json:"mean"`
+	Max  int     `json:"max"`
+	// NUMSNodes is the number of nodes cosigned by the NUMS point, see IsNUMSKey
+	NUMSNodes int `json:"nums_nodes"`
+}
+
+// CosignersPerNode returns the min, mean and max number of cosigners of the
+// nodes of g, and how many of them the NUMS point cosigns
+func CosignersPerNode(g *tree.TxGraph) (CosignerCount, error) {
+	_, count, err := branchPaths(context.Background(), g, nil, false)
+	return count, err
+}
+
+// numsKey is the x coordinate of the NUMS point
+var numsKey = schnorr.SerializePubKey(tree.UnspendableKey())
+
+// IsNUMSKey reports whether key is the NUMS point of BIP341, the unspendable
+// key of tree.UnspendableKey: no one knows its private key, so a tx cosigned
+// by it has a key path no one can spend, and it is no signer. Both parities of
+// the point match.
+func IsNUMSKey(key *secp256k1.PublicKey) bool {
+	return bytes.Equal(schnorr.SerializePubKey(key), numsKey)
+}
+
+// signerCount returns the number of cosigners in keys sharing the broadcast
+// weight of their tx, leaving out the NUMS point with excludeNUMS, and whether
+// the NUMS point is one of them. A tx cosigned by the NUMS point alone counts
+// it as its signer.
+func signerCount(keys []*secp256k1.PublicKey, excludeNUMS bool) (int, bool) {
+	nums := 0
+	for _, key := range keys {
+		if IsNUMSKey(key) {
+			nums++
+		}
+	}
+	if !excludeNUMS || nums == len(keys) {
+		return len(keys), nums > 0
+	}
+	return len(keys) - nums, nums > 0
+}
+
+// NodesBelowMinCosigners returns the sorted txids of the nodes having less than min cosigners
+func NodesBelowMinCosigners(g *tree.TxGraph, min int) ([]string, error) {
+	offending := make([]string, 0)
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return false, err
+		}
+		if len(keys) < min {
+			offending = append(offending, node.Root.UnsignedTx.TxID())
+		}
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(offending)
+	return offending, nil
+}
+
+// CosignerKeySet returns the set of hex encoded cosigner keys of the node
+func CosignerKeySet(node *tree.TxGraph) (map[string]struct{}, error) {
+	keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[hex.EncodeToString(key.SerializeCompressed())] = struct{}{}
+	}
+	return set, nil
+}
@@ -0,0 +1,77 @@
+package arktree
+
+import (
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestCosignersPerNode(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		keys, err := CosignerKeySet(stats.Tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := stats.CosignersPerNode
+		if count.Min != 1 || count.Max != len(keys) || count.Mean < 1 || count.Mean > float64(len(keys)) {
+			t.Errorf("min %d, mean %g, max %d, expected 1 and the %d keys of the root", count.Min, count.Mean, count.Max, len(keys))
+		}
+	})
+}
+
+func TestExcludeNUMS(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 4, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nums := hex.EncodeToString(tree.UnspendableKey().SerializeCompressed())
+	withNUMS := make([]tree.Leaf, 0, len(generation.Leaves))
+	for _, leaf := range generation.Leaves {
+		leaf.CosignersPublicKeys = append(slices.Clone(leaf.CosignersPublicKeys), nums)
+		withNUMS = append(withNUMS, leaf)
+	}
+	numsGeneration, err := Generate(GenerateOptions{Leaves: withNUMS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Analyze(generation.Tree, AnalyzeOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	numsStats, err := Analyze(numsGeneration.Tree, AnalyzeOptions{Workers: 1, ExcludeNUMS: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CosignersPerNode.NUMSNodes != 0 || numsStats.CosignersPerNode.NUMSNodes != numsStats.TotalSize {
+		t.Errorf("%d and %d nodes cosigned by the NUMS point, expected 0 and %d",
+			stats.CosignersPerNode.NUMSNodes, numsStats.CosignersPerNode.NUMSNodes, numsStats.TotalSize)
+	}
+	// the leaves are in the same order so the trees have the same shape,
+	// but not the same txids ordering the branches
+	weights, numsWeights := slices.Sorted(slices.Values(stats.BranchWeights)), slices.Sorted(slices.Values(numsStats.BranchWeights))
+	if !slices.Equal(weights, numsWeights) {
+		t.Errorf("weights %v without the NUMS point, expected %v", numsWeights, weights)
+	}
+
+	// the weights by level leave the NUMS point out alike
+	for i, leaf := range numsStats.LeafTxids {
+		branch, err := numsGeneration.Tree.SubGraph([]string{leaf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		levels, err := BroadcastWeightByLevel(branch, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum float64
+		for _, level := range levels {
+			sum += level
+		}
+		if !floatsClose(sum, numsStats.BranchWeights[i]) {
+			t.Errorf("branch of %s has levels adding up to %.2f for weight %.2f", leaf, sum, numsStats.BranchWeights[i])
+		}
+	}
+}
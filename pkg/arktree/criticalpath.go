@@ -0,0 +1,47 @@
+package arktree
+
+import (
+	"github.com/ark-network/ark/common/tree"
+)
+
+// CriticalPath is the root-to-leaf chain of a tree with the biggest summed
+// vsize, the most bytes a single exit can broadcast. It isn't always the
+// deepest branch: a shallower one with bigger txs can weigh more.
+type CriticalPath struct {
+	Txids  []string // root first
+	Vsizes []int    // vsize of each tx of Txids
+	Vsize  int      // sum of Vsizes
+}
+
+// Fee returns the fee in sats of broadcasting the path at feerate (sat/vB)
+func (p CriticalPath) Fee(feerate float64) int64 {
+	return FeeForVsize(p.Vsize, feerate)
+}
+
+// FindCriticalPath returns the critical path of g, estimating vsizes with
+// witness. Ties are broken by the smallest leaf txid so that the same tree
+// always gives the same path.
+func FindCriticalPath(g *tree.TxGraph, witness WitnessModel) (CriticalPath, error) {
+	vsize, err := witness.Vsize(g.Root)
+	if err != nil {
+		return CriticalPath{}, err
+	}
+
+	var best CriticalPath
+	for _, child := range g.Children {
+		path, err := FindCriticalPath(child, witness)
+		if err != nil {
+			return CriticalPath{}, err
+		}
+		if best.Txids == nil || path.Vsize > best.Vsize ||
+			(path.Vsize == best.Vsize && path.Txids[len(path.Txids)-1] < best.Txids[len(best.Txids)-1]) {
+			best = path
+		}
+	}
+
+	return CriticalPath{
+		Txids:  append([]string{g.Root.UnsignedTx.TxID()}, best.Txids...),
+		Vsizes: append([]int{vsize}, best.Vsizes...),
+		Vsize:  vsize + best.Vsize,
+	}, nil
+}
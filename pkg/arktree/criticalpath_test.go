@@ -0,0 +1,19 @@
+package arktree
+
+import "testing"
+
+func TestFindCriticalPath(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		path, err := FindCriticalPath(stats.Tree, DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		heaviest := 0
+		for _, cost := range stats.ExitCosts {
+			heaviest = max(heaviest, cost.Vsize)
+		}
+		if path.Vsize != heaviest {
+			t.Errorf("critical path of %d vB, most expensive exit %d vB", path.Vsize, heaviest)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package arktree
+
+import (
+	"math"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// degenerateBranchingFactor is the average number of children per internal
+// node below which a tree is considered a linear chain
+const degenerateBranchingFactor = 1.1
+
+// BranchingFactor returns the average number of children of the internal
+// nodes of g, 2 for a binary tree and 1 for a linear chain
+func BranchingFactor(g *tree.TxGraph) (float64, error) {
+	internal, children := 0, 0
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		if len(node.Children) > 0 {
+			internal++
+			children += len(node.Children)
+		}
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if internal == 0 {
+		return 0, nil
+	}
+	return float64(children) / float64(internal), nil
+}
+
+// Degenerate reports whether the tree is (nearly) a linear chain of nodes with
+// a single child: its depth equals its node count or its branching factor is ~1.
+// Every exit of such a tree broadcasts the whole chain, which the averages
+// over its branches don't show.
+func (s *Report) Degenerate() bool {
+	if s.TotalSize < 2 {
+		return false
+	}
+	return s.Depth == s.TotalSize || s.BranchingFactor < degenerateBranchingFactor
+}
+
+// FanOut describes how the nodes of a tree spread their children
+type FanOut struct {
+	Internal    int // nodes with at least one child
+	Children    int // children of the internal nodes
+	MaxChildren int // children of the widest node
+	Leaves      int
+	Depth       int
+	// Arity is the number of internal nodes by number of children, which
+	// tells a tree mixing fan-outs from one with the same Average
+	Arity map[int]int
+}
+
+// ComputeFanOut gathers the fan-out of g in a single traversal
+func ComputeFanOut(g *tree.TxGraph) FanOut {
+	f := FanOut{Arity: make(map[int]int)}
+	// the callback never fails
+	_ = Walk(g, func(node, _ *tree.TxGraph, depth int) error {
+		f.Depth = max(f.Depth, depth)
+		if len(node.Children) == 0 {
+			f.Leaves++
+			return nil
+		}
+
+		f.Internal++
+		f.Children += len(node.Children)
+		f.MaxChildren = max(f.MaxChildren, len(node.Children))
+		f.Arity[len(node.Children)]++
+		return nil
+	})
+
+	return f
+}
+
+// Average returns the mean number of children per internal node, the same as
+// BranchingFactor
+func (f FanOut) Average() float64 {
+	if f.Internal == 0 {
+		return 0
+	}
+	return float64(f.Children) / float64(f.Internal)
+}
+
+// Capacity returns the number of leaves of a perfect tree with the same depth
+// and maximum fan-out
+func (f FanOut) Capacity() float64 {
+	if f.Internal == 0 {
+		return float64(f.Leaves)
+	}
+	return math.Pow(float64(f.MaxChildren), float64(f.Depth-1))
+}
+
+// FillFactor returns the leaves of the tree over its Capacity, 1 for a perfect
+// tree and close to 0 for a chain
+func (f FanOut) FillFactor() float64 {
+	capacity := f.Capacity()
+	if capacity == 0 {
+		return 0
+	}
+	return float64(f.Leaves) / capacity
+}
+
+// TreeShape classifies a tree from its number of nodes per level
+type TreeShape string
+
+const (
+	// ShapePerfect is a tree whose internal nodes all have the full fan-out,
+	// every leaf being at the same depth
+	ShapePerfect TreeShape = "perfect"
+	// ShapeComplete is a tree whose levels are all full but the last one
+	ShapeComplete TreeShape = "complete"
+	// ShapeIrregular is any other tree
+	ShapeIrregular TreeShape = "irregular"
+)
+
+// NodesPerLevel returns the number of nodes of each level of g, the root
+// level first
+func NodesPerLevel(g *tree.TxGraph) []int {
+	var levels []int
+	// the callback never fails
+	_ = Walk(g, func(_, _ *tree.TxGraph, level int) error {
+		if level > len(levels) {
+			levels = append(levels, 0)
+		}
+		levels[level-1]++
+		return nil
+	})
+	return levels
+}
+
+// LeavesUnder returns the number of leaves under each node of g, 1 for a
+// leaf
+func LeavesUnder(g *tree.TxGraph) map[*tree.TxGraph]int {
+	leaves := make(map[*tree.TxGraph]int)
+	var countLeaves func(node *tree.TxGraph) int
+	countLeaves = func(node *tree.TxGraph) int {
+		count := 0
+		for _, child := range node.Children {
+			count += countLeaves(child)
+		}
+		leaves[node] = max(count, 1)
+		return leaves[node]
+	}
+	countLeaves(g)
+	return leaves
+}
+
+// ClassifyShape returns the shape of a tree from its NodesPerLevel, a level
+// being full when it has fanOut times the nodes of the level above. A fan-out
+// below 2 is taken as 2, so that a chain is irregular rather than a perfect
+// unary tree. The order of the nodes in the last level isn't known from the
+// counts, so a complete tree isn't required to fill it from the left.
+func ClassifyShape(levels []int, fanOut int) TreeShape {
+	fanOut = max(fanOut, 2)
+
+	full := 1
+	for i, count := range levels {
+		if count != full {
+			if i == len(levels)-1 && count > 0 && count < full {
+				return ShapeComplete
+			}
+			return ShapeIrregular
+		}
+		full *= fanOut
+	}
+	return ShapePerfect
+}
@@ -0,0 +1,82 @@
+package arktree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClassifyShape(t *testing.T) {
+	for _, test := range []struct {
+		levels []int
+		fanOut int
+		shape  TreeShape
+	}{
+		{[]int{1}, 0, ShapePerfect},
+		{[]int{1, 2, 4}, 2, ShapePerfect},
+		{[]int{1, 3, 9}, 3, ShapePerfect},
+		{[]int{1, 2, 3}, 2, ShapeComplete},
+		{[]int{1, 2, 3, 1}, 2, ShapeIrregular},
+		{[]int{1, 1, 1}, 1, ShapeIrregular},
+	} {
+		if shape := ClassifyShape(test.levels, test.fanOut); shape != test.shape {
+			t.Errorf("levels %v with fan-out %d: %s, expected %s", test.levels, test.fanOut, shape, test.shape)
+		}
+	}
+}
+
+func TestClassifyShapeOfBuiltTrees(t *testing.T) {
+	for _, test := range []struct {
+		numLeaves int
+		shape     TreeShape
+	}{
+		{8, ShapePerfect},
+		{6, ShapeComplete},
+		{5, ShapeIrregular},
+	} {
+		t.Run(fmt.Sprintf("%d leaves", test.numLeaves), func(t *testing.T) {
+			generation, err := Generate(GenerateOptions{NumLeaves: test.numLeaves, RawScripts: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			levels := NodesPerLevel(generation.Tree)
+			if shape := ClassifyShape(levels, ComputeFanOut(generation.Tree).MaxChildren); shape != test.shape {
+				t.Errorf("%s, levels %v, expected %s", shape, levels, test.shape)
+			}
+		})
+	}
+}
+
+func TestDegenerate(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		if stats.Degenerate() {
+			t.Errorf("depth %d for %d nodes, branching factor %.2f", stats.Depth, stats.TotalSize, stats.BranchingFactor)
+		}
+		if chain := (Report{TotalSize: stats.TotalSize, Depth: stats.TotalSize, BranchingFactor: 1}); stats.TotalSize > 1 && !chain.Degenerate() {
+			t.Errorf("a chain of %d nodes is not reported as degenerate", stats.TotalSize)
+		}
+	})
+}
+
+func TestComputeFanOut(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		fanOut := ComputeFanOut(stats.Tree)
+		if fanOut.Average() != stats.BranchingFactor {
+			t.Errorf("average fan-out %.2f, branching factor %.2f", fanOut.Average(), stats.BranchingFactor)
+		}
+		if fill := fanOut.FillFactor(); fill <= 0 || fill > 1 {
+			t.Errorf("fill factor %.2f", fill)
+		}
+		internal, children := 0, 0
+		for count, nodes := range fanOut.Arity {
+			internal += nodes
+			children += count * nodes
+		}
+		if internal != fanOut.Internal || children != fanOut.Children {
+			t.Errorf("arity %v counts %d nodes and %d children, expected %d and %d", fanOut.Arity, internal, children, fanOut.Internal, fanOut.Children)
+		}
+		// the builder only splits in two
+		if len(fanOut.Arity) > 0 && (len(fanOut.Arity) != 1 || fanOut.Arity[2] != fanOut.Internal) {
+			t.Errorf("arity %v of a binary tree", fanOut.Arity)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package arktree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// DuplicateScript is an output script paid to by several leaves
+type DuplicateScript struct {
+	Script  string // hex
+	Indexes []int  // of the leaves paying to Script, ascending
+}
+
+func (d DuplicateScript) String() string {
+	indexes := make([]string, 0, len(d.Indexes))
+	for _, index := range d.Indexes {
+		indexes = append(indexes, fmt.Sprint(index))
+	}
+	return fmt.Sprintf("leaves %s share the script %s", strings.Join(indexes, ", "), d.Script)
+}
+
+// DuplicateScripts returns the scripts paid to by more than one of leaves,
+// ordered by their first leaf. Shared scripts are legal but usually a mistake
+// that makes the outputs of the tree indistinguishable to accounting.
+func DuplicateScripts(leaves []tree.Leaf) []DuplicateScript {
+	indexes := make(map[string][]int)
+	var scripts []string // by first leaf
+	for i, leaf := range leaves {
+		script := strings.ToLower(leaf.Script)
+		if _, ok := indexes[script]; !ok {
+			scripts = append(scripts, script)
+		}
+		indexes[script] = append(indexes[script], i)
+	}
+
+	var duplicates []DuplicateScript
+	for _, script := range scripts {
+		if len(indexes[script]) > 1 {
+			duplicates = append(duplicates, DuplicateScript{Script: script, Indexes: indexes[script]})
+		}
+	}
+	return duplicates
+}
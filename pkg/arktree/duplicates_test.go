@@ -0,0 +1,41 @@
+package arktree
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestDuplicateScripts(t *testing.T) {
+	a, b, c := strings.Repeat("aa", 34), strings.Repeat("bb", 34), strings.Repeat("cc", 34)
+	leavesOf := func(scripts ...string) []tree.Leaf {
+		leaves := make([]tree.Leaf, 0, len(scripts))
+		for _, script := range scripts {
+			leaves = append(leaves, tree.Leaf{Script: script, Amount: 1000})
+		}
+		return leaves
+	}
+
+	for _, test := range []struct {
+		name   string
+		leaves []tree.Leaf
+		want   []string
+	}{
+		{"distinct scripts", leavesOf(a, b, c), nil},
+		// hex is case insensitive
+		{"duplicated scripts", leavesOf(a, b, strings.ToUpper(a), c, b), []string{
+			"leaves 0, 2 share the script " + a,
+			"leaves 1, 4 share the script " + b,
+		}},
+	} {
+		var got []string
+		for _, duplicate := range DuplicateScripts(test.leaves) {
+			got = append(got, duplicate.String())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: %q, expected %q", test.name, got, test.want)
+		}
+	}
+}
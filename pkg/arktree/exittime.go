@@ -0,0 +1,96 @@
+package arktree
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+)
+
+// DefaultBlockInterval is the expected time between two blocks
+const DefaultBlockInterval = 10 * time.Minute
+
+// TreeExpiry returns the relative locktime set on the input of the root of g,
+// nil if g doesn't carry one
+func TreeExpiry(g *tree.TxGraph) (*common.RelativeLocktime, error) {
+	if len(g.Root.Inputs) == 0 {
+		return nil, nil
+	}
+	return tree.GetVtxoTreeExpiry(g.Root.Inputs[0])
+}
+
+// LocktimeDuration returns how long locktime lasts, each block taking
+// blockInterval
+func LocktimeDuration(locktime common.RelativeLocktime, blockInterval time.Duration) time.Duration {
+	if locktime.Type == common.LocktimeTypeBlock {
+		return time.Duration(locktime.Value) * blockInterval
+	}
+	return time.Duration(locktime.Value) * time.Second
+}
+
+// ExitTimes returns the time each branch takes to exit: the exit starts once
+// locktime elapsed, then each tx of the branch confirms in its own block
+func ExitTimes(locktime common.RelativeLocktime, branchSizes []int, blockInterval time.Duration) []time.Duration {
+	start := LocktimeDuration(locktime, blockInterval)
+
+	times := make([]time.Duration, 0, len(branchSizes))
+	for _, size := range branchSizes {
+		times = append(times, start+time.Duration(size)*blockInterval)
+	}
+	return times
+}
+
+// ExitTimeRange returns the shortest, median and longest of times
+func ExitTimeRange(times []time.Duration) (shortest, median, longest time.Duration) {
+	if len(times) == 0 {
+		return 0, 0, 0
+	}
+
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+
+	mid := len(sorted) / 2
+	median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[0], median, sorted[len(sorted)-1]
+}
+
+// BranchExpiries returns the relative locktime gating the first exit tx of
+// each branch of g, ordered by leaf txid as LeafTxids: the one of the topmost
+// node of the branch carrying one, nil if none does. Trees built with a single
+// locktime only carry it on the root, so it is the same for every branch.
+func BranchExpiries(g *tree.TxGraph) ([]*common.RelativeLocktime, error) {
+	expiries := make(map[*tree.TxGraph]*common.RelativeLocktime)
+	leafExpiries := make(map[string]*common.RelativeLocktime)
+	if err := Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		var expiry *common.RelativeLocktime
+		if parent != nil {
+			expiry = expiries[parent]
+		}
+		if expiry == nil && len(node.Root.Inputs) > 0 {
+			var err error
+			if expiry, err = tree.GetVtxoTreeExpiry(node.Root.Inputs[0]); err != nil {
+				return err
+			}
+		}
+
+		if len(node.Children) == 0 {
+			leafExpiries[node.Root.UnsignedTx.TxID()] = expiry
+		} else {
+			expiries[node] = expiry
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	leaves := LeafTxids(g)
+	branches := make([]*common.RelativeLocktime, 0, len(leaves))
+	for _, leaf := range leaves {
+		branches = append(branches, leafExpiries[leaf])
+	}
+	return branches, nil
+}
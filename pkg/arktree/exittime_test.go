@@ -0,0 +1,20 @@
+package arktree
+
+import "testing"
+
+func TestBranchExpiries(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		expiries, err := BranchExpiries(stats.Tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expiries) != stats.NumLeaves {
+			t.Fatalf("%d expiries for %d leaves", len(expiries), stats.NumLeaves)
+		}
+		for i, expiry := range expiries {
+			if expiry == nil || stats.Expiry == nil || *expiry != *stats.Expiry {
+				t.Errorf("branch %d waits %v, the tree expiry is %v", i, expiry, stats.Expiry)
+			}
+		}
+	})
+}
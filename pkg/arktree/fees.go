@@ -0,0 +1,312 @@
+package arktree
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ExitCost is the cost of a user broadcasting its whole branch alone
+type ExitCost struct {
+	LeafTxid string `json:"leaf_txid"`
+	Vsize    int    `json:"vsize"`
+	Fee      int64  `json:"fee"`               // sats, on top of Prepaid
+	Value    int64  `json:"value"`             // sats owned by the leaf
+	Prepaid  int64  `json:"prepaid,omitempty"` // sats the txs of the branch pay themselves, see ApplyPerTxFee
+}
+
+// FeeAt returns the fee of the exit at feerate (sat/vB), less what its txs
+// prepay
+func (c ExitCost) FeeAt(feerate float64) int64 {
+	return max(0, FeeForVsize(c.Vsize, feerate)-c.Prepaid)
+}
+
+// feeRatio is the part of the leaf value spent in fees to exit
+func (c ExitCost) FeeRatio() float64 {
+	if c.Value == 0 {
+		return math.Inf(1)
+	}
+	return float64(c.Fee) / float64(c.Value)
+}
+
+// viable is false if exiting costs more than what the leaf owns
+func (c ExitCost) Viable() bool {
+	return c.Fee <= c.Value
+}
+
+// EstimateVsize estimates the virtual size of a tree tx once signed with
+// DefaultWitnessModel, see WitnessModel.Vsize for other models
+func EstimateVsize(tx *wire.MsgTx) int {
+	weight := tx.SerializeSizeStripped()*4 + EstimatedWitnessSize*len(tx.TxIn)
+	return (weight + 3) / 4
+}
+
+// NodeVsizes returns the estimated vsize of every node of g keyed by txid
+func NodeVsizes(g *tree.TxGraph, witness WitnessModel) (map[string]int, error) {
+	vsizes := make(map[string]int)
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return false, err
+		}
+		vsizes[node.Root.UnsignedTx.TxID()] = vsize
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	return vsizes, nil
+}
+
+// FeeForVsize returns the fee in sats paying feerate (sat/vB) for vsize vbytes
+func FeeForVsize(vsize int, feerate float64) int64 {
+	return int64(math.Ceil(float64(vsize) * feerate))
+}
+
+// LeafValue returns the amount owned by the leaf tx, anchor outputs excluded
+func LeafValue(tx *wire.MsgTx) int64 {
+	value := int64(0)
+	for _, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, tree.ANCHOR_PKSCRIPT) {
+			continue
+		}
+		value += out.Value
+	}
+	return value
+}
+
+// ExitCostOfBranches computes the exit cost of every branch at feerate (sat/vB)
+// branches are ordered by leaf txid, see LeafTxids
+func ExitCostOfBranches(g *tree.TxGraph, feerate float64, witness WitnessModel) ([]ExitCost, error) {
+	return exitCostOfBranches(g, feerate, witness, 0)
+}
+
+// exitCostOfBranches is ExitCostOfBranches for a tree whose txs each pay
+// perTxFee sats, which the fee of an exit needn't cover again
+func exitCostOfBranches(g *tree.TxGraph, feerate float64, witness WitnessModel, perTxFee int64) ([]ExitCost, error) {
+	leaves := LeafTxids(g)
+
+	costs := make([]ExitCost, 0, len(leaves))
+
+	for _, leaf := range leaves {
+		branch, err := g.SubGraph([]string{leaf})
+		if err != nil {
+			return nil, err
+		}
+
+		cost, err := exitCostOfBranch(branch, leaf, feerate, witness, perTxFee)
+		if err != nil {
+			return nil, err
+		}
+		costs = append(costs, cost)
+	}
+
+	return costs, nil
+}
+
+// exitCostOfBranch returns the exit cost of branch, the SubGraph of leaf
+func exitCostOfBranch(branch *tree.TxGraph, leaf string, feerate float64, witness WitnessModel, perTxFee int64) (ExitCost, error) {
+	cost := ExitCost{LeafTxid: leaf}
+	if err := branch.Apply(func(node *tree.TxGraph) (bool, error) {
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return false, err
+		}
+		cost.Vsize += vsize
+		cost.Prepaid += perTxFee
+		if len(node.Children) == 0 {
+			cost.Value = LeafValue(node.Root.UnsignedTx)
+		}
+		return true, nil
+	}); err != nil {
+		return ExitCost{}, err
+	}
+	cost.Fee = cost.FeeAt(feerate)
+	return cost, nil
+}
+
+// MaxViableFeerate returns the highest feerate (sat/vB) at which every exit of
+// costs stays viable, its fee not exceeding the value of its leaf: the lowest
+// value per vbyte of the branches, their prepaid fees counting as value. Above
+// it, the user of that branch can't afford to exit alone. It is 0 without
+// costs.
+func MaxViableFeerate(costs []ExitCost) float64 {
+	feerate := math.Inf(1)
+	for _, cost := range costs {
+		feerate = min(feerate, float64(cost.Value+cost.Prepaid)/float64(cost.Vsize))
+	}
+	if math.IsInf(feerate, 1) {
+		return 0
+	}
+	return feerate
+}
+
+// FeerateFees is the median and the highest exit fee of the branches of a
+// tree at a feerate
+type FeerateFees struct {
+	Feerate float64 // sat/vB
+	Median  float64 // sats
+	Max     int64   // sats
+}
+
+// FeeMatrix computes the exit fees of costs at each of feerates (sat/vB),
+// scaling the vsize of each exit rather than computing it again, less the
+// fees its txs prepay
+func FeeMatrix(costs []ExitCost, feerates []float64) ([]FeerateFees, error) {
+	matrix := make([]FeerateFees, 0, len(feerates))
+	for _, feerate := range feerates {
+		if feerate <= 0 || math.IsInf(feerate, 0) || math.IsNaN(feerate) {
+			return nil, fmt.Errorf("feerate must be positive, got %g", feerate)
+		}
+		exitFees := make([]int64, 0, len(costs))
+		for _, cost := range costs {
+			exitFees = append(exitFees, cost.FeeAt(feerate))
+		}
+		slices.Sort(exitFees)
+
+		fees := FeerateFees{Feerate: feerate}
+		if n := len(exitFees); n > 0 {
+			fees.Max = exitFees[n-1]
+			fees.Median = float64(exitFees[n/2])
+			if n%2 == 0 {
+				fees.Median = (float64(exitFees[n/2-1]) + fees.Median) / 2
+			}
+		}
+		matrix = append(matrix, fees)
+	}
+	return matrix, nil
+}
+
+// SweepCost compares sweeping the funds of a tree cooperatively with
+// broadcasting the whole tree, as every user exiting unilaterally does
+type SweepCost struct {
+	Vsize     int   `json:"vsize"`
+	Fee       int64 `json:"fee"` // sats
+	TreeVsize int   `json:"tree_vsize"`
+	TreeFee   int64 `json:"tree_fee"` // sats
+}
+
+// Ratio is the number of times the whole tree costs the cooperative sweep
+func (c SweepCost) Ratio() float64 {
+	if c.Vsize == 0 {
+		return 0
+	}
+	return float64(c.TreeVsize) / float64(c.Vsize)
+}
+
+// CooperativeSweepCost computes the cost of a cooperative sweep of g at
+// feerate (sat/vB). When the users cooperate none of the tree is broadcast but
+// a single tx spending the batch output, which the root spends too: the sweep
+// is counted as the root, the only tx needed to claim all the funds.
+func CooperativeSweepCost(g *tree.TxGraph, feerate float64, witness WitnessModel) (SweepCost, error) {
+	vsize, err := witness.Vsize(g.Root)
+	if err != nil {
+		return SweepCost{}, err
+	}
+	treeVsize, err := TreeVsize(g, witness)
+	if err != nil {
+		return SweepCost{}, err
+	}
+	return SweepCost{
+		Vsize:     vsize,
+		Fee:       FeeForVsize(vsize, feerate),
+		TreeVsize: treeVsize,
+		TreeFee:   FeeForVsize(treeVsize, feerate),
+	}, nil
+}
+
+// TreeVsize returns the estimated vsize of all the txs of g
+func TreeVsize(g *tree.TxGraph, witness WitnessModel) (int, error) {
+	total := 0
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return false, err
+		}
+		total += vsize
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// VsizePerLevel returns the estimated vsize of the txs of each level of g,
+// the root level first, as NodesPerLevel counts them
+func VsizePerLevel(g *tree.TxGraph, witness WitnessModel) ([]int, error) {
+	var levels []int
+	if err := Walk(g, func(node, _ *tree.TxGraph, level int) error {
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return err
+		}
+		if level > len(levels) {
+			levels = append(levels, 0)
+		}
+		levels[level-1] += vsize
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// TxSize is the estimated vsize of a tx of a tree
+type TxSize struct {
+	Txid      string
+	Level     int // the root being at 1
+	Cosigners int
+	Vsize     int
+}
+
+// TxSizes returns the estimated vsize of every tx of g, the biggest first,
+// ties ordered by level then txid
+func TxSizes(g *tree.TxGraph, witness WitnessModel) ([]TxSize, error) {
+	var sizes []TxSize
+	if err := Walk(g, func(node, _ *tree.TxGraph, level int) error {
+		cosigners, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, TxSize{Txid: node.Root.UnsignedTx.TxID(), Level: level, Cosigners: len(cosigners), Vsize: vsize})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Vsize != sizes[j].Vsize {
+			return sizes[i].Vsize > sizes[j].Vsize
+		}
+		if sizes[i].Level != sizes[j].Level {
+			return sizes[i].Level < sizes[j].Level
+		}
+		return sizes[i].Txid < sizes[j].Txid
+	})
+	return sizes, nil
+}
+
+// OverBudget returns the branches whose exit fee exceeds budget sats, the
+// most expensive first and ties ordered by leaf txid
+func OverBudget(costs []ExitCost, budget int64) []ExitCost {
+	var over []ExitCost
+	for _, cost := range costs {
+		if cost.Fee > budget {
+			over = append(over, cost)
+		}
+	}
+	sort.SliceStable(over, func(i, j int) bool {
+		if over[i].Fee != over[j].Fee {
+			return over[i].Fee > over[j].Fee
+		}
+		return over[i].LeafTxid < over[j].LeafTxid
+	})
+	return over
+}
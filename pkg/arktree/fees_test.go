@@ -0,0 +1,105 @@
+package arktree
+
+import "testing"
+
+func TestCooperativeSweepCost(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		vsizes, err := NodeVsizes(stats.Tree, DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		treeVsize := 0
+		for _, vsize := range vsizes {
+			treeVsize += vsize
+		}
+		sweep, root := stats.CooperativeSweep, vsizes[stats.Tree.Root.UnsignedTx.TxID()]
+		if sweep.Vsize != root || sweep.TreeVsize != treeVsize {
+			t.Errorf("sweep %d vB and tree %d vB, expected %d and %d", sweep.Vsize, sweep.TreeVsize, root, treeVsize)
+		}
+		if sweep.Fee != int64(sweep.Vsize) || sweep.TreeFee != int64(treeVsize) {
+			t.Errorf("fees %d and %d at 1 sat/vB", sweep.Fee, sweep.TreeFee)
+		}
+	})
+}
+
+func TestExitCostsAreNonNegative(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		if stats.WireSize.NonWitness <= 0 || stats.WireSize.Witness < 0 || stats.WireSize.Metadata < 0 {
+			t.Errorf("size on wire %+v", stats.WireSize)
+		}
+		for _, cost := range stats.ExitCosts {
+			if cost.Vsize <= 0 || cost.Fee < 0 || cost.Value < 0 {
+				t.Errorf("branch %s has exit cost %+v", cost.LeafTxid, cost)
+			}
+		}
+	})
+}
+
+func TestFeeMatrix(t *testing.T) {
+	stats := analyzeSeeded(t, GenerateOptions{NumLeaves: 9, RawScripts: true, AnalyzeOptions: AnalyzeOptions{Feerate: 1}}, 1)
+	matrix, err := FeeMatrix(stats.ExitCosts, []float64{stats.Feerate, 7.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	worst := int64(0)
+	fees := make([]float64, 0, len(stats.ExitCosts))
+	for _, cost := range stats.ExitCosts {
+		worst = max(worst, cost.Fee)
+		fees = append(fees, float64(cost.Fee))
+	}
+	if median := CalculateMedianFloat(fees); matrix[0].Max != worst || matrix[0].Median != median {
+		t.Errorf("max %d and median %g at %g sat/vB, expected %d and %g", matrix[0].Max, matrix[0].Median, stats.Feerate, worst, median)
+	}
+	if matrix[1].Max < matrix[0].Max*7 || matrix[1].Median < matrix[0].Median*7 {
+		t.Errorf("fees at 7.5 sat/vB lower than at 1: %+v", matrix[1])
+	}
+	if _, err := FeeMatrix(stats.ExitCosts, []float64{0}); err == nil {
+		t.Error("zero feerate accepted")
+	}
+}
+
+func TestVsizePerLevel(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		if len(stats.LevelVsizes) != stats.Depth {
+			t.Fatalf("%d levels for depth %d", len(stats.LevelVsizes), stats.Depth)
+		}
+		total := 0
+		for _, vsize := range stats.LevelVsizes {
+			total += vsize
+		}
+		if total != stats.CooperativeSweep.TreeVsize {
+			t.Errorf("%d vB over the levels, the tree is %d vB", total, stats.CooperativeSweep.TreeVsize)
+		}
+		if root := stats.LevelVsizes[0]; root != stats.CooperativeSweep.Vsize {
+			t.Errorf("root level %d vB, the root is %d vB", root, stats.CooperativeSweep.Vsize)
+		}
+	})
+}
+
+func TestTxSizes(t *testing.T) {
+	stats := analyzeSeeded(t, GenerateOptions{NumLeaves: 9, RawScripts: true}, 1)
+	sizes, err := TxSizes(stats.Tree, DefaultWitnessModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vsize, err := TreeVsize(stats.Tree, DefaultWitnessModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != stats.TotalSize {
+		t.Fatalf("%d txs, expected %d", len(sizes), stats.TotalSize)
+	}
+	total := 0
+	for i, size := range sizes {
+		total += size.Vsize
+		if i > 0 && size.Vsize > sizes[i-1].Vsize {
+			t.Errorf("tx %d of %d vB after one of %d vB", i, size.Vsize, sizes[i-1].Vsize)
+		}
+		if size.Level < 1 || size.Level > stats.Depth {
+			t.Errorf("tx %s at level %d of a tree of depth %d", size.Txid, size.Level, stats.Depth)
+		}
+	}
+	if total != vsize {
+		t.Errorf("summed vsize %d, expected %d", total, vsize)
+	}
+}
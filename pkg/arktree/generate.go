@@ -0,0 +1,227 @@
+package arktree
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/hkdf"
+)
+
+// RandomSource returns the source of randomness used to generate a tree:
+// crypto/rand unless seeded, in which case the same seed always produces the
+// same leaves, keys and tree
+func RandomSource(seed int64, seeded bool) io.Reader {
+	if !seeded {
+		return rand.Reader
+	}
+
+	var chachaSeed [32]byte
+	binary.LittleEndian.PutUint64(chachaSeed[:], uint64(seed))
+	return mathrand.NewChaCha8(chachaSeed)
+}
+
+// DefaultLeafAmount is the amount in sats of each generated leaf
+const DefaultLeafAmount = 1000
+
+// DefaultLocktime is the sweep locktime of the trees built without an explicit locktime
+var DefaultLocktime = common.RelativeLocktime{Value: 100, Type: common.LocktimeTypeBlock}
+
+// GenerateLeaves generates numLeaves leaves of amount sats each paying to a P2TR
+// script of a fresh key, or to 34 random bytes with rawScripts which is faster
+// but produces unspendable outputs. Each leaf is cosigned by a fresh random key
+// or, if groups is positive, by the key of its group (see CosignerGroup), the
+// group keys being generated first. A single group shares one key across all
+// leaves. With a cosignerSeed, the cosigner keys are derived from it by
+// DeriveCosignerKey, indexed by leaf or by group, instead of read from rnd.
+//
+// BuildVtxoTree needs all the leaves at once so they can't be streamed, but the
+// script and hex buffers are reused across leaves and group keys are only
+// encoded once.
+func GenerateLeaves(numLeaves int, amount uint64, groups int, rawScripts bool, rnd io.Reader, cosignerSeed []byte) ([]tree.Leaf, error) {
+	leaves, _, err := generateLeaves(numLeaves, amount, groups, rawScripts, rnd, cosignerSeed)
+	return leaves, err
+}
+
+// generateLeaves is GenerateLeaves also returning the private cosigner key of
+// each leaf, shared by the leaves of a group
+func generateLeaves(numLeaves int, amount uint64, groups int, rawScripts bool, rnd io.Reader, cosignerSeed []byte) ([]tree.Leaf, []*secp256k1.PrivateKey, error) {
+	leaves := make([]tree.Leaf, numLeaves)
+	keys := make([]*secp256k1.PrivateKey, numLeaves)
+
+	cosignerKey := func(index int) (*secp256k1.PrivateKey, error) {
+		if cosignerSeed != nil {
+			return DeriveCosignerKey(cosignerSeed, index)
+		}
+		return generatePrivateKey(rnd)
+	}
+
+	groupCosigners := make([][]string, 0, groups)
+	groupPrivkeys := make([]*secp256k1.PrivateKey, 0, groups)
+	for group := 0; group < groups; group++ {
+		groupPrivkey, err := cosignerKey(group)
+		if err != nil {
+			return nil, nil, fmt.Errorf("group %d: failed to generate shared private key: %w", group, err)
+		}
+		groupCosigners = append(groupCosigners, []string{hex.EncodeToString(groupPrivkey.PubKey().SerializeCompressed())})
+		groupPrivkeys = append(groupPrivkeys, groupPrivkey)
+	}
+
+	script := make([]byte, p2trScriptSize)
+	hexBuf := make([]byte, hex.EncodedLen(p2trScriptSize))
+
+	for i := 0; i < numLeaves; i++ {
+		if err := fillRandomScript(script, rawScripts, rnd); err != nil {
+			return nil, nil, &LeafError{Index: i, Err: fmt.Errorf("failed to generate script: %w", err)}
+		}
+		hex.Encode(hexBuf, script)
+
+		var cosigners []string
+		if groups > 0 {
+			cosigners = groupCosigners[CosignerGroup(i, groups)]
+			keys[i] = groupPrivkeys[CosignerGroup(i, groups)]
+		} else {
+			randomPrivkey, err := cosignerKey(i)
+			if err != nil {
+				return nil, nil, &LeafError{Index: i, Err: fmt.Errorf("failed to generate private key: %w", err)}
+			}
+			cosigners = []string{hex.EncodeToString(randomPrivkey.PubKey().SerializeCompressed())}
+			keys[i] = randomPrivkey
+		}
+
+		leaves[i] = tree.Leaf{
+			Amount:              amount,
+			Script:              string(hexBuf),
+			CosignersPublicKeys: cosigners,
+		}
+	}
+
+	return leaves, keys, nil
+}
+
+// drawAmounts sets the amount of each leaf to one of samples drawn uniformly
+// with replacement. The draws are read from rnd once the leaves are generated,
+// so that a seed gives the same scripts and keys with or without samples.
+func drawAmounts(leaves []tree.Leaf, samples []uint64, rnd io.Reader) error {
+	if len(samples) == 0 {
+		return errors.New("no amount samples")
+	}
+
+	var chachaSeed [32]byte
+	if _, err := io.ReadFull(rnd, chachaSeed[:]); err != nil {
+		return fmt.Errorf("failed to draw amounts: %w", err)
+	}
+	draws := mathrand.New(mathrand.NewChaCha8(chachaSeed))
+	for i := range leaves {
+		leaves[i].Amount = samples[draws.IntN(len(samples))]
+	}
+	return nil
+}
+
+// LeafError is an error caused by one of the leaves of a tree
+type LeafError struct {
+	Index int // position of the leaf in the leaves list
+	Err   error
+}
+
+func (e *LeafError) Error() string {
+	return fmt.Sprintf("leaf %d: %s", e.Index, e.Err)
+}
+
+func (e *LeafError) Unwrap() error {
+	return e.Err
+}
+
+// CosignerGroup returns the group of the i-th leaf when leaves are spread over
+// groups cosigner groups, round robin so that group sizes differ by one at most
+func CosignerGroup(i, groups int) int {
+	return i % groups
+}
+
+// p2trScriptSize is the size of a P2TR output script: OP_1 OP_DATA_32 <x-only key>
+const p2trScriptSize = 34
+
+// fillRandomScript fills script with a P2TR script of a fresh key, or with
+// random bytes if raw
+func fillRandomScript(script []byte, raw bool, rnd io.Reader) error {
+	if raw {
+		_, err := io.ReadFull(rnd, script)
+		return err
+	}
+
+	privkey, err := generatePrivateKey(rnd)
+	if err != nil {
+		return err
+	}
+	script[0] = txscript.OP_1
+	script[1] = txscript.OP_DATA_32
+	copy(script[2:], schnorr.SerializePubKey(privkey.PubKey()))
+	return nil
+}
+
+// cosignerKeyInfo is the HKDF info of the cosigner keys, followed by their
+// index as 8 big-endian bytes
+const cosignerKeyInfo = "arktree/cosigner/"
+
+// DeriveCosignerKey derives the index-th cosigner key from seed with
+// HKDF-SHA256, so that a seed always gives the same keys whatever the other
+// random data of the tree
+func DeriveCosignerKey(seed []byte, index int) (*secp256k1.PrivateKey, error) {
+	info := binary.BigEndian.AppendUint64([]byte(cosignerKeyInfo), uint64(index))
+	return generatePrivateKey(hkdf.New(sha256.New, seed, nil, info))
+}
+
+// keyGenAttempts is the number of times generatePrivateKey tries before giving up
+const keyGenAttempts = 3
+
+// generatePrivateKey generates a private key from rnd, retrying on failure so
+// that a transient RNG error doesn't waste a whole generation
+func generatePrivateKey(rnd io.Reader) (*secp256k1.PrivateKey, error) {
+	var err error
+	for attempt := 0; attempt < keyGenAttempts; attempt++ {
+		var privkey *secp256k1.PrivateKey
+		privkey, err = secp256k1.GeneratePrivateKeyFromRand(rnd)
+		if err == nil {
+			return privkey, nil
+		}
+	}
+	return nil, fmt.Errorf("%d attempts failed: %w", keyGenAttempts, err)
+}
+
+// ErrNilTree is returned when BuildVtxoTree returns neither a tree nor an error
+var ErrNilTree = errors.New("builder returned no tree")
+
+// BuildTree builds the vtxo tree of the leaves, spending the first output of
+// rootTxid. A missing tree is reported as ErrNilTree with the build parameters
+// rather than left to panic in the statistics.
+func BuildTree(leaves []tree.Leaf, sweepTreeRoot, rootTxid []byte, locktime common.RelativeLocktime) (*tree.TxGraph, error) {
+	root := &wire.OutPoint{
+		Hash:  chainhash.Hash(rootTxid),
+		Index: 0,
+	}
+	txtree, err := tree.BuildVtxoTree(root, leaves, sweepTreeRoot, locktime)
+	if err != nil {
+		return nil, err
+	}
+	if txtree == nil || txtree.Root == nil {
+		unit := "blocks"
+		if locktime.Type == common.LocktimeTypeSecond {
+			unit = "seconds"
+		}
+		return nil, fmt.Errorf("%w for %d leaves, spending %s with a sweep locktime of %d %s",
+			ErrNilTree, len(leaves), root, locktime.Value, unit)
+	}
+	return txtree, nil
+}
@@ -0,0 +1,79 @@
+package arktree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCosignerSeed(t *testing.T) {
+	cosignerSeed := bytes.Repeat([]byte{0x42}, 32)
+	cosigners := func(seed int64) []string {
+		generation, err := Generate(GenerateOptions{NumLeaves: 4, Seed: &seed, CosignerSeed: cosignerSeed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, leaf := range generation.Leaves {
+			keys = append(keys, leaf.CosignersPublicKeys...)
+		}
+		return keys
+	}
+
+	first, second := cosigners(1), cosigners(2)
+	if !slices.Equal(first, second) {
+		t.Errorf("keys %v with seed 1, %v with seed 2", first, second)
+	}
+	if first[0] == first[1] {
+		t.Errorf("leaves 0 and 1 share the key %s", first[0])
+	}
+}
+
+func TestCosignerKeysMatchLeaves(t *testing.T) {
+	for _, groups := range []int{0, 2} {
+		t.Run(fmt.Sprintf("%d groups", groups), func(t *testing.T) {
+			generation, err := Generate(GenerateOptions{NumLeaves: 4, CosignerGroups: groups})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(generation.CosignerKeys) != len(generation.Leaves) {
+				t.Fatalf("%d keys for %d leaves", len(generation.CosignerKeys), len(generation.Leaves))
+			}
+			for i, key := range generation.CosignerKeys {
+				pubkey := hex.EncodeToString(key.PubKey().SerializeCompressed())
+				if pubkey != generation.Leaves[i].CosignersPublicKeys[0] {
+					t.Errorf("leaf %d: key of %s, cosigner %s", i, pubkey, generation.Leaves[i].CosignersPublicKeys[0])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGenerateLeaves measures the allocations of the leaves of a large
+// tree, by script type and whether they share a cosigner key:
+//
+//	go test ./pkg/arktree -run '^$' -bench GenerateLeaves
+func BenchmarkGenerateLeaves(b *testing.B) {
+	const numLeaves = 10000
+	for _, rawScripts := range []bool{false, true} {
+		for _, groups := range []int{0, 1} {
+			scripts, keys := "p2tr", "fresh"
+			if rawScripts {
+				scripts = "raw"
+			}
+			if groups == 1 {
+				keys = "shared"
+			}
+			b.Run(fmt.Sprintf("scripts=%s/keys=%s", scripts, keys), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := GenerateLeaves(numLeaves, DefaultLeafAmount, groups, rawScripts, RandomSource(int64(i), true), nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
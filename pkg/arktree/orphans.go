@@ -0,0 +1,75 @@
+package arktree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// SplitOrphans separates the chunks of a serialized tree reachable from its
+// root from the orphans, which tree.NewTxGraph rejects as a whole. The root
+// is the chunk no other chunk has as a child, the one reaching the most
+// chunks if there are several. Orphans are returned as sorted txids.
+func SplitOrphans(chunks []tree.TxGraphChunk) (reachable []tree.TxGraphChunk, orphans []string, err error) {
+	byTxid := make(map[string]tree.TxGraphChunk, len(chunks))
+	txids := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		packet, err := psbt.NewFromRawBytes(strings.NewReader(chunk.Tx), true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chunk %d: failed to decode PSBT: %w", i, err)
+		}
+		txid := packet.UnsignedTx.TxID()
+		byTxid[txid] = chunk
+		txids = append(txids, txid)
+	}
+
+	isChild := make(map[string]bool)
+	for txid, chunk := range byTxid {
+		for _, child := range chunk.Children {
+			if child != txid {
+				isChild[child] = true
+			}
+		}
+	}
+
+	var best map[string]bool
+	for _, txid := range txids {
+		if isChild[txid] {
+			continue
+		}
+
+		visited := make(map[string]bool)
+		var visit func(txid string)
+		visit = func(txid string) {
+			chunk, ok := byTxid[txid]
+			if !ok || visited[txid] {
+				return
+			}
+			visited[txid] = true
+			for _, child := range chunk.Children {
+				visit(child)
+			}
+		}
+		visit(txid)
+
+		if len(visited) > len(best) {
+			best = visited
+		}
+	}
+	if best == nil {
+		return nil, nil, fmt.Errorf("no root chunk found")
+	}
+
+	for i, txid := range txids {
+		if best[txid] {
+			reachable = append(reachable, chunks[i])
+		} else {
+			orphans = append(orphans, txid)
+		}
+	}
+	sort.Strings(orphans)
+	return reachable, orphans, nil
+}
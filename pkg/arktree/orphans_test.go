@@ -0,0 +1,33 @@
+package arktree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitOrphans(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 3, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := generation.Tree.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Generate(GenerateOptions{NumLeaves: 1, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := other.Tree.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reachable, orphans, err := SplitOrphans(append(chunks, orphan...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{other.Tree.Root.UnsignedTx.TxID()}; len(reachable) != len(chunks) || !slices.Equal(orphans, want) {
+		t.Errorf("%d reachable nodes and orphans %v, expected %d and %v", len(reachable), orphans, len(chunks), want)
+	}
+}
@@ -0,0 +1,95 @@
+package arktree
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// BranchStatsParallel computes the size and the broadcast weight of every branch
+// like SizeOfBranches and WeightOfBranches do, spreading the leaves over workers goroutines.
+// Results are ordered by leaf txid, see LeafTxids.
+func BranchStatsParallel(g *tree.TxGraph, workers int) ([]int, []float64, error) {
+	return branchStatsParallel(context.Background(), g, LeafTxids(g), workers, false)
+}
+
+// branchStatsParallel is BranchStatsParallel stopping when ctx is done. Leaves
+// are handed out in order and the workers finish the branch they are on, so
+// the statistics computed so far are those of the first leaves.
+func branchStatsParallel(ctx context.Context, g *tree.TxGraph, leaves []string, workers int, excludeNUMS bool) ([]int, []float64, error) {
+	branchSizes := make([]int, len(leaves))
+	branchWeights := make([]float64, len(leaves))
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	indexes := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				size, weight, err := branchStats(g, leaves[i], excludeNUMS)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				branchSizes[i] = size
+				branchWeights[i] = weight
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for dispatched < len(leaves) {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case indexes <- dispatched:
+			dispatched++
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil && dispatched < len(leaves) {
+		return branchSizes[:dispatched], branchWeights[:dispatched], err
+	}
+	return branchSizes, branchWeights, nil
+}
+
+// branchStats returns the size and the broadcast weight of the branch ending at leafTxid
+func branchStats(g *tree.TxGraph, leafTxid string, excludeNUMS bool) (int, float64, error) {
+	branch, err := g.SubGraph([]string{leafTxid})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	size, err := NumberOfNodes(branch)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	weight, err := computeBroadcastWeight(branch, false, excludeNUMS)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return size, weight, nil
+}
@@ -0,0 +1,45 @@
+package arktree
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestBranchStatsParallelMatchesSerial(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		sizes, weights, err := BranchStatsParallel(stats.Tree, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(sizes, stats.BranchSizes) || !slices.Equal(weights, stats.BranchWeights) {
+			t.Errorf("parallel sizes %v and weights %v, serial %v and %v", sizes, weights, stats.BranchSizes, stats.BranchWeights)
+		}
+	})
+}
+
+// BenchmarkBranchStats compares the serial branch statistics with
+// BranchStatsParallel on the same trees
+func BenchmarkBranchStats(b *testing.B) {
+	for _, numLeaves := range benchmarkLeaves {
+		g := benchmarkTree(b, numLeaves)
+		b.Run(fmt.Sprintf("leaves=%d/serial", numLeaves), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := SizeOfBranches(g); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := WeightOfBranches(g, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("leaves=%d/parallel", numLeaves), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := BranchStatsParallel(g, runtime.NumCPU()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package arktree
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// branchPath holds the statistics of the branch from the root to a node
+type branchPath struct {
+	size         int
+	weight       float64
+	anchorWeight float64 // weight counting the anchor children
+}
+
+// branchPaths returns the branch of each leaf in a single walk of g, instead
+// of building the SubGraph of every leaf: the branch of a node is the one of
+// its parent plus the node, so it is derived from the path recorded for the
+// parent. The shares are added from the root down, the order Apply sums them
+// in on a branch, so the weights are the same floats ComputeBroadcastWeight
+// gives. The number of cosigners of the nodes, read for the shares, is
+// summarized along. With excludeNUMS the NUMS point doesn't share the weights,
+// see signerCount. It stops with ctx, returning no branch.
+func branchPaths(ctx context.Context, g *tree.TxGraph, leaves []string, excludeNUMS bool) ([]branchPath, CosignerCount, error) {
+	paths := make(map[*tree.TxGraph]branchPath)
+	leafPaths := make(map[string]branchPath, len(leaves))
+	var (
+		count          CosignerCount
+		nodes, signers int
+	)
+
+	if err := Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		cosignerKeys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+
+		if len(cosignerKeys) == 0 {
+			return fmt.Errorf("node %s has no cosigner keys", node.Root.UnsignedTx.TxID())
+		}
+
+		if nodes == 0 || len(cosignerKeys) < count.Min {
+			count.Min = len(cosignerKeys)
+		}
+		count.Max = max(count.Max, len(cosignerKeys))
+		nodes++
+		signers += len(cosignerKeys)
+		shared, nums := signerCount(cosignerKeys, excludeNUMS)
+		if nums {
+			count.NUMSNodes++
+		}
+
+		var path branchPath
+		if parent != nil {
+			path = paths[parent]
+		}
+		share := 1 / float64(shared)
+		path.size++
+		path.weight += share
+		path.anchorWeight += share
+		if HasAnchorOutput(node.Root.UnsignedTx) {
+			path.anchorWeight += share
+		}
+
+		if len(node.Children) == 0 {
+			leafPaths[node.Root.UnsignedTx.TxID()] = path
+		} else {
+			paths[node] = path
+		}
+		return nil
+	}); err != nil {
+		return nil, CosignerCount{}, err
+	}
+	count.Mean = float64(signers) / float64(nodes)
+
+	branches := make([]branchPath, 0, len(leaves))
+	for _, leaf := range leaves {
+		path, ok := leafPaths[leaf]
+		if !ok {
+			return nil, CosignerCount{}, fmt.Errorf("leaf %s not found in the tree", leaf)
+		}
+		branches = append(branches, path)
+	}
+	return branches, count, nil
+}
@@ -0,0 +1,54 @@
+package arktree
+
+import (
+	"github.com/ark-network/ark/common/tree"
+)
+
+// CoExitGroups returns the co-exit group sizes of g by level, the root level
+// first: for each node, the number of leaves under it. The users of these
+// leaves all broadcast the node to exit, so exiting together links them to
+// each other through it. A leaf is a group of its own user.
+func CoExitGroups(g *tree.TxGraph) [][]int {
+	leaves := LeavesUnder(g)
+
+	var levels [][]int
+	// the callback never fails
+	_ = Walk(g, func(node, _ *tree.TxGraph, level int) error {
+		if level > len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[level-1] = append(levels[level-1], leaves[node])
+		return nil
+	})
+	return levels
+}
+
+// PrivacyScore summarizes the co-exit groups of a tree, see CoExitGroups,
+// between 0 and 1:
+//
+//	score = 1 - mean(users linked by a shared tx) / users
+//
+// where the shared txs are the nodes of more than one user. It is higher when
+// each shared tx links fewer of the users: 0 when the root is the only one, as
+// it links all of them, and close to 1 for a deep balanced tree. A tree of a
+// single user, who shares no tx, scores 1.
+func PrivacyScore(groups [][]int) float64 {
+	if len(groups) == 0 || len(groups[0]) == 0 {
+		return 1
+	}
+	users := groups[0][0]
+
+	shared, linked := 0, 0
+	for _, level := range groups {
+		for _, size := range level {
+			if size > 1 {
+				shared++
+				linked += size
+			}
+		}
+	}
+	if shared == 0 {
+		return 1
+	}
+	return 1 - float64(linked)/float64(shared)/float64(users)
+}
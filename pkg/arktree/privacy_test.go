@@ -0,0 +1,41 @@
+package arktree
+
+import "testing"
+
+func TestCoExitGroups(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		groups := CoExitGroups(stats.Tree)
+		if groups[0][0] != stats.NumLeaves {
+			t.Errorf("the root links %d users, expected the %d leaves", groups[0][0], stats.NumLeaves)
+		}
+		nodes, linked, branches := 0, 0, 0
+		for _, level := range groups {
+			nodes += len(level)
+			for _, size := range level {
+				linked += size
+			}
+		}
+		for _, size := range stats.BranchSizes {
+			branches += size
+		}
+		if nodes != stats.TotalSize || linked != branches {
+			t.Errorf("%d groups linking %d users, expected %d txs and the %d txs of the branches", nodes, linked, stats.TotalSize, branches)
+		}
+	})
+}
+
+func TestPrivacyScore(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		groups [][]int
+		want   float64
+	}{
+		// 7 shared txs linking 8, 4, 4, 2, 2, 2 and 2 users
+		{"perfect tree of 8 leaves", [][]int{{8}, {4, 4}, {2, 2, 2, 2}, {1, 1, 1, 1, 1, 1, 1, 1}}, 1 - 24.0/7/8},
+		{"root alone linking the users", [][]int{{3}, {1, 1, 1}}, 0},
+	} {
+		if score := PrivacyScore(test.groups); !floatsClose(score, test.want) {
+			t.Errorf("%s: privacy score %g, expected %g", test.name, score, test.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package arktree
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Validate checks the internal consistency of the report, e.g. of one decoded
+// from JSON: non-negative counts, one entry per branch in the per-branch
+// fields, branch sizes within [1, depth], weights within (0, size] and the
+// median branch size within the smallest and biggest ones. Partial reports
+// may cover fewer branches than NumLeaves.
+func (s *Report) Validate() error {
+	if s.TotalSize < 0 || s.NumLeaves < 0 || s.Depth < 0 {
+		return fmt.Errorf("negative count: %d txs, %d leaves, depth %d", s.TotalSize, s.NumLeaves, s.Depth)
+	}
+	if s.Depth > s.TotalSize || s.NumLeaves > s.TotalSize {
+		return fmt.Errorf("%d txs can't have depth %d and %d leaves", s.TotalSize, s.Depth, s.NumLeaves)
+	}
+	if s.Feerate < 0 || s.BlockInterval < 0 || s.TotalValue < 0 {
+		return fmt.Errorf("negative feerate %g, block interval %s or total value %d", s.Feerate, s.BlockInterval, s.TotalValue)
+	}
+	if err := s.Witness.Validate(); err != nil {
+		return err
+	}
+
+	branches := len(s.BranchSizes)
+	if len(s.LeafTxids) != branches {
+		return fmt.Errorf("%d leaf txids for %d branch sizes", len(s.LeafTxids), branches)
+	}
+	if s.Partial {
+		if branches > s.NumLeaves {
+			return fmt.Errorf("%d branches for %d leaves", branches, s.NumLeaves)
+		}
+	} else if branches != s.NumLeaves {
+		return fmt.Errorf("%d branches for %d leaves", branches, s.NumLeaves)
+	}
+	if len(s.BranchWeights) > branches {
+		return fmt.Errorf("%d branch weights for %d branches", len(s.BranchWeights), branches)
+	}
+	if len(s.AnchorWeights) > 0 && len(s.AnchorWeights) != len(s.BranchWeights) {
+		return fmt.Errorf("%d anchor weights for %d branch weights", len(s.AnchorWeights), len(s.BranchWeights))
+	}
+	if len(s.VsizeWeights) > 0 && len(s.VsizeWeights) != len(s.BranchWeights) {
+		return fmt.Errorf("%d vsize weights for %d branch weights", len(s.VsizeWeights), len(s.BranchWeights))
+	}
+	if len(s.ExitTimes) > 0 && len(s.ExitTimes) != branches {
+		return fmt.Errorf("%d exit times for %d branches", len(s.ExitTimes), branches)
+	}
+	if len(s.ExitCosts) > 0 && len(s.ExitCosts) != s.NumLeaves {
+		return fmt.Errorf("%d exit costs for %d leaves", len(s.ExitCosts), s.NumLeaves)
+	}
+
+	for i, size := range s.BranchSizes {
+		if size < 1 || size > s.Depth {
+			return fmt.Errorf("branch %d has size %d, outside [1, %d]", i, size, s.Depth)
+		}
+		if i < len(s.BranchWeights) && (s.BranchWeights[i] <= 0 || s.BranchWeights[i] > float64(size)) {
+			return fmt.Errorf("branch %d has weight %g, outside (0, %d]", i, s.BranchWeights[i], size)
+		}
+	}
+	if branches > 0 {
+		median := CalculateMedian(s.BranchSizes)
+		if median < float64(slices.Min(s.BranchSizes)) || median > float64(slices.Max(s.BranchSizes)) {
+			return fmt.Errorf("median branch size %g outside [%d, %d]", median, slices.Min(s.BranchSizes), slices.Max(s.BranchSizes))
+		}
+	}
+
+	if c := s.CosignersPerNode; c.Max > 0 && (c.Min < 1 || c.Mean < float64(c.Min) || c.Mean > float64(c.Max)) {
+		return fmt.Errorf("cosigners per node: min %d, mean %g, max %d", c.Min, c.Mean, c.Max)
+	}
+
+	for _, cost := range s.ExitCosts {
+		if cost.Vsize <= 0 || cost.Fee < 0 || cost.Value < 0 {
+			return fmt.Errorf("branch %s has exit cost %+v", cost.LeafTxid, cost)
+		}
+	}
+
+	return nil
+}
+
+// SummaryVersion is bumped whenever Summary changes incompatibly
+const SummaryVersion = 1
+
+// Distribution summarizes a per-branch metric, Counts maps each value to the
+// number of branches having it so that distributions can be pooled exactly:
+// branch sizes are keyed by their integer value, weights by their 2 decimals
+// representation, since JSON object keys must be strings.
+type Distribution struct {
+	Max    float64        `json:"max"`
+	Mean   float64        `json:"mean"`
+	Median float64        `json:"median"`
+	Stddev float64        `json:"stddev"`
+	Counts map[string]int `json:"counts"`
+}
+
+// NewDistribution summarizes weights
+func NewDistribution(weights []float64) Distribution {
+	counts := make(map[string]int)
+	for weight, count := range GroupWeights(weights) {
+		counts[fmt.Sprintf("%.2f", weight)] = count
+	}
+	return Distribution{
+		Max:    MaxFloat(weights),
+		Mean:   CalculateAverageFloat(weights),
+		Median: CalculateMedianFloat(weights),
+		Stddev: CalculateStddevFloat(weights),
+		Counts: counts,
+	}
+}
+
+// NewSizeDistribution summarizes branch sizes
+func NewSizeDistribution(sizes []int) Distribution {
+	values := make([]float64, 0, len(sizes))
+	counts := make(map[string]int)
+	for _, size := range sizes {
+		values = append(values, float64(size))
+		counts[strconv.Itoa(size)]++
+	}
+	d := NewDistribution(values)
+	d.Counts = counts
+	return d
+}
+
+// Summary is the aggregate statistics of a Report, the JSON document printed
+// by arktree generate --output json along with the fields only the CLI sets.
+// The keys it shares with Report, such as leaves, total_transactions or
+// branch_sizes, name the same metrics. They follow the order of the fields,
+// and its arrays have a fixed order too: branches by leaf txid, key_churn by
+// level.
+type Summary struct {
+	SchemaVersion     int               `json:"schema_version"` // SummaryVersion
+	Leaves            int               `json:"leaves"`
+	TotalTransactions int               `json:"total_transactions"`
+	BranchSizes       Distribution      `json:"branch_sizes"`
+	ExcludedBranchTxs int               `json:"excluded_branch_txs,omitempty"` // shared txs left out of the branch sizes, see SummaryOptions
+	BroadcastWeights  Distribution      `json:"broadcast_weights"`
+	VsizeWeights      *Distribution     `json:"vsize_weights,omitempty"` // with WeightByVsize
+	Balance           float64           `json:"balance"`
+	Amortization      float64           `json:"amortization"`
+	BroadcastSharing  SharingSummary    `json:"broadcast_sharing"`
+	BranchingFactor   float64           `json:"branching_factor"`
+	Degenerate        bool              `json:"degenerate"`
+	TxVersion         int32             `json:"tx_version"`
+	TxLocktime        uint32            `json:"tx_locktime"`
+	SizeOnWire        int               `json:"size_on_wire"`
+	StoragePerVtxo    StorageSummary    `json:"storage_per_vtxo"`
+	TotalValue        int64             `json:"total_value"`             // sats owned by the leaves
+	ValueClamped      bool              `json:"value_clamped,omitempty"` // total_value overflowed, with ClampValue
+	CooperativeSweep  *SweepCostSummary `json:"cooperative_sweep,omitempty"`
+	MaxViableFeerate  float64           `json:"max_viable_feerate,omitempty"` // sat/vB, 0 in partial reports
+	Partial           bool              `json:"partial,omitempty"`            // see AnalyzeContext
+	ExitTimes         *ExitTimesSummary `json:"exit_times,omitempty"`         // if the tree has an expiry
+	KeyChurn          []LevelChurn      `json:"key_churn"`
+	LevelVsizes       []int             `json:"level_vsizes,omitempty"` // vB by level, the root first
+	NUMSNodes         int               `json:"nums_nodes,omitempty"`   // nodes cosigned by the NUMS point
+	TreeFees          int64             `json:"tree_fees,omitempty"`    // sats of the per-tx fees, with PerTxFee
+	Branches          []BranchSummary   `json:"branches"`
+}
+
+// SummaryOptions holds the settings of Report.Summary
+type SummaryOptions struct {
+	// Labels maps leaf txids to the label of their branch, e.g. from a
+	// leaves file, and may be nil
+	Labels map[string]string
+	// ExcludedBranchTxs is the number of txs left out of every branch size,
+	// such as the txs every exit broadcasts. The balance and the other
+	// statistics derived from the branch sizes still count them.
+	ExcludedBranchTxs int
+}
+
+// SharingSummary compares the total broadcast weight with sharing with the
+// total when every leaf broadcasts its branch alone, see BroadcastSharing
+type SharingSummary struct {
+	Shared float64 `json:"shared"`
+	Naive  float64 `json:"naive"`
+	Ratio  float64 `json:"ratio"`
+}
+
+// StorageSummary is the average number of bytes of tree data stored per leaf
+type StorageSummary struct {
+	Total      float64 `json:"total"`
+	NonWitness float64 `json:"non_witness"`
+	Witness    float64 `json:"witness"`
+	Metadata   float64 `json:"metadata"`
+}
+
+// ExitTimesSummary is the range of the time each user takes to exit, in seconds
+type ExitTimesSummary struct {
+	BlockInterval float64 `json:"block_interval"`
+	Min           float64 `json:"min"`
+	Median        float64 `json:"median"`
+	Max           float64 `json:"max"`
+}
+
+// SweepCostSummary is the cost of the cooperative sweep of the tree against
+// broadcasting all of it, see CooperativeSweepCost
+type SweepCostSummary struct {
+	Vsize     int     `json:"vsize"`
+	Fee       int64   `json:"fee"`
+	TreeVsize int     `json:"tree_vsize"`
+	TreeFee   int64   `json:"tree_fee"`
+	Ratio     float64 `json:"ratio"`
+}
+
+// BranchSummary is the statistics of a single branch
+type BranchSummary struct {
+	LeafTxid string  `json:"leaf_txid"`
+	Label    string  `json:"label,omitempty"`
+	Size     int     `json:"size"`
+	Weight   float64 `json:"weight"`
+}
+
+// ReportedBranchSizes returns the branch sizes less excluded txs each
+func (s *Report) ReportedBranchSizes(excluded int) []int {
+	if excluded == 0 {
+		return s.BranchSizes
+	}
+	sizes := make([]int, len(s.BranchSizes))
+	for i, size := range s.BranchSizes {
+		sizes[i] = size - excluded
+	}
+	return sizes
+}
+
+// Summary returns the aggregate statistics of the report
+func (s *Report) Summary(opts SummaryOptions) Summary {
+	branchSizes := s.ReportedBranchSizes(opts.ExcludedBranchTxs)
+	branches := make([]BranchSummary, 0, len(s.LeafTxids))
+	for i, txid := range s.LeafTxids {
+		branch := BranchSummary{
+			LeafTxid: txid,
+			Label:    opts.Labels[txid],
+			Size:     branchSizes[i],
+		}
+		if i < len(s.BranchWeights) { // partial reports may lack the last weights
+			branch.Weight = s.BranchWeights[i]
+		}
+		branches = append(branches, branch)
+	}
+
+	var vsizeWeights *Distribution
+	if len(s.VsizeWeights) > 0 {
+		weights := NewDistribution(s.VsizeWeights)
+		vsizeWeights = &weights
+	}
+
+	var sweep *SweepCostSummary
+	if cost := s.CooperativeSweep; cost.Vsize > 0 { // not computed in partial reports
+		sweep = &SweepCostSummary{
+			Vsize:     cost.Vsize,
+			Fee:       cost.Fee,
+			TreeVsize: cost.TreeVsize,
+			TreeFee:   cost.TreeFee,
+			Ratio:     cost.Ratio(),
+		}
+	}
+
+	var exitTimes *ExitTimesSummary
+	if len(s.ExitTimes) > 0 {
+		shortest, median, longest := ExitTimeRange(s.ExitTimes)
+		exitTimes = &ExitTimesSummary{
+			BlockInterval: s.BlockInterval.Seconds(),
+			Min:           shortest.Seconds(),
+			Median:        median.Seconds(),
+			Max:           longest.Seconds(),
+		}
+	}
+
+	shared, naive, ratio := BroadcastSharing(s.BranchSizes, s.BranchWeights)
+	storage := s.WireSize.PerVtxo(s.NumLeaves)
+	return Summary{
+		SchemaVersion:     SummaryVersion,
+		Leaves:            s.NumLeaves,
+		TotalTransactions: s.TotalSize,
+		BranchSizes:       NewSizeDistribution(branchSizes),
+		ExcludedBranchTxs: opts.ExcludedBranchTxs,
+		BroadcastWeights:  NewDistribution(s.BranchWeights),
+		VsizeWeights:      vsizeWeights,
+		Balance:           BalanceScore(s.BranchSizes),
+		Amortization:      Amortization(s.TotalSize, s.BranchSizes),
+		BroadcastSharing:  SharingSummary{Shared: shared, Naive: naive, Ratio: ratio},
+		BranchingFactor:   s.BranchingFactor,
+		Degenerate:        s.Degenerate(),
+		TxVersion:         s.TxVersion,
+		TxLocktime:        s.TxLocktime,
+		SizeOnWire:        s.WireSize.Total(),
+		StoragePerVtxo: StorageSummary{
+			Total:      storage.Total(),
+			NonWitness: storage.NonWitness,
+			Witness:    storage.Witness,
+			Metadata:   storage.Metadata,
+		},
+		TotalValue:       s.TotalValue,
+		ValueClamped:     s.ValueClamped,
+		CooperativeSweep: sweep,
+		MaxViableFeerate: MaxViableFeerate(s.ExitCosts),
+		Partial:          s.Partial,
+		ExitTimes:        exitTimes,
+		KeyChurn:         s.KeyChurn,
+		LevelVsizes:      s.LevelVsizes,
+		NUMSNodes:        s.CosignersPerNode.NUMSNodes,
+		TreeFees:         s.TreeFees(),
+		Branches:         branches,
+	}
+}
@@ -0,0 +1,85 @@
+package arktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestReportValidateJSONRoundTrip(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		if err := stats.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := json.Marshal(stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Report
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if err := decoded.Validate(); err != nil {
+			t.Fatalf("decoded report: %s", err)
+		}
+
+		decoded.BranchSizes[0] = decoded.Depth + 1
+		if decoded.Validate() == nil {
+			t.Error("a branch bigger than the depth is not rejected")
+		}
+	})
+}
+
+func TestDistributionCounts(t *testing.T) {
+	sizes := NewSizeDistribution([]int{2, 4, 4, 4, 4})
+	if want := map[string]int{"2": 1, "4": 4}; !maps.Equal(sizes.Counts, want) {
+		t.Errorf("branch size counts %v, expected %v", sizes.Counts, want)
+	}
+	if sizes.Max != 4 || sizes.Mean != 3.6 || sizes.Median != 4 {
+		t.Errorf("branch size distribution %+v", sizes)
+	}
+
+	weights := NewDistribution([]float64{1.2, 1.95, 1.95})
+	if want := map[string]int{"1.20": 1, "1.95": 2}; !maps.Equal(weights.Counts, want) {
+		t.Errorf("weight counts %v, expected %v", weights.Counts, want)
+	}
+}
+
+// jsonObject returns v encoded as a JSON object and decoded
+func jsonObject(t *testing.T, v any) map[string]any {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object map[string]any
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+func TestSummaryNamesTheReportMetrics(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		report, summary := jsonObject(t, stats), jsonObject(t, stats.Summary(SummaryOptions{}))
+		// the shared keys of scalars hold the same values
+		for key, value := range summary {
+			if shared, ok := report[key]; ok && key != "branch_sizes" && key != "vsize_weights" &&
+				key != "cooperative_sweep" && key != "exit_times" && fmt.Sprint(shared) != fmt.Sprint(value) {
+				t.Errorf("%s: %v in the report, %v in the summary", key, shared, value)
+			}
+		}
+		if summary["leaves"] != float64(stats.NumLeaves) || summary["total_transactions"] != float64(stats.TotalSize) {
+			t.Errorf("summary of %d leaves and %d txs: %v, %v", stats.NumLeaves, stats.TotalSize, summary["leaves"], summary["total_transactions"])
+		}
+	})
+
+	stats := &Report{NumLeaves: 2, LeafTxids: []string{"a", "b"}, BranchSizes: []int{3, 4}, BranchWeights: []float64{1.5, 2}}
+	summary := stats.Summary(SummaryOptions{Labels: map[string]string{"b": "bob"}, ExcludedBranchTxs: 1})
+	want := []BranchSummary{{LeafTxid: "a", Size: 2, Weight: 1.5}, {LeafTxid: "b", Label: "bob", Size: 3, Weight: 2}}
+	if !slices.Equal(summary.Branches, want) || summary.BranchSizes.Max != 3 || summary.ExcludedBranchTxs != 1 {
+		t.Errorf("branches %+v, branch sizes %+v", summary.Branches, summary.BranchSizes)
+	}
+}
@@ -0,0 +1,223 @@
+package arktree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// SignTree runs the MuSig2 signing round of every tx of g, the keys playing
+// every cosigner: each cosigner of a tx sends a nonce then a partial signature,
+// and their aggregation is set as its taproot key spend signature. Every
+// cosigner of the tree needs its key, the keys of no cosigner are ignored.
+// sweepTreeRoot is the taproot tweak the tree was built with.
+//
+// The signer sessions of the tree package can't be used: they always aggregate
+// the keys with MuSig2, which doesn't give back the key of a tx with a single
+// cosigner, paid to that key tweaked like any taproot key. Such a tx is signed
+// with a plain BIP340 signature instead.
+func SignTree(g *tree.TxGraph, keys []*secp256k1.PrivateKey, sweepTreeRoot []byte) error {
+	if err := CheckSweepTreeRoot(g, sweepTreeRoot); err != nil {
+		return err
+	}
+
+	// the root is cosigned by every key of the tree
+	cosigners, err := CosignerKeySet(g)
+	if err != nil {
+		return err
+	}
+	signers := make(map[string]*secp256k1.PrivateKey, len(cosigners))
+	for _, key := range keys {
+		pubkey := hex.EncodeToString(key.PubKey().SerializeCompressed())
+		if _, ok := cosigners[pubkey]; ok {
+			signers[pubkey] = key
+		}
+	}
+	missing := make([]string, 0)
+	for pubkey := range cosigners {
+		if _, ok := signers[pubkey]; !ok {
+			missing = append(missing, pubkey)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no private key for %d of the %d cosigners, first %s", len(missing), len(cosigners), missing[0])
+	}
+
+	prevouts, err := prevoutFetcher(g, sweepTreeRoot)
+	if err != nil {
+		return err
+	}
+
+	return Walk(g, func(node, _ *tree.TxGraph, _ int) error {
+		txid := node.Root.UnsignedTx.TxID()
+		message, err := sighash(node, prevouts)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", txid, err)
+		}
+
+		keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+		sig, err := signMusig2(message, keys, signers, sweepTreeRoot)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", txid, err)
+		}
+		node.Root.Inputs[0].TaprootKeySpendSig = sig.Serialize()
+		return nil
+	})
+}
+
+// signMusig2 signs message with the keys of the cosigners, tweaked with
+// sweepTreeRoot, signers being the private keys by hex compressed public key
+func signMusig2(message [32]byte, cosigners []*secp256k1.PublicKey, signers map[string]*secp256k1.PrivateKey, sweepTreeRoot []byte) (*schnorr.Signature, error) {
+	privkeys := make([]*secp256k1.PrivateKey, 0, len(cosigners))
+	for _, cosigner := range cosigners {
+		privkeys = append(privkeys, signers[hex.EncodeToString(cosigner.SerializeCompressed())])
+	}
+
+	if len(cosigners) == 1 {
+		return schnorr.Sign(txscript.TweakTaprootPrivKey(*privkeys[0], sweepTreeRoot), message[:])
+	}
+
+	nonces := make([]*musig2.Nonces, 0, len(privkeys))
+	pubNonces := make([][musig2.PubNonceSize]byte, 0, len(privkeys))
+	for _, privkey := range privkeys {
+		nonce, err := musig2.GenNonces(musig2.WithPublicKey(privkey.PubKey()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		nonces = append(nonces, nonce)
+		pubNonces = append(pubNonces, nonce.PubNonce)
+	}
+	combinedNonce, err := musig2.AggregateNonces(pubNonces)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate nonces: %w", err)
+	}
+
+	partialSigs := make([]*musig2.PartialSignature, 0, len(privkeys))
+	for i, privkey := range privkeys {
+		partialSig, err := musig2.Sign(
+			nonces[i].SecNonce, privkey, combinedNonce, cosigners, message,
+			musig2.WithSortedKeys(), musig2.WithTaprootSignTweak(sweepTreeRoot),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+
+	return musig2.CombineSigs(
+		partialSigs[0].R, partialSigs, musig2.WithTaprootTweakedCombine(message, cosigners, sweepTreeRoot, true),
+	), nil
+}
+
+// VerifyTreeSignatures checks the taproot key spend signature of every tx of g
+// against the aggregated key of its cosigners tweaked with sweepTreeRoot
+func VerifyTreeSignatures(g *tree.TxGraph, sweepTreeRoot []byte) error {
+	if err := CheckSweepTreeRoot(g, sweepTreeRoot); err != nil {
+		return err
+	}
+
+	amount, err := rootInputAmount(g)
+	if err != nil {
+		return err
+	}
+	return tree.ValidateTreeSigs(sweepTreeRoot, amount, g)
+}
+
+// CheckSweepTreeRoot checks that sweepTreeRoot is the taproot tweak of the
+// outputs of the tree: every node spends an output paying to the aggregated
+// key of its cosigners tweaked with it. A wrong root would still give
+// signatures verifying against the wrongly tweaked keys, but not spending the
+// outputs. A tree of a single tx has no output to check it against.
+func CheckSweepTreeRoot(g *tree.TxGraph, sweepTreeRoot []byte) error {
+	return Walk(g, func(node, parent *tree.TxGraph, _ int) error {
+		if parent == nil {
+			return nil
+		}
+
+		keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+		aggregateKey, err := tree.AggregateKeys(keys, sweepTreeRoot)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.Root.UnsignedTx.TxID(), err)
+		}
+		script, err := common.P2TRScript(aggregateKey.FinalKey)
+		if err != nil {
+			return err
+		}
+
+		outpoint := node.Root.UnsignedTx.TxIn[0].PreviousOutPoint
+		if !bytes.Equal(parent.Root.UnsignedTx.TxOut[outpoint.Index].PkScript, script) {
+			return fmt.Errorf("sweep tree root %x doesn't match output %s of the tree", sweepTreeRoot, outpoint)
+		}
+		return nil
+	})
+}
+
+// prevoutFetcher returns the outputs spent by the txs of g: the batch output,
+// paying to the aggregated key of the root cosigners, for the root
+func prevoutFetcher(g *tree.TxGraph, sweepTreeRoot []byte) (*txscript.MultiPrevOutFetcher, error) {
+	amount, err := rootInputAmount(g)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := tree.GetCosignerKeys(g.Root.Inputs[0])
+	if err != nil {
+		return nil, err
+	}
+	aggregateKey, err := tree.AggregateKeys(keys, sweepTreeRoot)
+	if err != nil {
+		return nil, err
+	}
+	script, err := common.P2TRScript(aggregateKey.FinalKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prevouts := txscript.NewMultiPrevOutFetcher(nil)
+	prevouts.AddPrevOut(g.Root.UnsignedTx.TxIn[0].PreviousOutPoint, wire.NewTxOut(amount, script))
+	if err := g.Apply(func(node *tree.TxGraph) (bool, error) {
+		for _, child := range node.Children {
+			outpoint := child.Root.UnsignedTx.TxIn[0].PreviousOutPoint
+			prevouts.AddPrevOut(outpoint, node.Root.UnsignedTx.TxOut[outpoint.Index])
+		}
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+	return prevouts, nil
+}
+
+// sighash is the taproot key spend sighash of the input of node
+func sighash(node *tree.TxGraph, prevouts *txscript.MultiPrevOutFetcher) ([32]byte, error) {
+	tx := node.Root.UnsignedTx
+	hash, err := txscript.CalcTaprootSignatureHash(txscript.NewTxSigHashes(tx, prevouts), txscript.SigHashDefault, tx, 0, prevouts)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return [32]byte(hash), nil
+}
+
+// rootInputAmount is the amount of the batch output spent by the root, which
+// pays its outputs without fee
+func rootInputAmount(g *tree.TxGraph) (int64, error) {
+	values := make([]int64, 0, len(g.Root.UnsignedTx.TxOut))
+	for _, out := range g.Root.UnsignedTx.TxOut {
+		values = append(values, out.Value)
+	}
+	return SumValues(values)
+}
@@ -0,0 +1,48 @@
+package arktree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSignTree(t *testing.T) {
+	for _, opts := range []GenerateOptions{
+		{NumLeaves: 1},
+		{NumLeaves: 5},
+		{NumLeaves: 5, CosignerGroups: 2},
+		{NumLeaves: 3, SharedCosigner: true},
+	} {
+		t.Run(fmt.Sprintf("%d leaves %d groups shared %t", opts.NumLeaves, opts.CosignerGroups, opts.SharedCosigner), func(t *testing.T) {
+			generation, err := Generate(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := SignTree(generation.Tree, generation.CosignerKeys, generation.SweepTreeRoot); err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyTreeSignatures(generation.Tree, generation.SweepTreeRoot); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSignTreeRejects(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignTree(generation.Tree, generation.CosignerKeys[1:], generation.SweepTreeRoot); err == nil {
+		t.Error("signed without the key of leaf 0")
+	}
+	if err := SignTree(generation.Tree, generation.CosignerKeys, make([]byte, 32)); err == nil {
+		t.Error("signed with a wrong sweep tree root")
+	}
+	if err := SignTree(generation.Tree, generation.CosignerKeys, generation.SweepTreeRoot); err != nil {
+		t.Fatal(err)
+	}
+	generation.Tree.Root.Inputs[0].TaprootKeySpendSig[0] ^= 1
+	if err := VerifyTreeSignatures(generation.Tree, generation.SweepTreeRoot); err == nil {
+		t.Error("tampered root signature verifies")
+	}
+}
@@ -0,0 +1,670 @@
+package arktree
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// LeafTxids returns the txids of the graph's leaves sorted lexicographically
+// g.Leaves() walks the children map so its order is not stable across runs,
+// every per-branch statistic is ordered by this list to be comparable between runs
+func LeafTxids(g *tree.TxGraph) []string {
+	leaves := g.Leaves()
+
+	txids := make([]string, 0, len(leaves))
+	for _, leaf := range leaves {
+		txids = append(txids, leaf.UnsignedTx.TxID())
+	}
+	sort.Strings(txids)
+
+	return txids
+}
+
+// WeightUnit is what a node counts for in the broadcast weight of a branch,
+// split between its cosigners
+type WeightUnit string
+
+const (
+	WeightByCount WeightUnit = "count" // the node is one tx, BranchWeights
+	WeightByVsize WeightUnit = "vsize" // the node is its vsize, also computing VsizeWeights
+)
+
+// AnalyzeOptions holds the settings of the statistics phase
+type AnalyzeOptions struct {
+	Workers         int
+	WithAnchors     bool
+	VerifyCosigners bool
+	Feerate         float64
+	// WeightBy adds VsizeWeights to the report if WeightByVsize, WeightByCount
+	// if empty
+	WeightBy WeightUnit
+	// BlockInterval is the time between two blocks used to estimate exit
+	// times, DefaultBlockInterval if 0
+	BlockInterval time.Duration
+	// Witness estimates the witness of the txs, DefaultWitnessModel if zero
+	Witness WitnessModel
+	// ClampValue clamps a TotalValue overflowing int64 instead of failing
+	ClampValue bool
+	// ExcludeNUMS leaves the NUMS point out of the cosigners sharing the
+	// broadcast weights, see IsNUMSKey
+	ExcludeNUMS bool
+	// PerTxFee is the fee in sats every tx pays out of the value it spends,
+	// see ApplyPerTxFee: Generate deducts it, the analysis checks that the
+	// value is conserved with it and counts it as prepaying the exits
+	PerTxFee int64
+	// MeasureAllocs adds the heap allocations of each phase to its
+	// PhaseTiming, at the cost of stopping the world twice per phase
+	MeasureAllocs bool
+}
+
+// Report holds the statistics computed on a tree. Its JSON encoding is stable
+// for importers, durations being in nanoseconds; see Validate for the
+// invariants between its fields.
+type Report struct {
+	Tree              *tree.TxGraph            `json:"-"`
+	TotalSize         int                      `json:"total_transactions"` // number of txs
+	Depth             int                      `json:"depth"`              // levels, the root being at level 1
+	BranchingFactor   float64                  `json:"branching_factor"`
+	TxVersion         int32                    `json:"tx_version"`  // of the root tx
+	TxLocktime        uint32                   `json:"tx_locktime"` // of the root tx
+	NumLeaves         int                      `json:"leaves"`
+	LeafTxids         []string                 `json:"leaf_txids"`     // branches are ordered by leaf txid
+	BranchSizes       []int                    `json:"branch_sizes"`   // txs of each branch
+	BranchWeights     []float64                `json:"branch_weights"` // txs to broadcast, shared txs counting for a fraction
+	AnchorWeights     []float64                `json:"anchor_weights,omitempty"`
+	VsizeWeights      []float64                `json:"vsize_weights,omitempty"` // vbytes to broadcast, with WeightByVsize
+	CosignersVerified bool                     `json:"cosigners_verified"`
+	CosignersPerNode  CosignerCount            `json:"cosigners_per_node"`
+	WireSize          WireSize                 `json:"wire_size"`
+	Feerate           float64                  `json:"feerate"`              // sat/vB
+	PerTxFee          int64                    `json:"per_tx_fee,omitempty"` // sats paid by every tx, see AnalyzeOptions
+	Witness           WitnessModel             `json:"witness"`
+	ExitCosts         []ExitCost               `json:"exit_costs"`
+	CooperativeSweep  SweepCost                `json:"cooperative_sweep"`
+	LevelVsizes       []int                    `json:"level_vsizes"`     // by level, the root first
+	TotalValue        int64                    `json:"total_value"`      // sats owned by the leaves
+	ValueClamped      bool                     `json:"value_clamped"`    // TotalValue overflowed and was clamped to math.MaxInt64
+	Expiry            *common.RelativeLocktime `json:"expiry,omitempty"` // of the tree, nil if it has none
+	BlockInterval     time.Duration            `json:"block_interval"`
+	ExitTimes         []time.Duration          `json:"exit_times,omitempty"` // by branch, empty without Expiry
+	KeyChurn          []LevelChurn             `json:"key_churn"`
+	Timings           []PhaseTiming            `json:"timings"`
+	Partial           bool                     `json:"partial"` // the analysis was cancelled, see AnalyzeContext
+}
+
+// PhaseTiming is the elapsed time of one phase of a run and, with
+// MeasureAllocs, the number and bytes of the heap allocations it made
+type PhaseTiming struct {
+	Name       string        `json:"name"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Allocs     uint64        `json:"allocs,omitempty"`
+	AllocBytes uint64        `json:"alloc_bytes,omitempty"`
+}
+
+// phaseMeter measures a phase from startPhase to its end
+type phaseMeter struct {
+	start  time.Time
+	allocs bool
+	mem    runtime.MemStats
+}
+
+// startPhase starts measuring a phase, its allocations too if allocs. The
+// allocation counters are read before the clock starts and after it stops,
+// so that reading them doesn't count in the elapsed time, and nothing but
+// the phase runs between the two reads.
+func startPhase(allocs bool) *phaseMeter {
+	m := &phaseMeter{allocs: allocs}
+	if allocs {
+		runtime.ReadMemStats(&m.mem)
+	}
+	m.start = time.Now()
+	return m
+}
+
+// end returns the measures of the phase name
+func (m *phaseMeter) end(name string) PhaseTiming {
+	timing := PhaseTiming{Name: name, Elapsed: time.Since(m.start)}
+	if m.allocs {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		timing.Allocs = mem.Mallocs - m.mem.Mallocs
+		timing.AllocBytes = mem.TotalAlloc - m.mem.TotalAlloc
+	}
+	return timing
+}
+
+func (s *Report) BiggestBranch() int {
+	biggest := 0
+	for _, size := range s.BranchSizes {
+		if size > biggest {
+			biggest = size
+		}
+	}
+	return biggest
+}
+
+// TreeFees returns the sats of the per-tx fees locked in the tree, those of
+// all its txs
+func (s *Report) TreeFees() int64 {
+	return s.PerTxFee * int64(s.TotalSize)
+}
+
+func (s *Report) HeaviestBranch() float64 {
+	return MaxFloat(s.BranchWeights)
+}
+
+// Analyze computes the statistics of txtree
+func Analyze(txtree *tree.TxGraph, opts AnalyzeOptions) (*Report, error) {
+	return AnalyzeContext(context.Background(), txtree, opts)
+}
+
+// AnalyzeContext computes the statistics of txtree until ctx is done. If ctx
+// is cancelled midway, it returns the statistics gathered so far with Partial
+// set: LeafTxids and BranchSizes then only cover the first branches,
+// BranchWeights may cover fewer of them, and the phases that didn't run are
+// left empty.
+func AnalyzeContext(ctx context.Context, txtree *tree.TxGraph, opts AnalyzeOptions) (*Report, error) {
+	if opts.BlockInterval < 0 {
+		return nil, fmt.Errorf("block interval must be positive, got %s", opts.BlockInterval)
+	}
+	if err := opts.Witness.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.PerTxFee < 0 {
+		return nil, fmt.Errorf("per-tx fee must not be negative, got %d", opts.PerTxFee)
+	}
+	if opts.WeightBy != "" && opts.WeightBy != WeightByCount && opts.WeightBy != WeightByVsize {
+		return nil, fmt.Errorf("unknown weight unit %q, expected %s or %s", opts.WeightBy, WeightByCount, WeightByVsize)
+	}
+
+	totalSize, err := NumberOfNodes(txtree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total size: %w", err)
+	}
+
+	branchingFactor, err := BranchingFactor(txtree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branching factor: %w", err)
+	}
+
+	leaves := LeafTxids(txtree)
+	report := &Report{
+		Tree:              txtree,
+		TotalSize:         totalSize,
+		Depth:             TreeDepth(txtree),
+		BranchingFactor:   branchingFactor,
+		TxVersion:         txtree.Root.UnsignedTx.Version,
+		TxLocktime:        txtree.Root.UnsignedTx.LockTime,
+		NumLeaves:         len(leaves),
+		LeafTxids:         leaves,
+		CosignersVerified: opts.VerifyCosigners,
+		Feerate:           opts.Feerate,
+		PerTxFee:          opts.PerTxFee,
+		BlockInterval:     opts.BlockInterval,
+		Witness:           opts.Witness,
+	}
+	if report.Witness == (WitnessModel{}) {
+		report.Witness = DefaultWitnessModel
+	}
+	if report.BlockInterval == 0 {
+		report.BlockInterval = DefaultBlockInterval
+	}
+
+	// sizes are computed before weights, so the sizes gathered so far cover at
+	// least as many branches as the weights
+	partial := func() (*Report, error) {
+		report.LeafTxids = report.LeafTxids[:len(report.BranchSizes)]
+		report.Partial = true
+		return report, nil
+	}
+
+	if opts.Workers > 1 {
+		phase := startPhase(opts.MeasureAllocs)
+		report.BranchSizes, report.BranchWeights, err = branchStatsParallel(ctx, txtree, leaves, opts.Workers, opts.ExcludeNUMS)
+		if ctx.Err() != nil {
+			return partial()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get branch statistics: %w", err)
+		}
+		report.Timings = append(report.Timings, phase.end("Branch stats (parallel)"))
+
+		report.CosignersPerNode, err = CosignersPerNode(txtree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count cosigners: %w", err)
+		}
+	} else {
+		phase := startPhase(opts.MeasureAllocs)
+		var paths []branchPath
+		paths, report.CosignersPerNode, err = branchPaths(ctx, txtree, leaves, opts.ExcludeNUMS)
+		if ctx.Err() != nil {
+			return partial()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get branch statistics: %w", err)
+		}
+		report.BranchSizes = make([]int, 0, len(paths))
+		report.BranchWeights = make([]float64, 0, len(paths))
+		for _, path := range paths {
+			report.BranchSizes = append(report.BranchSizes, path.size)
+			report.BranchWeights = append(report.BranchWeights, path.weight)
+			if opts.WithAnchors {
+				report.AnchorWeights = append(report.AnchorWeights, path.anchorWeight)
+			}
+		}
+		report.Timings = append(report.Timings, phase.end("Branch stats"))
+	}
+
+	// the serial path gets the anchor weights in the same walk
+	if opts.WithAnchors && opts.Workers > 1 {
+		report.AnchorWeights, err = weightOfBranches(ctx, txtree, leaves, true, opts.ExcludeNUMS)
+		if ctx.Err() != nil {
+			return partial()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get weight of branches with anchors: %w", err)
+		}
+	}
+
+	if opts.WeightBy == WeightByVsize {
+		report.VsizeWeights, err = vsizeWeightOfBranches(ctx, txtree, leaves, report.Witness, opts.ExcludeNUMS)
+		if ctx.Err() != nil {
+			return partial()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vsize weight of branches: %w", err)
+		}
+	}
+
+	if opts.VerifyCosigners {
+		if err := VerifyCosignerSets(txtree); err != nil {
+			return nil, fmt.Errorf("cosigner sets verification failed: %w", err)
+		}
+	}
+
+	report.Expiry, err = TreeExpiry(txtree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree expiry: %w", err)
+	}
+	if report.Expiry != nil {
+		report.ExitTimes = ExitTimes(*report.Expiry, report.BranchSizes, report.BlockInterval)
+	}
+
+	report.WireSize, err = SizeOnWire(txtree, report.Witness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size on wire: %w", err)
+	}
+	if ctx.Err() != nil {
+		return partial()
+	}
+
+	if opts.PerTxFee != 0 {
+		discrepancies, err := ValueConservationWithFee(txtree, opts.PerTxFee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check value conservation: %w", err)
+		}
+		if len(discrepancies) > 0 {
+			return nil, fmt.Errorf("%d tx(s) don't pay the per-tx fee of %d sats, first %s", len(discrepancies), opts.PerTxFee, discrepancies[0])
+		}
+	}
+	report.ExitCosts, err = exitCostOfBranches(txtree, opts.Feerate, report.Witness, opts.PerTxFee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exit cost of branches: %w", err)
+	}
+	report.TotalValue, report.ValueClamped, err = TotalValue(report.ExitCosts, opts.ClampValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total value: %w", err)
+	}
+	report.CooperativeSweep, err = CooperativeSweepCost(txtree, opts.Feerate, report.Witness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cooperative sweep cost: %w", err)
+	}
+	report.LevelVsizes, err = VsizePerLevel(txtree, report.Witness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vsize per level: %w", err)
+	}
+	if ctx.Err() != nil {
+		return partial()
+	}
+
+	report.KeyChurn, err = KeyChurn(txtree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cosigner key churn: %w", err)
+	}
+
+	return report, nil
+}
+
+// GroupWeights counts the branches by weight rounded to 2 decimal places,
+// a perfectly regular tree has a single group
+func GroupWeights(weights []float64) map[float64]int {
+	weightCount := make(map[float64]int)
+	for _, weight := range weights {
+		roundedWeight := float64(int(weight*100)) / 100 // Round to 2 decimal places
+		weightCount[roundedWeight]++
+	}
+	return weightCount
+}
+
+// SizeOfBranches returns the number of txs of every branch
+// branches are ordered by leaf txid, see LeafTxids
+func SizeOfBranches(g *tree.TxGraph) ([]int, error) {
+	paths, _, err := branchPaths(context.Background(), g, LeafTxids(g), false)
+	if err != nil {
+		return nil, err
+	}
+
+	branchSizes := make([]int, 0, len(paths))
+	for _, path := range paths {
+		branchSizes = append(branchSizes, path.size)
+	}
+	return branchSizes, nil
+}
+
+func NumberOfNodes(g *tree.TxGraph) (int, error) {
+	count := 0
+	if err := g.Apply(func(tx *tree.TxGraph) (bool, error) {
+		count++
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// TreeDepth returns the number of levels of the graph, the root being at level 1
+func TreeDepth(g *tree.TxGraph) int {
+	depth := 0
+	// the callback never fails
+	_ = Walk(g, func(_, _ *tree.TxGraph, level int) error {
+		depth = max(depth, level)
+		return nil
+	})
+	return depth
+}
+
+func CalculateAverage(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0
+	for _, value := range values {
+		sum += value
+	}
+	return float64(sum) / float64(len(values))
+}
+
+func CalculateMedian(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	// Create a copy to avoid modifying the original slice
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+
+	// Sort the values
+	for i := 0; i < len(sorted)-1; i++ {
+		for j := 0; j < len(sorted)-i-1; j++ {
+			if sorted[j] > sorted[j+1] {
+				sorted[j], sorted[j+1] = sorted[j+1], sorted[j]
+			}
+		}
+	}
+
+	// Calculate median
+	n := len(sorted)
+	if n%2 == 0 {
+		// Even number of elements - average of two middle values
+		return float64(sorted[n/2-1]+sorted[n/2]) / 2.0
+	} else {
+		// Odd number of elements - middle value
+		return float64(sorted[n/2])
+	}
+}
+
+// WeightOfBranches returns the broadcast weight of every branch
+// branches are ordered by leaf txid, see LeafTxids
+func WeightOfBranches(g *tree.TxGraph, withAnchors bool) ([]float64, error) {
+	return weightOfBranches(context.Background(), g, LeafTxids(g), withAnchors, false)
+}
+
+// weightOfBranches returns the broadcast weight of the branch of each leaf,
+// stopping with no weight when ctx is done
+func weightOfBranches(ctx context.Context, g *tree.TxGraph, leaves []string, withAnchors, excludeNUMS bool) ([]float64, error) {
+	paths, _, err := branchPaths(ctx, g, leaves, excludeNUMS)
+	if err != nil {
+		return nil, err
+	}
+
+	branchWeights := make([]float64, 0, len(paths))
+	for _, path := range paths {
+		if withAnchors {
+			branchWeights = append(branchWeights, path.anchorWeight)
+		} else {
+			branchWeights = append(branchWeights, path.weight)
+		}
+	}
+	return branchWeights, nil
+}
+
+func CalculateAverageFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func CalculateMedianFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	// Create a copy to avoid modifying the original slice
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+
+	// Sort the values
+	for i := 0; i < len(sorted)-1; i++ {
+		for j := 0; j < len(sorted)-i-1; j++ {
+			if sorted[j] > sorted[j+1] {
+				sorted[j], sorted[j+1] = sorted[j+1], sorted[j]
+			}
+		}
+	}
+
+	// Calculate median
+	n := len(sorted)
+	if n%2 == 0 {
+		// Even number of elements - average of two middle values
+		return (sorted[n/2-1] + sorted[n/2]) / 2.0
+	} else {
+		// Odd number of elements - middle value
+		return sorted[n/2]
+	}
+}
+
+// weight = the part of the tx a user has to broadcast
+// if a tx is shared by 3 cosigners, each cosigner has to broadcast 1/3 of the tx
+// withAnchors also counts the child tx spending the anchor output to pay the fees (CPFP),
+// it is shared by the cosigners the same way the parent tx is
+func ComputeBroadcastWeight(branch *tree.TxGraph, withAnchors bool) (float64, error) {
+	return computeBroadcastWeight(branch, withAnchors, false)
+}
+
+// computeBroadcastWeight is ComputeBroadcastWeight leaving the NUMS point out
+// of the cosigners with excludeNUMS, see signerCount
+func computeBroadcastWeight(branch *tree.TxGraph, withAnchors, excludeNUMS bool) (float64, error) {
+	var totalWeight float64
+	if err := branch.Apply(func(g *tree.TxGraph) (bool, error) {
+		cosignerKeys, err := tree.GetCosignerKeys(g.Root.Inputs[0])
+		if err != nil {
+			return false, err
+		}
+
+		if len(cosignerKeys) == 0 {
+			return false, fmt.Errorf("node %s has no cosigner keys", g.Root.UnsignedTx.TxID())
+		}
+
+		signers, _ := signerCount(cosignerKeys, excludeNUMS)
+		share := 1 / float64(signers)
+		totalWeight += share
+		if withAnchors && HasAnchorOutput(g.Root.UnsignedTx) {
+			totalWeight += share
+		}
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return totalWeight, nil
+}
+
+// VsizeWeightOfBranches returns the vsize weight of every branch, see
+// ComputeVsizeWeight; branches are ordered by leaf txid, see LeafTxids
+func VsizeWeightOfBranches(g *tree.TxGraph, witness WitnessModel) ([]float64, error) {
+	return vsizeWeightOfBranches(context.Background(), g, LeafTxids(g), witness, false)
+}
+
+// vsizeWeightOfBranches returns the vsize weight of the branch of each leaf,
+// stopping with the weights computed so far when ctx is done
+func vsizeWeightOfBranches(ctx context.Context, g *tree.TxGraph, leaves []string, witness WitnessModel, excludeNUMS bool) ([]float64, error) {
+	weights := make([]float64, 0, len(leaves))
+
+	for _, leaf := range leaves {
+		if err := ctx.Err(); err != nil {
+			return weights, err
+		}
+
+		branch, err := g.SubGraph([]string{leaf})
+		if err != nil {
+			return nil, err
+		}
+
+		weight, err := computeVsizeWeight(branch, witness, excludeNUMS)
+		if err != nil {
+			return nil, err
+		}
+
+		weights = append(weights, weight)
+	}
+
+	return weights, nil
+}
+
+// ComputeVsizeWeight is ComputeBroadcastWeight in vbytes: each node counts
+// for its vsize divided by its number of cosigners instead of 1/len(keys), so
+// that the txs with more inputs or outputs weigh more
+func ComputeVsizeWeight(branch *tree.TxGraph, witness WitnessModel) (float64, error) {
+	return computeVsizeWeight(branch, witness, false)
+}
+
+// computeVsizeWeight is ComputeVsizeWeight leaving the NUMS point out of the
+// cosigners with excludeNUMS, see signerCount
+func computeVsizeWeight(branch *tree.TxGraph, witness WitnessModel, excludeNUMS bool) (float64, error) {
+	var totalWeight float64
+	if err := branch.Apply(func(g *tree.TxGraph) (bool, error) {
+		cosignerKeys, err := tree.GetCosignerKeys(g.Root.Inputs[0])
+		if err != nil {
+			return false, err
+		}
+
+		if len(cosignerKeys) == 0 {
+			return false, fmt.Errorf("node %s has no cosigner keys", g.Root.UnsignedTx.TxID())
+		}
+
+		vsize, err := witness.Vsize(g.Root)
+		if err != nil {
+			return false, err
+		}
+		signers, _ := signerCount(cosignerKeys, excludeNUMS)
+		totalWeight += float64(vsize) / float64(signers)
+		return true, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return totalWeight, nil
+}
+
+// BroadcastWeightByLevel returns the broadcast weight of branch broken down by
+// level, the root level first: the share of each node, as accumulated by
+// ComputeBroadcastWeight, goes to the level of the node. It shows whether the
+// shared top of the tree or the unshared bottom dominates the exit cost.
+// excludeNUMS leaves the NUMS point out of the cosigners, see signerCount.
+func BroadcastWeightByLevel(branch *tree.TxGraph, withAnchors, excludeNUMS bool) ([]float64, error) {
+	var levels []float64
+	if err := Walk(branch, func(node, _ *tree.TxGraph, depth int) error {
+		cosignerKeys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+
+		if len(cosignerKeys) == 0 {
+			return fmt.Errorf("node %s has no cosigner keys", node.Root.UnsignedTx.TxID())
+		}
+
+		for len(levels) < depth {
+			levels = append(levels, 0)
+		}
+		signers, _ := signerCount(cosignerKeys, excludeNUMS)
+		share := 1 / float64(signers)
+		levels[depth-1] += share
+		if withAnchors && HasAnchorOutput(node.Root.UnsignedTx) {
+			levels[depth-1] += share
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func HasAnchorOutput(tx *wire.MsgTx) bool {
+	for _, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, tree.ANCHOR_PKSCRIPT) {
+			return true
+		}
+	}
+	return false
+}
+
+func MaxFloat(values []float64) float64 {
+	max := 0.0
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+// CalculateStddevFloat returns the population standard deviation of values
+func CalculateStddevFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	mean := CalculateAverageFloat(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
@@ -0,0 +1,422 @@
+package arktree
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	mathrand "math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// testLeaves are the numbers of leaves of the seeded trees of the tests,
+// small enough to build in a few seconds and covering odd and power of two sizes
+var testLeaves = []int{1, 2, 3, 7, 16, 33}
+
+var (
+	testReportsOnce sync.Once
+	testReports     []*Report
+	testReportsErr  error
+)
+
+// forEachTestTree runs fn in a subtest for the statistics of the seeded tree
+// of each of testLeaves, built once for all the tests. fn mustn't modify them.
+func forEachTestTree(t *testing.T, fn func(t *testing.T, seed int64, stats *Report)) {
+	t.Helper()
+	testReportsOnce.Do(func() {
+		for i, numLeaves := range testLeaves {
+			seed := int64(i + 1)
+			stats, err := GenerateAndAnalyze(GenerateOptions{
+				NumLeaves: numLeaves,
+				Seed:      &seed,
+				AnalyzeOptions: AnalyzeOptions{
+					Workers:         1,
+					WithAnchors:     true,
+					VerifyCosigners: true,
+					Feerate:         1,
+				},
+			})
+			if err != nil {
+				testReportsErr = fmt.Errorf("%d leaves: %w", numLeaves, err)
+				return
+			}
+			testReports = append(testReports, stats)
+		}
+	})
+	if testReportsErr != nil {
+		t.Fatal(testReportsErr)
+	}
+	for i, stats := range testReports {
+		t.Run(fmt.Sprintf("%d leaves", stats.NumLeaves), func(t *testing.T) {
+			fn(t, int64(i+1), stats)
+		})
+	}
+}
+
+// benchmarkLeaves are the numbers of leaves of the trees of the benchmarks,
+// BuildVtxoTree taking seconds from a few hundred leaves
+var benchmarkLeaves = []int{16, 64, 256}
+
+var benchmarkTrees sync.Map // number of leaves -> *tree.TxGraph
+
+// benchmarkTree returns the seeded tree of numLeaves leaves with raw scripts,
+// built once for all the benchmarks and their runs
+func benchmarkTree(b *testing.B, numLeaves int) *tree.TxGraph {
+	b.Helper()
+	if g, ok := benchmarkTrees.Load(numLeaves); ok {
+		return g.(*tree.TxGraph)
+	}
+	seed := int64(1)
+	generation, err := Generate(GenerateOptions{NumLeaves: numLeaves, RawScripts: true, Seed: &seed})
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkTrees.Store(numLeaves, generation.Tree)
+	return generation.Tree
+}
+
+// analyzeSeeded builds the seeded tree of opts and computes its statistics
+func analyzeSeeded(t testing.TB, opts GenerateOptions, seed int64) *Report {
+	t.Helper()
+	opts.Seed = &seed
+	stats, err := GenerateAndAnalyze(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func floatsClose(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBranchSizesAndWeights(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		if len(stats.BranchSizes) != stats.NumLeaves {
+			t.Errorf("%d branches for %d leaves", len(stats.BranchSizes), stats.NumLeaves)
+		}
+		if depth := TreeDepth(stats.Tree); stats.BiggestBranch() != depth {
+			t.Errorf("biggest branch %d, depth %d", stats.BiggestBranch(), depth)
+		}
+		for i, size := range stats.BranchSizes {
+			if size < 1 || size > stats.TotalSize {
+				t.Errorf("branch %d has %d txs", i, size)
+			}
+			if weight := stats.BranchWeights[i]; weight <= 0 || weight > float64(size) {
+				t.Errorf("branch %d has weight %.2f for %d txs", i, weight, size)
+			}
+			if !floatsClose(stats.AnchorWeights[i], 2*stats.BranchWeights[i]) {
+				t.Errorf("branch %d has anchor weight %.2f for weight %.2f", i, stats.AnchorWeights[i], stats.BranchWeights[i])
+			}
+		}
+	})
+}
+
+func TestBranchStatsMatchSubGraph(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		for i, leaf := range stats.LeafTxids {
+			branch, err := stats.Tree.SubGraph([]string{leaf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, err := NumberOfNodes(branch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			weight, err := ComputeBroadcastWeight(branch, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			anchorWeight, err := ComputeBroadcastWeight(branch, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the weights are summed in the same order, so they are equal to the bit
+			if size != stats.BranchSizes[i] || weight != stats.BranchWeights[i] || anchorWeight != stats.AnchorWeights[i] {
+				t.Errorf("branch of %s: %d txs weighing %v (%v with anchors), expected %d, %v and %v",
+					leaf, stats.BranchSizes[i], stats.BranchWeights[i], stats.AnchorWeights[i], size, weight, anchorWeight)
+			}
+		}
+	})
+}
+
+func TestSharedCosignerWeightIsBranchSize(t *testing.T) {
+	// The builder deduplicates cosigner keys: with a shared key every tx has a
+	// single signer, so that broadcasting a branch costs a whole tx per tx.
+	for _, numLeaves := range testLeaves {
+		for _, workers := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%d leaves %d workers", numLeaves, workers), func(t *testing.T) {
+				stats := analyzeSeeded(t, GenerateOptions{
+					NumLeaves:      numLeaves,
+					SharedCosigner: true,
+					AnalyzeOptions: AnalyzeOptions{Workers: workers},
+				}, 1)
+
+				if len(stats.BranchWeights) != len(stats.BranchSizes) {
+					t.Fatalf("%d branch weights for %d branches", len(stats.BranchWeights), len(stats.BranchSizes))
+				}
+				for i, size := range stats.BranchSizes {
+					if stats.BranchWeights[i] != float64(size) {
+						t.Errorf("branch %d: weight %v, expected its size %d", i, stats.BranchWeights[i], size)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVsizeWeightsAddUpToTreeVsize(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		weights, err := VsizeWeightOfBranches(stats.Tree, DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vsizes, err := NodeVsizes(stats.Tree, DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum float64
+		for _, weight := range weights {
+			sum += weight
+		}
+		vsize := 0
+		for _, node := range vsizes {
+			vsize += node
+		}
+		if !floatsClose(sum, float64(vsize)) {
+			t.Errorf("vsize weights add up to %.2f vB for %d vB of txs", sum, vsize)
+		}
+	})
+}
+
+func TestComputeBroadcastWeightWithoutCosigners(t *testing.T) {
+	generation, err := Generate(GenerateOptions{NumLeaves: 2, RawScripts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64, err := generation.Tree.Root.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := psbt.NewFromRawBytes(strings.NewReader(b64), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.Inputs[0].Unknowns = nil
+
+	_, err = ComputeBroadcastWeight(&tree.TxGraph{Root: node}, false)
+	if err == nil || !strings.Contains(err.Error(), node.UnsignedTx.TxID()) {
+		t.Errorf("expected an error naming %s, got %v", node.UnsignedTx.TxID(), err)
+	}
+}
+
+func TestBroadcastWeightByLevel(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		for _, leaf := range stats.LeafTxids {
+			branch, err := stats.Tree.SubGraph([]string{leaf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			weight, err := ComputeBroadcastWeight(branch, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			levels, err := BroadcastWeightByLevel(branch, true, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for _, level := range levels {
+				sum += level
+			}
+			if len(levels) != TreeDepth(branch) || !floatsClose(sum, weight) {
+				t.Errorf("branch of %s has %d levels adding up to %.2f for weight %.2f", leaf, len(levels), sum, weight)
+			}
+		}
+	})
+}
+
+// BenchmarkSubGraph measures SubGraph, which the parallel statistics call once
+// per leaf, on the leaves of each branch depth of the benchmark trees
+func BenchmarkSubGraph(b *testing.B) {
+	for _, numLeaves := range benchmarkLeaves {
+		g := benchmarkTree(b, numLeaves)
+		sizes, err := SizeOfBranches(g)
+		if err != nil {
+			b.Fatal(err)
+		}
+		// the leaves by branch depth, ordered by leaf txid as the sizes
+		byDepth := make(map[int][]string)
+		for i, leaf := range LeafTxids(g) {
+			byDepth[sizes[i]] = append(byDepth[sizes[i]], leaf)
+		}
+
+		for _, depth := range slices.Sorted(maps.Keys(byDepth)) {
+			leaves := byDepth[depth]
+			b.Run(fmt.Sprintf("leaves=%d/depth=%d", numLeaves, depth), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := g.SubGraph([]string{leaves[i%len(leaves)]}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBranchSizes compares the branch sizes, computed in a single walk
+// of the tree caching the ancestor paths, with a SubGraph call per leaf
+func BenchmarkBranchSizes(b *testing.B) {
+	for _, numLeaves := range benchmarkLeaves {
+		g := benchmarkTree(b, numLeaves)
+		leaves := LeafTxids(g)
+		b.Run(fmt.Sprintf("leaves=%d/walk", numLeaves), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := SizeOfBranches(g); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("leaves=%d/subgraph", numLeaves), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, leaf := range leaves {
+					branch, err := g.SubGraph([]string{leaf})
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := NumberOfNodes(branch); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// balancedBranches returns the size and the broadcast weight of each branch
+// of the tree of numLeaves leaves BuildVtxoTree builds, in a seeded random
+// order: the nodes of each level are paired from the leaves up, an odd one
+// out moving up a level as is, and each node is cosigned by the leaves under
+// it. Deriving them from the shape spares the benchmarks builds that would
+// take far longer than the sorts at the leaf counts where they matter.
+func balancedBranches(numLeaves int) ([]int, []float64) {
+	sizes := make([]int, numLeaves)
+	weights := make([]float64, numLeaves)
+	level := make([][]int, numLeaves) // the leaves under each node of the level
+	for i := range level {
+		level[i] = []int{i}
+		sizes[i], weights[i] = 1, 1
+	}
+	for len(level) > 1 {
+		next := make([][]int, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			leaves := append(level[i], level[i+1]...)
+			for _, leaf := range leaves {
+				sizes[leaf]++
+				weights[leaf] += 1 / float64(len(leaves))
+			}
+			next = append(next, leaves)
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+
+	rnd := mathrand.New(mathrand.NewPCG(1, 1))
+	rnd.Shuffle(numLeaves, func(i, j int) {
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+		weights[i], weights[j] = weights[j], weights[i]
+	})
+	return sizes, weights
+}
+
+// bubbleSort sorts values in place as CalculateMedian does
+func bubbleSort[T cmp.Ordered](values []T) {
+	for i := 0; i < len(values)-1; i++ {
+		for j := 0; j < len(values)-i-1; j++ {
+			if values[j] > values[j+1] {
+				values[j], values[j+1] = values[j+1], values[j]
+			}
+		}
+	}
+}
+
+func TestBalancedBranchesMatchTheTree(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		sizes, weights := balancedBranches(stats.NumLeaves)
+		bubbleSort(sizes)
+		bubbleSort(weights)
+		if want := slices.Sorted(slices.Values(stats.BranchSizes)); !slices.Equal(sizes, want) {
+			t.Errorf("sizes %v, expected %v", sizes, want)
+		}
+		for i, want := range slices.Sorted(slices.Values(stats.BranchWeights)) {
+			if !floatsClose(weights[i], want) {
+				t.Errorf("weight %d is %g, expected %g", i, weights[i], want)
+			}
+		}
+	})
+}
+
+// BenchmarkMedianSort compares the bubble sort of CalculateMedian and
+// CalculateMedianFloat with sort.Slice on the branch sizes and weights of
+// balanced trees. The bubble sort is quadratic, so it takes seconds from
+// about 100000 leaves.
+func BenchmarkMedianSort(b *testing.B) {
+	for _, numLeaves := range []int{64, 1024, 8192} {
+		sizes, weights := balancedBranches(numLeaves)
+		benchmarkSorts(b, fmt.Sprintf("leaves=%d/values=sizes", numLeaves), sizes)
+		benchmarkSorts(b, fmt.Sprintf("leaves=%d/values=weights", numLeaves), weights)
+	}
+}
+
+// benchmarkSorts runs the bubble sort and sort.Slice benchmarks of copies of
+// values under name
+func benchmarkSorts[T cmp.Ordered](b *testing.B, name string, values []T) {
+	for _, sorter := range []struct {
+		name string
+		sort func([]T)
+	}{
+		{"bubble", bubbleSort[T]},
+		{"sort.Slice", func(sorted []T) {
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		}},
+	} {
+		b.Run(name+"/sort="+sorter.name, func(b *testing.B) {
+			sorted := make([]T, len(values))
+			for i := 0; i < b.N; i++ {
+				copy(sorted, values)
+				sorter.sort(sorted)
+			}
+		})
+	}
+}
+
+func TestPhaseAllocs(t *testing.T) {
+	for _, measure := range []bool{false, true} {
+		t.Run(fmt.Sprintf("measure %t", measure), func(t *testing.T) {
+			report := analyzeSeeded(t, GenerateOptions{
+				NumLeaves:      4,
+				AnalyzeOptions: AnalyzeOptions{Workers: 1, MeasureAllocs: measure},
+			}, 1)
+			for _, timing := range report.Timings {
+				if !measure && (timing.Allocs != 0 || timing.AllocBytes != 0) {
+					t.Errorf("%s: allocations measured without MeasureAllocs", timing.Name)
+				}
+				if measure && timing.Name != "Random data init" && (timing.Allocs == 0 || timing.AllocBytes < timing.Allocs) {
+					t.Errorf("%s: %d allocations of %d bytes", timing.Name, timing.Allocs, timing.AllocBytes)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package arktree
+
+import (
+	"github.com/ark-network/ark/common"
+	"github.com/ark-network/ark/common/tree"
+)
+
+// BranchStats is the statistics of the branch of one leaf, see StreamBranches
+type BranchStats struct {
+	LeafTxid string
+	Size     int
+	// Weight is the broadcast weight of the branch, anchors excluded
+	Weight float64
+	Cost   ExitCost
+	// Expiry is the relative locktime of the topmost node of the branch
+	// carrying one, nil if none does
+	Expiry *common.RelativeLocktime
+}
+
+// StreamBranches calls fn with the statistics of each branch of g, ordered by
+// leaf txid as LeafTxids, the exit cost being at feerate (sat/vB). Each branch
+// is computed from the SubGraph of its leaf alone, so that the memory used
+// doesn't grow with the branches already visited, unlike the *OfBranches
+// functions returning them all at once. It stops at the first error, of fn
+// included.
+func StreamBranches(g *tree.TxGraph, feerate float64, witness WitnessModel, fn func(BranchStats) error) error {
+	for _, leaf := range LeafTxids(g) {
+		branch, err := g.SubGraph([]string{leaf})
+		if err != nil {
+			return err
+		}
+
+		stats := BranchStats{LeafTxid: leaf}
+		if stats.Size, err = NumberOfNodes(branch); err != nil {
+			return err
+		}
+		if stats.Weight, err = ComputeBroadcastWeight(branch, false); err != nil {
+			return err
+		}
+		if stats.Cost, err = exitCostOfBranch(branch, leaf, feerate, witness, 0); err != nil {
+			return err
+		}
+		if err := branch.Apply(func(node *tree.TxGraph) (bool, error) {
+			if len(node.Root.Inputs) == 0 {
+				return true, nil
+			}
+			expiry, err := tree.GetVtxoTreeExpiry(node.Root.Inputs[0])
+			if err != nil {
+				return false, err
+			}
+			stats.Expiry = expiry
+			return expiry == nil, nil
+		}); err != nil {
+			return err
+		}
+
+		if err := fn(stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package arktree
+
+import "testing"
+
+func TestStreamBranches(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		costs, err := ExitCostOfBranches(stats.Tree, 1, DefaultWitnessModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := 0
+		if err := StreamBranches(stats.Tree, 1, DefaultWitnessModel, func(branch BranchStats) error {
+			if branch.LeafTxid != stats.LeafTxids[i] || branch.Size != stats.BranchSizes[i] || branch.Cost != costs[i] {
+				t.Errorf("branch %d is %+v, expected leaf %s of size %d costing %+v", i, branch, stats.LeafTxids[i], stats.BranchSizes[i], costs[i])
+			}
+			if (branch.Expiry == nil) != (stats.Expiry == nil) || branch.Expiry != nil && *branch.Expiry != *stats.Expiry {
+				t.Errorf("branch %d waits %v, the tree expiry is %v", i, branch.Expiry, stats.Expiry)
+			}
+			i++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if i != stats.NumLeaves {
+			t.Errorf("%d branches streamed for %d leaves", i, stats.NumLeaves)
+		}
+	})
+}
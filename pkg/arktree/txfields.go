@@ -0,0 +1,44 @@
+package arktree
+
+import (
+	"fmt"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// Range of the tx versions accepted by TxVersion, 3 being the TRUC version
+// used by the builder
+const (
+	MinTxVersion = 1
+	MaxTxVersion = 3
+)
+
+// ValidateTxVersion checks that version is a standard tx version
+func ValidateTxVersion(version int32) error {
+	if version < MinTxVersion || version > MaxTxVersion {
+		return fmt.Errorf("tx version must be between %d and %d, got %d", MinTxVersion, MaxTxVersion, version)
+	}
+	return nil
+}
+
+// checkTxFields checks that every tx of g has the requested version and
+// locktime, nil meaning any. BuildVtxoTree sets both fields itself, so a
+// mismatch means it ignored the request.
+func checkTxFields(g *tree.TxGraph, version *int32, locktime *uint32) error {
+	if version != nil {
+		if err := ValidateTxVersion(*version); err != nil {
+			return err
+		}
+	}
+
+	return g.Apply(func(node *tree.TxGraph) (bool, error) {
+		tx := node.Root.UnsignedTx
+		if version != nil && tx.Version != *version {
+			return false, fmt.Errorf("the builder ignores the tx version: requested %d, tx %s has version %d", *version, tx.TxID(), tx.Version)
+		}
+		if locktime != nil && tx.LockTime != *locktime {
+			return false, fmt.Errorf("the builder ignores the tx locktime: requested %d, tx %s has locktime %d", *locktime, tx.TxID(), tx.LockTime)
+		}
+		return true, nil
+	})
+}
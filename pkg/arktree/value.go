@@ -0,0 +1,78 @@
+package arktree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// ErrValueOverflow is returned when a sum of values doesn't fit in an int64
+var ErrValueOverflow = errors.New("value overflows int64")
+
+// ErrUnderfunded is returned when the input funding a tree can't pay for its
+// leaves and fees
+var ErrUnderfunded = errors.New("input amount doesn't cover the tree")
+
+// SumValues returns the sum of values in sats, or ErrValueOverflow instead of
+// wrapping to a nonsensical negative total. Values are never negative in a
+// tree, a negative one is an error too.
+func SumValues(values []int64) (int64, error) {
+	total := int64(0)
+	for i, value := range values {
+		if value < 0 {
+			return 0, fmt.Errorf("value %d is negative: %d", i, value)
+		}
+		if value > math.MaxInt64-total {
+			return 0, fmt.Errorf("%w: adding value %d (%d sats) to %d sats", ErrValueOverflow, i, value, total)
+		}
+		total += value
+	}
+	return total, nil
+}
+
+// TotalValue returns the sats owned by the leaves of a tree, see ExitCost.Value.
+// With clamp, a total overflowing int64 is clamped to math.MaxInt64 and
+// reported by clamped rather than returned as an error.
+func TotalValue(costs []ExitCost, clamp bool) (total int64, clamped bool, err error) {
+	values := make([]int64, 0, len(costs))
+	for _, cost := range costs {
+		values = append(values, cost.Value)
+	}
+
+	total, err = SumValues(values)
+	if clamp && errors.Is(err, ErrValueOverflow) {
+		return math.MaxInt64, true, nil
+	}
+	return total, false, err
+}
+
+// LeavesTotal returns the sats owned by leaves, before the tree is built
+func LeavesTotal(leaves []tree.Leaf) (int64, error) {
+	values := make([]int64, 0, len(leaves))
+	for i, leaf := range leaves {
+		if leaf.Amount > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: leaf %d (%d sats)", ErrValueOverflow, i, leaf.Amount)
+		}
+		values = append(values, int64(leaf.Amount))
+	}
+	return SumValues(values)
+}
+
+// FundingHeadroom returns the sats of inputAmount left once leafTotal and
+// fees are paid, or an error wrapping ErrUnderfunded if they exceed it
+func FundingHeadroom(inputAmount, leafTotal, fees int64) (int64, error) {
+	spent, err := SumValues([]int64{leafTotal, fees})
+	if err != nil {
+		return 0, err
+	}
+	if spent > inputAmount {
+		owed := fmt.Sprintf("the leaves own %d sats", leafTotal)
+		if fees > 0 {
+			owed += fmt.Sprintf(" and the fees are %d sats", fees)
+		}
+		return 0, fmt.Errorf("%w: %s, %d sats more than the %d sats of the input", ErrUnderfunded, owed, spent-inputAmount, inputAmount)
+	}
+	return inputAmount - spent, nil
+}
@@ -0,0 +1,58 @@
+package arktree
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// leavesOfMaxMoney returns the exit costs of n leaves holding all the bitcoins
+func leavesOfMaxMoney(n int) []ExitCost {
+	costs := make([]ExitCost, n)
+	for i := range costs {
+		costs[i].Value = btcutil.MaxSatoshi
+	}
+	return costs
+}
+
+func TestTotalValue(t *testing.T) {
+	// 4392 leaves of all the bitcoins fit in an int64, 4393 don't
+	for _, test := range []struct {
+		name        string
+		leaves      int
+		clamp       bool
+		wantTotal   int64
+		wantClamped bool
+		wantErr     error
+	}{
+		{"4392 leaves are summed", 4392, false, 4392 * btcutil.MaxSatoshi, false, nil},
+		{"4393 leaves overflow", 4393, false, 0, false, ErrValueOverflow},
+		{"4393 leaves are clamped", 4393, true, math.MaxInt64, true, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			total, clamped, err := TotalValue(leavesOfMaxMoney(test.leaves), test.clamp)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error %v, expected %v", err, test.wantErr)
+			}
+			if err == nil && (total != test.wantTotal || clamped != test.wantClamped) {
+				t.Errorf("total %d (clamped %t), expected %d (%t)", total, clamped, test.wantTotal, test.wantClamped)
+			}
+		})
+	}
+}
+
+func TestFundingHeadroom(t *testing.T) {
+	if headroom, err := FundingHeadroom(1000, 600, 400); err != nil || headroom != 0 {
+		t.Errorf("exact funding: headroom %d, %v", headroom, err)
+	}
+	if _, err := FundingHeadroom(1000, 600, 401); !errors.Is(err, ErrUnderfunded) {
+		t.Errorf("got %v, expected ErrUnderfunded", err)
+	}
+	seed := int64(1)
+	_, err := Generate(GenerateOptions{NumLeaves: 4, Amount: 1000, RawScripts: true, Seed: &seed, InputAmount: 3999})
+	if !errors.Is(err, ErrUnderfunded) {
+		t.Errorf("generate: got %v, expected ErrUnderfunded before building", err)
+	}
+}
@@ -0,0 +1,46 @@
+package arktree
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+// SkipChildren is returned by a WalkFunc to skip the children of its node
+// without stopping the walk
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called by Walk on each node with its parent, nil for the root,
+// and its depth, the root being at depth 1
+type WalkFunc func(node, parent *tree.TxGraph, depth int) error
+
+// Walk calls fn on every node of g, parents before their children and siblings
+// by output index. Unlike g.Apply, fn is given the depth and the parent of the
+// node. The walk stops at the first error of fn other than SkipChildren, and
+// returns it.
+func Walk(g *tree.TxGraph, fn WalkFunc) error {
+	return walk(g, nil, 1, fn)
+}
+
+func walk(node, parent *tree.TxGraph, depth int, fn WalkFunc) error {
+	if err := fn(node, parent, depth); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+
+	indexes := make([]uint32, 0, len(node.Children))
+	for index := range node.Children {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+
+	for _, index := range indexes {
+		if err := walk(node.Children[index], node, depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
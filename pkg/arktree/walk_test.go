@@ -0,0 +1,40 @@
+package arktree
+
+import (
+	"testing"
+
+	"github.com/ark-network/ark/common/tree"
+)
+
+func TestWalk(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *Report) {
+		depths := make(map[string]int)
+		if err := Walk(stats.Tree, func(node, parent *tree.TxGraph, depth int) error {
+			if parent == nil {
+				if depth != 1 {
+					t.Errorf("root at depth %d", depth)
+				}
+			} else if parentDepth := depths[parent.Root.UnsignedTx.TxID()]; depth != parentDepth+1 {
+				t.Errorf("node at depth %d under a parent at depth %d", depth, parentDepth)
+			}
+			depths[node.Root.UnsignedTx.TxID()] = depth
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(depths) != stats.TotalSize {
+			t.Errorf("%d nodes visited, %d in the tree", len(depths), stats.TotalSize)
+		}
+
+		visited := 0
+		if err := Walk(stats.Tree, func(_, _ *tree.TxGraph, _ int) error {
+			visited++
+			return SkipChildren
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if visited != 1 {
+			t.Errorf("skipping the root's children visited %d nodes", visited)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package arktree
+
+import (
+	"github.com/ark-network/ark/common/tree"
+)
+
+const (
+	// a tree tx is spent via the taproot key path: segwit marker and flag (2),
+	// witness item count (1), signature length (1) and a 64-byte schnorr signature
+	EstimatedWitnessSize = 2 + 1 + 1 + 64
+	// metadata needed to commit to the tree structure
+	txidSize = 32
+	edgeSize = 4 + txidSize // parent output index -> child txid
+)
+
+// WireSize is the number of bytes needed to store or transmit a tree
+type WireSize struct {
+	NonWitness int `json:"non_witness"`
+	Witness    int `json:"witness"`
+	Metadata   int `json:"metadata"`
+}
+
+func (s WireSize) Total() int {
+	return s.NonWitness + s.Witness + s.Metadata
+}
+
+// PerVtxo returns the average number of bytes stored per leaf of a tree of
+// numLeaves leaves, the zero value if it has none
+func (s WireSize) PerVtxo(numLeaves int) StoragePerVtxo {
+	if numLeaves == 0 {
+		return StoragePerVtxo{}
+	}
+	n := float64(numLeaves)
+	return StoragePerVtxo{
+		NonWitness: float64(s.NonWitness) / n,
+		Witness:    float64(s.Witness) / n,
+		Metadata:   float64(s.Metadata) / n,
+	}
+}
+
+// StoragePerVtxo is the share of the wire size of a tree a server stores for
+// each of its leaves
+type StoragePerVtxo struct {
+	NonWitness float64
+	Witness    float64
+	Metadata   float64
+}
+
+func (s StoragePerVtxo) Total() float64 {
+	return s.NonWitness + s.Witness + s.Metadata
+}
+
+// SizeOnWire computes the serialized size of all the txs of the graph
+// witness bytes are estimated since the txs are not signed yet
+func SizeOnWire(g *tree.TxGraph, witness WitnessModel) (WireSize, error) {
+	var size WireSize
+	if err := g.Apply(func(tx *tree.TxGraph) (bool, error) {
+		witnessSize, err := witness.WitnessSize(tx.Root)
+		if err != nil {
+			return false, err
+		}
+		size.NonWitness += tx.Root.UnsignedTx.SerializeSizeStripped()
+		size.Witness += witnessSize
+		size.Metadata += txidSize + edgeSize*len(tx.Children)
+		return true, nil
+	}); err != nil {
+		return WireSize{}, err
+	}
+	return size, nil
+}
@@ -0,0 +1,56 @@
+package arktree
+
+import (
+	"fmt"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+)
+
+// WitnessModel estimates the witness of each input of a tree tx as Base bytes
+// plus PerCosigner bytes for each cosigner of the input, to match the sizes
+// measured for other scripts than the taproot key path
+type WitnessModel struct {
+	Base        int `json:"base"`
+	PerCosigner int `json:"per_cosigner"`
+}
+
+// DefaultWitnessModel is the taproot key path spend of the tree txs: MuSig2
+// aggregates the cosigners into a single key and signature, so the witness is
+// EstimatedWitnessSize bytes whatever the number of cosigners
+var DefaultWitnessModel = WitnessModel{Base: EstimatedWitnessSize, PerCosigner: 0}
+
+// Validate checks that the sizes of m aren't negative
+func (m WitnessModel) Validate() error {
+	if m.Base < 0 || m.PerCosigner < 0 {
+		return fmt.Errorf("witness sizes must not be negative, got %d base and %d per cosigner bytes", m.Base, m.PerCosigner)
+	}
+	return nil
+}
+
+// WitnessSize estimates the witness bytes of all the inputs of ptx
+func (m WitnessModel) WitnessSize(ptx *psbt.Packet) (int, error) {
+	size := 0
+	for i, input := range ptx.Inputs {
+		cosigners := 0
+		if m.PerCosigner > 0 {
+			keys, err := tree.GetCosignerKeys(input)
+			if err != nil {
+				return 0, fmt.Errorf("input %d: %w", i, err)
+			}
+			cosigners = len(keys)
+		}
+		size += m.Base + m.PerCosigner*cosigners
+	}
+	return size, nil
+}
+
+// Vsize estimates the virtual size of ptx once signed
+func (m WitnessModel) Vsize(ptx *psbt.Packet) (int, error) {
+	witness, err := m.WitnessSize(ptx)
+	if err != nil {
+		return 0, err
+	}
+	weight := ptx.UnsignedTx.SerializeSizeStripped()*4 + witness
+	return (weight + 3) / 4, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// leafDepths returns the depth of every leaf tx (root = 1) keyed by the hex
+// script of its non-anchor output
+func leafDepths(g *tree.TxGraph) (map[string]int, error) {
+	depths := make(map[string]int)
+
+	var walk func(node *tree.TxGraph, depth int) error
+	walk = func(node *tree.TxGraph, depth int) error {
+		if len(node.Children) == 0 {
+			for _, out := range node.Root.UnsignedTx.TxOut {
+				if bytes.Equal(out.PkScript, tree.ANCHOR_PKSCRIPT) {
+					continue
+				}
+				script := hex.EncodeToString(out.PkScript)
+				if _, ok := depths[script]; ok {
+					return fmt.Errorf("duplicate leaf script %s", script)
+				}
+				depths[script] = depth
+			}
+			return nil
+		}
+
+		for _, child := range node.Children {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(g, 1); err != nil {
+		return nil, err
+	}
+	return depths, nil
+}
+
+// leafLabels maps the txid of each leaf tx to the label of its leaf
+func leafLabels(g *tree.TxGraph, leaves []tree.Leaf, labels []string) (map[string]string, error) {
+	txids := make(map[string]string)
+	for _, leaf := range g.Leaves() {
+		for _, out := range leaf.UnsignedTx.TxOut {
+			if bytes.Equal(out.PkScript, tree.ANCHOR_PKSCRIPT) {
+				continue
+			}
+			txids[hex.EncodeToString(out.PkScript)] = leaf.UnsignedTx.TxID()
+		}
+	}
+
+	byTxid := make(map[string]string, len(leaves))
+	for i, leaf := range leaves {
+		txid, ok := txids[strings.ToLower(leaf.Script)]
+		if !ok {
+			return nil, fmt.Errorf("leaf %d not found in tree", i)
+		}
+		byTxid[txid] = labels[i]
+	}
+	return byTxid, nil
+}
+
+// weightDepthCorrelation returns the Pearson correlation between the weight of
+// each leaf and the depth of its leaf tx, a negative value means the heavier
+// leaves are placed closer to the root
+func weightDepthCorrelation(g *tree.TxGraph, leaves []tree.Leaf, weights []float64) (float64, error) {
+	depths, err := leafDepths(g)
+	if err != nil {
+		return 0, err
+	}
+
+	xs := make([]float64, 0, len(leaves))
+	ys := make([]float64, 0, len(leaves))
+	for i, leaf := range leaves {
+		depth, ok := depths[strings.ToLower(leaf.Script)]
+		if !ok {
+			return 0, fmt.Errorf("leaf %d not found in tree", i)
+		}
+		xs = append(xs, weights[i])
+		ys = append(ys, float64(depth))
+	}
+
+	return pearson(xs, ys), nil
+}
+
+// pearson returns the correlation coefficient of xs and ys, NaN if one of them
+// is constant
+func pearson(xs, ys []float64) float64 {
+	meanX := arktree.CalculateAverageFloat(xs)
+	meanY := arktree.CalculateAverageFloat(ys)
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		dy := ys[i] - meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if varX == 0 || varY == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+func printWeightPlacement(correlation float64) {
+	fmt.Println("\n⚖️  LEAF WEIGHT PLACEMENT:")
+	fmt.Println(strings.Repeat("─", 40))
+	if math.IsNaN(correlation) {
+		fmt.Println("Weight/depth correlation:      n/a (constant weights or depths)")
+	} else {
+		fmt.Printf("Weight/depth correlation: %8.2f\n", correlation)
+	}
+	fmt.Println("BuildVtxoTree ignores leaf weights, a negative correlation means heavier leaves ended up shallower")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prefixScanLeavesFiles []string
+	prefixScanSeed        int64
+)
+
+var prefixScanCmd = &cobra.Command{
+	Use:   "prefix-scan",
+	Short: "Print how the tree of a leaves file grows as its leaves are committed in order",
+	Long: `Build a tree for the first 1, 2, ... N leaves of the leaves file, in the order of the file, and print a CSV line per tree with its depth, number of txs, biggest branch, max branch weight and vsize, along with the txs and vbytes the last leaf added: the incremental cost of each VTXO committed to the round.
+
+Unlike simulate-growth, which generates its leaves, the leaves are the real ones of the file. All the trees spend the same root outpoint with the same sweep script, derived from --seed, so that they only differ by their leaves. Each line is written as soon as its tree is built.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(prefixScanLeavesFiles) == 0 {
+			fmt.Printf("Error: --leaves-file is required\n")
+			os.Exit(1)
+		}
+		if err := leafFields.validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		set, err := loadLeavesFiles(prefixScanLeavesFiles, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: Failed to load leaves: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := writePrefixScan(os.Stdout, set.leaves, prefixScanSeed, witnessModel()); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	prefixScanCmd.Flags().StringArrayVar(&prefixScanLeavesFiles, "leaves-file", nil, "JSON file of the leaves in the order they are committed (\"-\" reads stdin), repeat to concatenate the leaves of several files in the order given")
+	prefixScanCmd.Flags().Int64Var(&prefixScanSeed, "seed", 1, "Seed of the root outpoint and sweep script shared by all the trees")
+	addWitnessFlags(prefixScanCmd)
+	addLeafFieldFlags(prefixScanCmd)
+
+	rootCmd.AddCommand(prefixScanCmd)
+}
+
+// prefixStep is the tree of the first leaves of a prefix-scan
+type prefixStep struct {
+	leaves, depth, nodes, biggestBranch int
+	maxBranchWeight                     float64
+	vsize                               int
+}
+
+// scanPrefixes builds the tree of each prefix of leaves with seed, the
+// shortest first, and calls fn with its measures
+func scanPrefixes(leaves []tree.Leaf, seed int64, witness arktree.WitnessModel, fn func(prefixStep) error) error {
+	for count := 1; count <= len(leaves); count++ {
+		generation, err := arktree.Generate(arktree.GenerateOptions{
+			Leaves: leaves[:count],
+			Seed:   &seed,
+		})
+		if err != nil {
+			return fmt.Errorf("tree of the first %d leaves: %w", count, err)
+		}
+		sizes, weights, err := arktree.BranchStatsParallel(generation.Tree, 1)
+		if err != nil {
+			return fmt.Errorf("tree of the first %d leaves: %w", count, err)
+		}
+		vsize, err := arktree.TreeVsize(generation.Tree, witness)
+		if err != nil {
+			return fmt.Errorf("tree of the first %d leaves: %w", count, err)
+		}
+
+		nodes, err := arktree.NumberOfNodes(generation.Tree)
+		if err != nil {
+			return fmt.Errorf("tree of the first %d leaves: %w", count, err)
+		}
+		biggest := 0
+		for _, size := range sizes {
+			biggest = max(biggest, size)
+		}
+		if err := fn(prefixStep{
+			leaves:          count,
+			depth:           arktree.TreeDepth(generation.Tree),
+			nodes:           nodes,
+			biggestBranch:   biggest,
+			maxBranchWeight: arktree.MaxFloat(weights),
+			vsize:           vsize,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePrefixScan writes the trees of the prefixes of leaves as CSV, a line
+// per prefix flushed once its tree is built, with the txs and vbytes added
+// since the previous prefix
+func writePrefixScan(w io.Writer, leaves []tree.Leaf, seed int64, witness arktree.WitnessModel) error {
+	cw := csv.NewWriter(w)
+	writeRow := func(record []string) error {
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if err := writeRow([]string{"leaves", "depth", "nodes", "added_nodes", "max_branch_size", "max_branch_weight", "vsize", "added_vsize"}); err != nil {
+		return err
+	}
+	var previous prefixStep
+	return scanPrefixes(leaves, seed, witness, func(step prefixStep) error {
+		defer func() { previous = step }()
+		return writeRow([]string{
+			strconv.Itoa(step.leaves),
+			strconv.Itoa(step.depth),
+			strconv.Itoa(step.nodes),
+			strconv.Itoa(step.nodes - previous.nodes),
+			strconv.Itoa(step.biggestBranch),
+			strconv.FormatFloat(step.maxBranchWeight, 'f', 4, 64),
+			strconv.Itoa(step.vsize),
+			strconv.Itoa(step.vsize - previous.vsize),
+		})
+	})
+}
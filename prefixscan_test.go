@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestScanPrefixes(t *testing.T) {
+	const numLeaves, seed = 7, 1
+	generation, stats := seededTree(t, numLeaves, seed), seededReport(t, numLeaves, seed)
+	var last prefixStep
+	if err := scanPrefixes(generation.Leaves, seed, arktree.DefaultWitnessModel, func(step prefixStep) error {
+		if step.leaves != last.leaves+1 || step.nodes <= last.nodes {
+			t.Errorf("%d leaves with %d txs after %d leaves with %d txs", step.leaves, step.nodes, last.leaves, last.nodes)
+		}
+		last = step
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the last prefix is the tree of all the leaves
+	if last.leaves != stats.NumLeaves || last.nodes != stats.TotalSize || last.depth != stats.Depth || last.biggestBranch != stats.BiggestBranch() {
+		t.Errorf("last prefix %+v, expected %d leaves, %d txs, depth %d", last, stats.NumLeaves, stats.TotalSize, stats.Depth)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// Wire types of the protobuf encoding
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// protoEncoder encodes the messages of proto/stats.proto. It only covers the
+// few field types used there, the protobuf module only being needed by the
+// tests decoding it. As in proto3, scalar fields holding their zero value are
+// omitted.
+type protoEncoder struct {
+	buf []byte
+}
+
+func (e *protoEncoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *protoEncoder) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// int encodes an int32 or int64 field, negative values taking 10 bytes
+func (e *protoEncoder) int(field int, v int64) {
+	e.uint(field, uint64(v))
+}
+
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *protoEncoder) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *protoEncoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.bytes(field, []byte(s))
+}
+
+// message encodes the fields written by encode as an embedded message
+func (e *protoEncoder) message(field int, encode func(*protoEncoder)) {
+	var sub protoEncoder
+	encode(&sub)
+	e.bytes(field, sub.buf)
+}
+
+// packedUints encodes a repeated uint32 or uint64 field, packed as proto3
+// does by default
+func (e *protoEncoder) packedUints(field int, values []int) {
+	if len(values) == 0 {
+		return
+	}
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, uint64(v))
+	}
+	e.bytes(field, packed)
+}
+
+// packedDoubles encodes a repeated double field, packed
+func (e *protoEncoder) packedDoubles(field int, values []float64) {
+	if len(values) == 0 {
+		return
+	}
+	packed := make([]byte, 0, 8*len(values))
+	for _, v := range values {
+		packed = binary.LittleEndian.AppendUint64(packed, math.Float64bits(v))
+	}
+	e.bytes(field, packed)
+}
+
+func (e *protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, protoBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// stringUintMap encodes a map<string, uint32> field, keys sorted for a
+// deterministic output
+func (e *protoEncoder) stringUintMap(field int, m map[string]int) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		e.message(field, func(entry *protoEncoder) {
+			entry.string(1, key)
+			entry.uint(2, uint64(m[key]))
+		})
+	}
+}
+
+// distributionMessage returns the encoder of d as a Distribution message
+func distributionMessage(d arktree.Distribution) func(*protoEncoder) {
+	return func(e *protoEncoder) {
+		e.double(1, d.Max)
+		e.double(2, d.Mean)
+		e.double(3, d.Median)
+		e.double(4, d.Stddev)
+		e.stringUintMap(5, d.Counts)
+	}
+}
+
+// encode encodes report as the Stats message of proto/stats.proto, the
+// branches only if withBranches
+func (r statsReport) encode(e *protoEncoder, withBranches bool) {
+	e.uint(1, uint64(r.SchemaVersion))
+	e.uint(2, uint64(r.Leaves))
+	e.uint(3, uint64(r.ExcludedLeaves))
+	e.uint(4, uint64(r.TotalTransactions))
+	e.message(5, distributionMessage(r.BranchSizes))
+	e.message(6, distributionMessage(r.BroadcastWeights))
+	e.double(7, r.Balance)
+	e.double(8, r.Amortization)
+	e.message(9, func(e *protoEncoder) {
+		e.double(1, r.BroadcastSharing.Shared)
+		e.double(2, r.BroadcastSharing.Naive)
+		e.double(3, r.BroadcastSharing.Ratio)
+	})
+	e.double(10, r.BranchingFactor)
+	e.bool(11, r.Degenerate)
+	e.int(12, int64(r.TxVersion))
+	e.uint(13, uint64(r.TxLocktime))
+	e.uint(14, uint64(r.SizeOnWire))
+	e.string(15, r.Checksum)
+	e.bool(16, r.Partial)
+	if r.ExitTimes != nil {
+		e.message(17, func(e *protoEncoder) {
+			e.double(1, r.ExitTimes.BlockInterval)
+			e.double(2, r.ExitTimes.Min)
+			e.double(3, r.ExitTimes.Median)
+			e.double(4, r.ExitTimes.Max)
+		})
+	}
+	for _, churn := range r.KeyChurn {
+		e.message(18, func(e *protoEncoder) {
+			e.uint(1, uint64(churn.Level))
+			e.uint(2, uint64(churn.Parents))
+			e.uint(3, uint64(churn.KeysGained))
+			e.uint(4, uint64(churn.NewKeys))
+		})
+	}
+	if withBranches {
+		for _, branch := range r.Branches {
+			e.message(19, func(e *protoEncoder) {
+				e.string(1, branch.LeafTxid)
+				e.string(2, branch.Label)
+				e.uint(3, uint64(branch.Size))
+				e.double(4, branch.Weight)
+			})
+		}
+	}
+	e.stringUintMap(20, r.NodeSizes)
+	e.message(21, func(e *protoEncoder) {
+		e.double(1, r.StoragePerVtxo.Total)
+		e.double(2, r.StoragePerVtxo.NonWitness)
+		e.double(3, r.StoragePerVtxo.Witness)
+		e.double(4, r.StoragePerVtxo.Metadata)
+	})
+	if r.WorstCase != nil {
+		e.message(22, func(e *protoEncoder) {
+			e.uint(1, uint64(r.WorstCase.BiggestBranch))
+			e.uint(2, uint64(r.WorstCase.BestPossible))
+			e.uint(3, uint64(r.WorstCase.TheoreticalMax))
+			e.double(4, r.WorstCase.Closeness)
+		})
+	}
+	e.int(23, r.TotalValue)
+	e.bool(24, r.ValueClamped)
+	e.uint(25, uint64(r.ExcludedBranchTxs))
+	e.double(26, r.MaxViableFeerate)
+	if sweep := r.CooperativeSweep; sweep != nil {
+		e.message(27, func(e *protoEncoder) {
+			e.uint(1, uint64(sweep.Vsize))
+			e.int(2, sweep.Fee)
+			e.uint(3, uint64(sweep.TreeVsize))
+			e.int(4, sweep.TreeFee)
+			e.double(5, sweep.Ratio)
+		})
+	}
+	e.int(28, r.TreeFees)
+	e.uint(29, uint64(r.NUMSNodes))
+	e.packedUints(30, r.LevelVsizes)
+	if r.VsizeWeights != nil {
+		e.message(31, distributionMessage(*r.VsizeWeights))
+	}
+	e.string(32, r.SweepTreeRoot)
+	if cosigners := r.CosignersPerNode; cosigners != nil {
+		e.message(33, func(e *protoEncoder) {
+			e.uint(1, uint64(cosigners.Min))
+			e.double(2, cosigners.Mean)
+			e.uint(3, uint64(cosigners.Max))
+			e.uint(4, uint64(cosigners.NUMSNodes))
+		})
+	}
+	e.stringUintMap(34, r.Arity)
+	if raw := r.RawArrays; raw != nil {
+		e.message(35, func(e *protoEncoder) {
+			for _, txid := range raw.LeafTxids {
+				e.bytes(1, []byte(txid))
+			}
+			e.packedUints(2, raw.BranchSizes)
+			e.packedDoubles(3, raw.BranchWeights)
+		})
+	}
+}
+
+// writeProtobuf writes report as a Stats message prefixed by its length
+func writeProtobuf(w io.Writer, report statsReport, withBranches bool) error {
+	var e protoEncoder
+	report.encode(&e, withBranches)
+
+	_, err := w.Write(append(binary.AppendUvarint(nil, uint64(len(e.buf))), e.buf...))
+	return err
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoMessage is a decoded message: the values of each field in order,
+// uint64 for varints, float64 for doubles and []byte for the others
+type protoMessage map[protowire.Number][]any
+
+func decodeProto(t *testing.T, b []byte) protoMessage {
+	t.Helper()
+	m := make(protoMessage)
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %s", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var value any
+		switch wireType {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			value = v
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(v)
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			value = v
+		default:
+			t.Fatalf("field %d: unexpected wire type %d", number, wireType)
+		}
+		if n < 0 {
+			t.Fatalf("field %d: %s", number, protowire.ParseError(n))
+		}
+		b = b[n:]
+		m[number] = append(m[number], value)
+	}
+	return m
+}
+
+// check compares the single value of field with want, proto3 omitting the
+// fields holding their zero value
+func (m protoMessage) check(t *testing.T, path string, field protowire.Number, want any) {
+	t.Helper()
+	values := m[field]
+	switch want := want.(type) {
+	case string:
+		if want == "" && len(values) == 0 || len(values) == 1 && string(values[0].([]byte)) == want {
+			return
+		}
+	default:
+		if isZero(want) && len(values) == 0 || len(values) == 1 && values[0] == want {
+			return
+		}
+	}
+	t.Errorf("%s.%d: %v, expected %v", path, field, values, want)
+}
+
+func isZero(v any) bool {
+	return v == uint64(0) || v == float64(0)
+}
+
+// message decodes the embedded message of field, nil if absent
+func (m protoMessage) message(t *testing.T, path string, field protowire.Number) protoMessage {
+	t.Helper()
+	switch values := m[field]; len(values) {
+	case 0:
+		return nil
+	case 1:
+		return decodeProto(t, values[0].([]byte))
+	default:
+		t.Fatalf("%s.%d: %d messages, expected one", path, field, len(values))
+		return nil
+	}
+}
+
+// messages decodes the embedded messages of the repeated field
+func (m protoMessage) messages(t *testing.T, field protowire.Number) []protoMessage {
+	t.Helper()
+	var messages []protoMessage
+	for _, value := range m[field] {
+		messages = append(messages, decodeProto(t, value.([]byte)))
+	}
+	return messages
+}
+
+// stringUintMap decodes the map<string, uint32> field
+func (m protoMessage) stringUintMap(t *testing.T, field protowire.Number) map[string]int {
+	t.Helper()
+	decoded := make(map[string]int)
+	for _, entry := range m.messages(t, field) {
+		var value uint64
+		if values := entry[2]; len(values) == 1 {
+			value = values[0].(uint64)
+		}
+		decoded[string(entry[1][0].([]byte))] = int(value)
+	}
+	return decoded
+}
+
+// packedVarints decodes the packed repeated varint field
+func (m protoMessage) packedVarints(t *testing.T, field protowire.Number) []int {
+	t.Helper()
+	var decoded []int
+	for _, value := range m[field] {
+		b := value.([]byte)
+		for len(b) > 0 {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				t.Fatalf("field %d: %s", field, protowire.ParseError(n))
+			}
+			decoded = append(decoded, int(v))
+			b = b[n:]
+		}
+	}
+	return decoded
+}
+
+// packedDoubles decodes the packed repeated double field
+func (m protoMessage) packedDoubles(t *testing.T, field protowire.Number) []float64 {
+	t.Helper()
+	var decoded []float64
+	for _, value := range m[field] {
+		b := value.([]byte)
+		for len(b) > 0 {
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				t.Fatalf("field %d: %s", field, protowire.ParseError(n))
+			}
+			decoded = append(decoded, math.Float64frombits(v))
+			b = b[n:]
+		}
+	}
+	return decoded
+}
+
+func checkDistribution(t *testing.T, path string, m protoMessage, d arktree.Distribution) {
+	t.Helper()
+	if m == nil {
+		t.Fatalf("%s missing", path)
+	}
+	m.check(t, path, 1, d.Max)
+	m.check(t, path, 2, d.Mean)
+	m.check(t, path, 3, d.Median)
+	m.check(t, path, 4, d.Stddev)
+	if counts := m.stringUintMap(t, 5); !maps.Equal(counts, d.Counts) {
+		t.Errorf("%s.counts: %v, expected %v", path, counts, d.Counts)
+	}
+}
+
+func TestWriteProtobuf(t *testing.T) {
+	seed := int64(5)
+	stats, err := arktree.GenerateAndAnalyze(arktree.GenerateOptions{
+		NumLeaves: 7,
+		Seed:      &seed,
+		AnalyzeOptions: arktree.AnalyzeOptions{
+			Workers:  1,
+			WeightBy: arktree.WeightByVsize,
+			Feerate:  2,
+			PerTxFee: 10,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every optional field is set, so that the encoding of all of them is checked
+	report := newStatsReport(stats, map[string]string{stats.LeafTxids[0]: "alice"})
+	report.ExcludedLeaves = 2
+	report.Checksum = treeChecksum(stats.Tree)
+	report.SweepTreeRoot = strings.Repeat("ab", 32)
+	report.WorstCase = newWorstCaseReport(stats)
+	report.CosignersPerNode = &stats.CosignersPerNode
+	report.Arity = arityJSON(arktree.ComputeFanOut(stats.Tree).Arity)
+	report.RawArrays = newRawArraysReport(stats)
+	if report.NodeSizes, err = arktree.NodeVsizes(stats.Tree, stats.Witness); err != nil {
+		t.Fatal(err)
+	}
+	if report.VsizeWeights == nil || report.ExitTimes == nil || report.CooperativeSweep == nil || report.TreeFees == 0 {
+		t.Fatal("the report lacks optional fields")
+	}
+
+	var b bytes.Buffer
+	if err := writeProtobuf(&b, report, true); err != nil {
+		t.Fatal(err)
+	}
+	length, n := protowire.ConsumeVarint(b.Bytes())
+	if n < 0 || int(length) != b.Len()-n {
+		t.Fatalf("length prefix %d for %d bytes", length, b.Len()-n)
+	}
+	m := decodeProto(t, b.Bytes()[n:])
+
+	m.check(t, "Stats", 1, uint64(report.SchemaVersion))
+	m.check(t, "Stats", 2, uint64(report.Leaves))
+	m.check(t, "Stats", 3, uint64(report.ExcludedLeaves))
+	m.check(t, "Stats", 4, uint64(report.TotalTransactions))
+	checkDistribution(t, "branch_sizes", m.message(t, "Stats", 5), report.BranchSizes)
+	checkDistribution(t, "broadcast_weights", m.message(t, "Stats", 6), report.BroadcastWeights)
+	m.check(t, "Stats", 7, report.Balance)
+	m.check(t, "Stats", 8, report.Amortization)
+	sharing := m.message(t, "Stats", 9)
+	sharing.check(t, "broadcast_sharing", 1, report.BroadcastSharing.Shared)
+	sharing.check(t, "broadcast_sharing", 2, report.BroadcastSharing.Naive)
+	sharing.check(t, "broadcast_sharing", 3, report.BroadcastSharing.Ratio)
+	m.check(t, "Stats", 10, report.BranchingFactor)
+	m.check(t, "Stats", 11, boolVarint(report.Degenerate))
+	m.check(t, "Stats", 12, uint64(report.TxVersion))
+	m.check(t, "Stats", 13, uint64(report.TxLocktime))
+	m.check(t, "Stats", 14, uint64(report.SizeOnWire))
+	m.check(t, "Stats", 15, report.Checksum)
+	m.check(t, "Stats", 16, boolVarint(report.Partial))
+	exitTimes := m.message(t, "Stats", 17)
+	exitTimes.check(t, "exit_times", 1, report.ExitTimes.BlockInterval)
+	exitTimes.check(t, "exit_times", 2, report.ExitTimes.Min)
+	exitTimes.check(t, "exit_times", 3, report.ExitTimes.Median)
+	exitTimes.check(t, "exit_times", 4, report.ExitTimes.Max)
+
+	churns := m.messages(t, 18)
+	if len(churns) != len(report.KeyChurn) {
+		t.Errorf("%d key churns, expected %d", len(churns), len(report.KeyChurn))
+	}
+	for i, churn := range churns[:min(len(churns), len(report.KeyChurn))] {
+		want := report.KeyChurn[i]
+		churn.check(t, "key_churn", 1, uint64(want.Level))
+		churn.check(t, "key_churn", 2, uint64(want.Parents))
+		churn.check(t, "key_churn", 3, uint64(want.KeysGained))
+		churn.check(t, "key_churn", 4, uint64(want.NewKeys))
+	}
+
+	branches := m.messages(t, 19)
+	if len(branches) != len(report.Branches) {
+		t.Fatalf("%d branches, expected %d", len(branches), len(report.Branches))
+	}
+	for i, branch := range branches {
+		want := report.Branches[i]
+		branch.check(t, "branches", 1, want.LeafTxid)
+		branch.check(t, "branches", 2, want.Label)
+		branch.check(t, "branches", 3, uint64(want.Size))
+		branch.check(t, "branches", 4, want.Weight)
+	}
+
+	if nodeSizes := m.stringUintMap(t, 20); !maps.Equal(nodeSizes, report.NodeSizes) {
+		t.Errorf("node_sizes %v, expected %v", nodeSizes, report.NodeSizes)
+	}
+	storage := m.message(t, "Stats", 21)
+	storage.check(t, "storage_per_vtxo", 1, report.StoragePerVtxo.Total)
+	storage.check(t, "storage_per_vtxo", 2, report.StoragePerVtxo.NonWitness)
+	storage.check(t, "storage_per_vtxo", 3, report.StoragePerVtxo.Witness)
+	storage.check(t, "storage_per_vtxo", 4, report.StoragePerVtxo.Metadata)
+	worstCase := m.message(t, "Stats", 22)
+	worstCase.check(t, "worst_case", 1, uint64(report.WorstCase.BiggestBranch))
+	worstCase.check(t, "worst_case", 2, uint64(report.WorstCase.BestPossible))
+	worstCase.check(t, "worst_case", 3, uint64(report.WorstCase.TheoreticalMax))
+	worstCase.check(t, "worst_case", 4, report.WorstCase.Closeness)
+	m.check(t, "Stats", 23, uint64(report.TotalValue))
+	m.check(t, "Stats", 24, boolVarint(report.ValueClamped))
+	m.check(t, "Stats", 25, uint64(report.ExcludedBranchTxs))
+	m.check(t, "Stats", 26, report.MaxViableFeerate)
+	sweep := m.message(t, "Stats", 27)
+	sweep.check(t, "cooperative_sweep", 1, uint64(report.CooperativeSweep.Vsize))
+	sweep.check(t, "cooperative_sweep", 2, uint64(report.CooperativeSweep.Fee))
+	sweep.check(t, "cooperative_sweep", 3, uint64(report.CooperativeSweep.TreeVsize))
+	sweep.check(t, "cooperative_sweep", 4, uint64(report.CooperativeSweep.TreeFee))
+	sweep.check(t, "cooperative_sweep", 5, report.CooperativeSweep.Ratio)
+	m.check(t, "Stats", 28, uint64(report.TreeFees))
+	m.check(t, "Stats", 29, uint64(report.NUMSNodes))
+	if levels := m.packedVarints(t, 30); !slices.Equal(levels, report.LevelVsizes) {
+		t.Errorf("level_vsizes %v, expected %v", levels, report.LevelVsizes)
+	}
+	checkDistribution(t, "vsize_weights", m.message(t, "Stats", 31), *report.VsizeWeights)
+	m.check(t, "Stats", 32, report.SweepTreeRoot)
+	cosigners := m.message(t, "Stats", 33)
+	cosigners.check(t, "cosigners_per_node", 1, uint64(report.CosignersPerNode.Min))
+	cosigners.check(t, "cosigners_per_node", 2, report.CosignersPerNode.Mean)
+	cosigners.check(t, "cosigners_per_node", 3, uint64(report.CosignersPerNode.Max))
+	cosigners.check(t, "cosigners_per_node", 4, uint64(report.CosignersPerNode.NUMSNodes))
+	if arity := m.stringUintMap(t, 34); !maps.Equal(arity, report.Arity) {
+		t.Errorf("arity %v, expected %v", arity, report.Arity)
+	}
+	raw := m.message(t, "Stats", 35)
+	var txids []string
+	for _, txid := range raw[1] {
+		txids = append(txids, string(txid.([]byte)))
+	}
+	if !slices.Equal(txids, report.RawArrays.LeafTxids) || !slices.Equal(raw.packedVarints(t, 2), report.RawArrays.BranchSizes) ||
+		!slices.Equal(raw.packedDoubles(t, 3), report.RawArrays.BranchWeights) {
+		t.Errorf("raw_arrays %v", raw)
+	}
+
+	for number := range m {
+		if number > 35 {
+			t.Errorf("unexpected field %d", number)
+		}
+	}
+
+	// the branches are left out without --branch-details
+	b.Reset()
+	if err := writeProtobuf(&b, report, false); err != nil {
+		t.Fatal(err)
+	}
+	_, n = protowire.ConsumeVarint(b.Bytes())
+	if branches := decodeProto(t, b.Bytes()[n:])[19]; len(branches) != 0 {
+		t.Errorf("%d branches without --branch-details", len(branches))
+	}
+}
+
+func boolVarint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// buildParams are the generate flags a tree was built with, recorded in the
+// manifest of its export so that it can be rebuilt
+type buildParams struct {
+	Leaves         int    `json:"leaves"`
+	Seed           *int64 `json:"seed,omitempty"` // unseeded trees can't be rebuilt identically
+	Amount         uint64 `json:"amount"`
+	AmountSamples  string `json:"amount_samples,omitempty"` // file the amounts were drawn from
+	LocktimeType   string `json:"locktime_type"`
+	LocktimeValue  uint32 `json:"locktime_value"` // after rounding
+	SharedCosigner bool   `json:"shared_cosigner,omitempty"`
+	CosignerGroups int    `json:"cosigner_groups,omitempty"`
+	CosignerSeed   string `json:"cosigner_seed,omitempty"` // hex
+	RawScripts     bool   `json:"raw_scripts,omitempty"`
+	SweepTreeRoot  string `json:"sweep_tree_root,omitempty"` // hex, if given rather than random
+	PerTxFee       int64  `json:"per_tx_fee,omitempty"`      // sats
+}
+
+// generateOptions returns the options building a tree with p
+func (p buildParams) generateOptions() (arktree.GenerateOptions, error) {
+	locktime, _, err := parseLocktime(p.LocktimeType, p.LocktimeValue, false)
+	if err != nil {
+		return arktree.GenerateOptions{}, err
+	}
+
+	var cosignerSeed []byte
+	if p.CosignerSeed != "" {
+		cosignerSeed, err = hex.DecodeString(p.CosignerSeed)
+		if err != nil {
+			return arktree.GenerateOptions{}, fmt.Errorf("invalid cosigner seed: %w", err)
+		}
+	}
+
+	var sweepTreeRoot []byte
+	if p.SweepTreeRoot != "" {
+		sweepTreeRoot, err = parseSweepRoot(p.SweepTreeRoot)
+		if err != nil {
+			return arktree.GenerateOptions{}, err
+		}
+	}
+
+	var samples []uint64
+	if p.AmountSamples != "" {
+		samples, err = loadAmountSamples(p.AmountSamples)
+		if err != nil {
+			return arktree.GenerateOptions{}, fmt.Errorf("failed to load amount samples: %w", err)
+		}
+	}
+
+	return arktree.GenerateOptions{
+		NumLeaves:      p.Leaves,
+		Amount:         p.Amount,
+		AmountSamples:  samples,
+		SharedCosigner: p.SharedCosigner,
+		CosignerGroups: p.CosignerGroups,
+		CosignerSeed:   cosignerSeed,
+		RawScripts:     p.RawScripts,
+		SweepTreeRoot:  sweepTreeRoot,
+		Locktime:       &locktime,
+		Seed:           p.Seed,
+		AnalyzeOptions: arktree.AnalyzeOptions{PerTxFee: p.PerTxFee},
+	}, nil
+}
+
+// loadBuildParams reads the build parameters of an exported tree, or of a
+// manifest saved on its own
+func loadBuildParams(path string) (*buildParams, error) {
+	r, closeFile, err := openExport(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	var file struct {
+		Manifest *exportManifest `json:"manifest"`
+		Build    *buildParams    `json:"build"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	build := file.Build
+	if file.Manifest != nil {
+		build = file.Manifest.Build
+	}
+	if build == nil {
+		return nil, fmt.Errorf("no build parameters, the tree was built from a leaves file or exported by an older version")
+	}
+	return build, nil
+}
+
+var rebuildOverrides buildParams
+
+// overrideField sets *field to value if flag was given, recording the change in overridden
+func overrideField[T any](flags *pflag.FlagSet, flag string, field *T, value T, overridden *[]string) {
+	if !flags.Changed(flag) {
+		return
+	}
+	*overridden = append(*overridden, fmt.Sprintf("%s: %v → %v", flag, *field, value))
+	*field = value
+}
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [tree-or-manifest-file]",
+	Short: "Rebuild a tree from the parameters of its manifest",
+	Long: `Read the build parameters recorded in the manifest of a tree exported with "generate --out" and build the tree again, then print its statistics.
+
+Each generate flag given overrides the matching manifest field and the others are inherited, which makes parameter sweeps around a known tree easy. Trees generated without --seed rebuild to a different tree with the same parameters.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		build, err := loadBuildParams(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to load build parameters: %s\n", err)
+			os.Exit(1)
+		}
+
+		var overridden []string
+		flags := cmd.Flags()
+		overrideField(flags, "leaves", &build.Leaves, rebuildOverrides.Leaves, &overridden)
+		overrideField(flags, "amount", &build.Amount, rebuildOverrides.Amount, &overridden)
+		overrideField(flags, "amount-samples", &build.AmountSamples, rebuildOverrides.AmountSamples, &overridden)
+		overrideField(flags, "locktime-type", &build.LocktimeType, rebuildOverrides.LocktimeType, &overridden)
+		overrideField(flags, "locktime-value", &build.LocktimeValue, rebuildOverrides.LocktimeValue, &overridden)
+		overrideField(flags, "shared-cosigner", &build.SharedCosigner, rebuildOverrides.SharedCosigner, &overridden)
+		overrideField(flags, "cosigner-groups", &build.CosignerGroups, rebuildOverrides.CosignerGroups, &overridden)
+		overrideField(flags, "raw-scripts", &build.RawScripts, rebuildOverrides.RawScripts, &overridden)
+		overrideField(flags, "sweep-root", &build.SweepTreeRoot, rebuildOverrides.SweepTreeRoot, &overridden)
+		overrideField(flags, "per-tx-fee", &build.PerTxFee, perTxFee, &overridden)
+		if flags.Changed("seed") {
+			old := "none"
+			if build.Seed != nil {
+				old = fmt.Sprint(*build.Seed)
+			}
+			overridden = append(overridden, fmt.Sprintf("seed: %s → %d", old, rebuildSeed))
+			build.Seed = &rebuildSeed
+		}
+
+		if build.Leaves <= 0 {
+			fmt.Println("Error: Number of leaves must be a positive integer")
+			os.Exit(1)
+		}
+		opts, err := build.generateOptions()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		var baseline map[string]float64
+		if baselinePath != "" {
+			baseline, err = loadBaselineMetrics(baselinePath)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to load baseline: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Println("🔁 Ark Tree Rebuilder")
+		fmt.Println("=" + strings.Repeat("=", 50))
+		fmt.Printf("📄 Parameters from %s\n", args[0])
+		if len(overridden) == 0 {
+			fmt.Println("🔧 No overridden field")
+		} else {
+			fmt.Println("🔧 Overridden fields:")
+			for _, field := range overridden {
+				fmt.Printf("   %s\n", field)
+			}
+		}
+		if build.Seed == nil {
+			warnf(os.Stdout, "the tree wasn't seeded, the rebuilt tree differs from the original")
+		}
+		fmt.Println()
+
+		fmt.Printf("🌿 Building Vtxo tree with %d leaves... ", build.Leaves)
+		generation, err := arktree.Generate(opts)
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ (%s)\n", generation.Timings[len(generation.Timings)-1].Elapsed)
+
+		fmt.Print("📈 Calculating tree statistics... ")
+		stats, err := analyze(generation.Tree, arktree.AnalyzeOptions{
+			Workers:         workers,
+			WithAnchors:     withAnchors,
+			WeightBy:        arktree.WeightUnit(weightBy),
+			VerifyCosigners: verifyCosigners,
+			Feerate:         feerate,
+			BlockInterval:   blockInterval,
+			Witness:         witnessModel(),
+			ClampValue:      clampValue,
+			ExcludeNUMS:     excludeNUMS,
+			PerTxFee:        build.PerTxFee,
+		})
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅")
+
+		// exported once analyzed, to record the metrics of the run
+		if rebuildOut != "" {
+			fmt.Printf("💾 Exporting tree to %s... ", rebuildOut)
+			if err := exportTree(rebuildOut, generation.Tree, build, generation.SweepTreeRoot, stats); err != nil {
+				fmt.Printf("\n❌ Error: Failed to export tree: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("✅")
+		}
+
+		if baseline != nil {
+			printBaselineChanges(stats, baseline)
+		} else {
+			printStats(stats)
+		}
+		exitIfPartial(stats)
+	},
+}
+
+var (
+	rebuildSeed int64
+	rebuildOut  string
+)
+
+func init() {
+	rebuildCmd.Flags().IntVar(&rebuildOverrides.Leaves, "leaves", 0, "Override the number of leaves")
+	rebuildCmd.Flags().Int64Var(&rebuildSeed, "seed", 0, "Override the seed")
+	rebuildCmd.Flags().Uint64Var(&rebuildOverrides.Amount, "amount", 0, "Override the amount of each leaf")
+	rebuildCmd.Flags().StringVar(&rebuildOverrides.AmountSamples, "amount-samples", "", "Override the file the leaf amounts are drawn from, empty to use --amount")
+	rebuildCmd.Flags().StringVar(&rebuildOverrides.LocktimeType, "locktime-type", "", "Override the unit of the sweep locktime: block or second")
+	rebuildCmd.Flags().Uint32Var(&rebuildOverrides.LocktimeValue, "locktime-value", 0, "Override the sweep locktime, seconds must be a multiple of 512")
+	rebuildCmd.Flags().BoolVar(&rebuildOverrides.SharedCosigner, "shared-cosigner", false, "Override the reuse of a single cosigner key")
+	rebuildCmd.Flags().IntVar(&rebuildOverrides.CosignerGroups, "cosigner-groups", 0, "Override the number of cosigner groups")
+	rebuildCmd.Flags().BoolVar(&rebuildOverrides.RawScripts, "raw-scripts", false, "Override the use of random bytes as leaf scripts")
+	rebuildCmd.Flags().StringVar(&rebuildOverrides.SweepTreeRoot, "sweep-root", "", "Override the hex sweep tree root of 32 bytes, empty for a random one")
+	rebuildCmd.Flags().StringVar(&rebuildOut, "out", "", "Export the rebuilt tree to the given file, gzip compressed if it ends in .gz")
+	rebuildCmd.Flags().StringVar(&baselinePath, "baseline", "", "Only print the metrics differing from those recorded in this export or manifest, with their deltas")
+	rebuildCmd.Flags().BoolVar(&includeCosigners, "include-cosigners", false, "Store the cosigner keys of every node in the export, checked against the tree on import")
+
+	addStatsFlags(rebuildCmd)
+
+	rootCmd.AddCommand(rebuildCmd)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// statsSchemaVersion is the schema version of statsReport, see arktree.SummaryVersion
+const statsSchemaVersion = arktree.SummaryVersion
+
+// statsReport is the JSON document printed by --output json: the summary of
+// the library, followed by the fields of the flags of generate. Its keys follow
+// the order of the fields, or are sorted with --canonical. The maps, counts,
+// arity and node_sizes, are always encoded with their keys sorted.
+type statsReport struct {
+	arktree.Summary
+	ExcludedLeaves   int                    `json:"excluded_leaves,omitempty"`    // with --ignore-amount
+	Checksum         string                 `json:"checksum,omitempty"`           // with --checksum
+	SweepTreeRoot    string                 `json:"sweep_tree_root,omitempty"`    // hex, with --sweep-root
+	WorstCase        *worstCaseReport       `json:"worst_case,omitempty"`         // with --shape worst
+	CosignersPerNode *arktree.CosignerCount `json:"cosigners_per_node,omitempty"` // with --cosigners-per-node
+	Arity            map[string]int         `json:"arity,omitempty"`              // internal nodes by number of children, with --arity
+	NodeSizes        map[string]int         `json:"node_sizes,omitempty"`         // estimated vsize by txid, with --include-node-sizes
+	RawArrays        *rawArraysReport       `json:"raw_arrays,omitempty"`         // with --raw-arrays
+}
+
+// rawArraysReport is the branch sizes and weights as computed, an entry per
+// leaf in the order of LeafTxids, for statistics of their own
+type rawArraysReport struct {
+	LeafTxids     []string  `json:"leaf_txids"`
+	BranchSizes   []int     `json:"branch_sizes"`
+	BranchWeights []float64 `json:"branch_weights"`
+}
+
+func newRawArraysReport(stats *arktree.Report) *rawArraysReport {
+	return &rawArraysReport{
+		LeafTxids:     stats.LeafTxids,
+		BranchSizes:   stats.BranchSizes,
+		BranchWeights: stats.BranchWeights,
+	}
+}
+
+// newStatsReport returns the report of stats, labels maps leaf txids to the
+// labels of the leaves file and may be nil
+func newStatsReport(stats *arktree.Report, labels map[string]string) statsReport {
+	return statsReport{Summary: stats.Summary(arktree.SummaryOptions{
+		Labels:            labels,
+		ExcludedBranchTxs: excludedBranchTxs(stats),
+	})}
+}
+
+// compactStatsReport is the JSON document printed by --output json with
+// --compact-stats: a single flat object of the headline numbers of
+// statsReport, without arrays or maps. Every field is always present, so that
+// dashboards can rely on this exact field set:
+//
+//   - schema_version, leaves, excluded_leaves, total_transactions, depth
+//   - branch_size_ and broadcast_weight_ min, mean, median, p90, p99, max and
+//     stddev
+//   - balance, amortization, branching_factor, size_on_wire,
+//     storage_per_vtxo, total_value and partial
+//
+// depth is the biggest branch size, the number of levels of the tree.
+type compactStatsReport struct {
+	SchemaVersion     int `json:"schema_version"`
+	Leaves            int `json:"leaves"`
+	ExcludedLeaves    int `json:"excluded_leaves"`
+	TotalTransactions int `json:"total_transactions"`
+	Depth             int `json:"depth"`
+
+	BranchSizeMin    float64 `json:"branch_size_min"`
+	BranchSizeMean   float64 `json:"branch_size_mean"`
+	BranchSizeMedian float64 `json:"branch_size_median"`
+	BranchSizeP90    float64 `json:"branch_size_p90"`
+	BranchSizeP99    float64 `json:"branch_size_p99"`
+	BranchSizeMax    float64 `json:"branch_size_max"`
+	BranchSizeStddev float64 `json:"branch_size_stddev"`
+
+	BroadcastWeightMin    float64 `json:"broadcast_weight_min"`
+	BroadcastWeightMean   float64 `json:"broadcast_weight_mean"`
+	BroadcastWeightMedian float64 `json:"broadcast_weight_median"`
+	BroadcastWeightP90    float64 `json:"broadcast_weight_p90"`
+	BroadcastWeightP99    float64 `json:"broadcast_weight_p99"`
+	BroadcastWeightMax    float64 `json:"broadcast_weight_max"`
+	BroadcastWeightStddev float64 `json:"broadcast_weight_stddev"`
+
+	Balance         float64 `json:"balance"`
+	Amortization    float64 `json:"amortization"`
+	BranchingFactor float64 `json:"branching_factor"`
+	SizeOnWire      int     `json:"size_on_wire"`
+	StoragePerVtxo  float64 `json:"storage_per_vtxo"` // total bytes
+	TotalValue      int64   `json:"total_value"`
+	Partial         bool    `json:"partial"`
+}
+
+// newCompactStatsReport returns the headline numbers of report, the minimum
+// and percentiles being computed from the exact per-branch values of stats
+// rather than the rounded counts of the report
+func newCompactStatsReport(report statsReport, stats *arktree.Report) compactStatsReport {
+	branchSizes := reportedBranchSizes(stats)
+	sizes := make([]float64, 0, len(branchSizes))
+	for _, size := range branchSizes {
+		sizes = append(sizes, float64(size))
+	}
+	weights := slices.Clone(stats.BranchWeights)
+	slices.Sort(sizes)
+	slices.Sort(weights)
+
+	return compactStatsReport{
+		SchemaVersion:     report.SchemaVersion,
+		Leaves:            report.Leaves,
+		ExcludedLeaves:    report.ExcludedLeaves,
+		TotalTransactions: report.TotalTransactions,
+		Depth:             int(report.BranchSizes.Max) + report.ExcludedBranchTxs,
+
+		BranchSizeMin:    sortedPercentile(sizes, 0),
+		BranchSizeMean:   report.BranchSizes.Mean,
+		BranchSizeMedian: report.BranchSizes.Median,
+		BranchSizeP90:    sortedPercentile(sizes, 0.9),
+		BranchSizeP99:    sortedPercentile(sizes, 0.99),
+		BranchSizeMax:    report.BranchSizes.Max,
+		BranchSizeStddev: report.BranchSizes.Stddev,
+
+		BroadcastWeightMin:    sortedPercentile(weights, 0),
+		BroadcastWeightMean:   report.BroadcastWeights.Mean,
+		BroadcastWeightMedian: report.BroadcastWeights.Median,
+		BroadcastWeightP90:    sortedPercentile(weights, 0.9),
+		BroadcastWeightP99:    sortedPercentile(weights, 0.99),
+		BroadcastWeightMax:    report.BroadcastWeights.Max,
+		BroadcastWeightStddev: report.BroadcastWeights.Stddev,
+
+		Balance:         report.Balance,
+		Amortization:    report.Amortization,
+		BranchingFactor: report.BranchingFactor,
+		SizeOnWire:      report.SizeOnWire,
+		StoragePerVtxo:  report.StoragePerVtxo.Total,
+		TotalValue:      report.TotalValue,
+		Partial:         report.Partial,
+	}
+}
+
+// sortedPercentile returns the smallest of the sorted values with at least q
+// of them at or below it, the smallest value for q = 0 and 0 if there is none,
+// like pooledDistribution.percentile
+func sortedPercentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, index)]
+}
+
+// writeJSON writes a statistics report as compact JSON, indented with two
+// spaces if pretty. If canonical, the keys of every object are sorted so that
+// the same tree always gives the same bytes, whatever the field order.
+func writeJSON(w io.Writer, report any, pretty, canonical bool) error {
+	if !canonical {
+		enc := json.NewEncoder(w)
+		if pretty {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(report)
+	}
+
+	encoded, err := marshalCanonical(report)
+	if err != nil {
+		return err
+	}
+	if pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, encoded, "", "  "); err != nil {
+			return err
+		}
+		encoded = indented.Bytes()
+	}
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+// marshalCanonical encodes v with the keys of every object sorted. Going
+// through generic maps sorts them, and numbers are kept as encoded to not lose
+// precision.
+func marshalCanonical(v any) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+// readStatsReport reads a report written by --output json and checks its schema version
+func readStatsReport(r io.Reader) (statsReport, error) {
+	var report statsReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return statsReport{}, fmt.Errorf("invalid statistics JSON: %w", err)
+	}
+
+	if report.SchemaVersion != statsSchemaVersion {
+		return statsReport{}, fmt.Errorf(
+			"unsupported schema version %d (expected %d)", report.SchemaVersion, statsSchemaVersion,
+		)
+	}
+
+	return report, nil
+}
+
+// pooledDistribution merges per-run distributions as if all their branches
+// came from a single run: means are weighted by the number of branches, the
+// stddev combines the within and between runs variance
+type pooledDistribution struct {
+	n      int
+	sum    float64
+	sumSq  float64
+	max    float64
+	counts map[float64]int
+}
+
+func newPooledDistribution() *pooledDistribution {
+	return &pooledDistribution{counts: make(map[float64]int)}
+}
+
+func (p *pooledDistribution) add(d arktree.Distribution) error {
+	n := 0
+	for key, count := range d.Counts {
+		value, err := strconv.ParseFloat(key, 64)
+		if err != nil {
+			return fmt.Errorf("invalid count key %q: %w", key, err)
+		}
+		p.counts[value] += count
+		n += count
+	}
+
+	p.n += n
+	p.sum += d.Mean * float64(n)
+	p.sumSq += (d.Stddev*d.Stddev + d.Mean*d.Mean) * float64(n)
+	if d.Max > p.max {
+		p.max = d.Max
+	}
+	return nil
+}
+
+func (p *pooledDistribution) mean() float64 {
+	if p.n == 0 {
+		return 0
+	}
+	return p.sum / float64(p.n)
+}
+
+func (p *pooledDistribution) stddev() float64 {
+	if p.n == 0 {
+		return 0
+	}
+	mean := p.mean()
+	return math.Sqrt(math.Max(0, p.sumSq/float64(p.n)-mean*mean))
+}
+
+// percentile returns the smallest value with at least q of the pooled
+// branches at or below it, q in (0, 1]
+func (p *pooledDistribution) percentile(q float64) float64 {
+	values := make([]float64, 0, len(p.counts))
+	for value := range p.counts {
+		values = append(values, value)
+	}
+	sort.Float64s(values)
+
+	target := q * float64(p.n)
+	cumulative := 0
+	for _, value := range values {
+		cumulative += p.counts[value]
+		if float64(cumulative) >= target {
+			return value
+		}
+	}
+	return 0
+}
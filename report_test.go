@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestRawArraysSurviveJSON(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	report := newStatsReport(stats, nil)
+	report.RawArrays = newRawArraysReport(stats)
+	var b bytes.Buffer
+	if err := writeJSON(&b, report, false, false); err != nil {
+		t.Fatal(err)
+	}
+	var decoded statsReport
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	raw := decoded.RawArrays
+	if raw == nil || !slices.Equal(raw.LeafTxids, stats.LeafTxids) || !slices.Equal(raw.BranchSizes, stats.BranchSizes) || !slices.Equal(raw.BranchWeights, stats.BranchWeights) {
+		t.Errorf("decoded raw arrays %+v", raw)
+	}
+}
+
+func TestCanonicalJSONIsReproducible(t *testing.T) {
+	var runs [2]bytes.Buffer
+	for i := range runs {
+		seed := int64(1)
+		stats, err := arktree.GenerateAndAnalyze(arktree.GenerateOptions{
+			NumLeaves:      7,
+			Seed:           &seed,
+			AnalyzeOptions: arktree.AnalyzeOptions{Workers: 1, WithAnchors: true, Feerate: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJSON(&runs[i], newStatsReport(stats, nil), false, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, second := runs[0].Bytes(), runs[1].Bytes()
+	if !bytes.Equal(first, second) {
+		t.Errorf("runs differ:\n%s%s", first, second)
+	}
+	if !bytes.HasPrefix(first, []byte(`{"amortization":`)) {
+		t.Errorf("keys aren't sorted: %.40s", first)
+	}
+}
+
+func TestCompactStatsReport(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *arktree.Report) {
+		compact := newCompactStatsReport(newStatsReport(stats, nil), stats)
+		encoded, err := json.Marshal(compact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range fields {
+			switch value.(type) {
+			case []any, map[string]any:
+				t.Errorf("field %s is not a scalar", name)
+			}
+		}
+
+		for _, quantiles := range [][]float64{
+			{compact.BranchSizeMin, compact.BranchSizeMedian, compact.BranchSizeP90, compact.BranchSizeP99, compact.BranchSizeMax},
+			{compact.BroadcastWeightMin, compact.BroadcastWeightMedian, compact.BroadcastWeightP90, compact.BroadcastWeightP99, compact.BroadcastWeightMax},
+		} {
+			if !slices.IsSorted(quantiles) {
+				t.Errorf("min, median, p90, p99 and max aren't ordered: %v", quantiles)
+			}
+		}
+		if compact.Depth != stats.Depth {
+			t.Errorf("depth %d, expected %d", compact.Depth, stats.Depth)
+		}
+	})
+}
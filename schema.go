@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaCompact bool
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the statistics of generate --output json",
+	Long: `Print the JSON Schema (draft 2020-12) of the statistics object generate --output json prints, or with --compact that of --compact-stats, to validate parsers against it or generate typed bindings.
+
+The schema is derived by reflection from arktree.Summary, the type of the statistics of the library, and the fields the flags of generate add to it, so it can't drift from the output: every key is listed with its type, the keys always present are required, those only present with some flags aren't, and no other key is allowed. Arrays and objects that may be empty are also allowed to be null.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema := statsSchema(schemaCompact)
+		if err := writeJSON(os.Stdout, schema, true, true); err != nil {
+			fmt.Printf("❌ Error: Failed to write the schema: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	schemaCmd.Flags().BoolVar(&schemaCompact, "compact", false, "Print the schema of the --compact-stats output")
+
+	rootCmd.AddCommand(schemaCmd)
+}
+
+// statsSchema returns the JSON Schema of statsReport, arktree.Summary and the
+// fields of the generate flags, or of compactStatsReport if compact
+func statsSchema(compact bool) map[string]any {
+	var report any = statsReport{}
+	title := "arktree statistics"
+	if compact {
+		report = compactStatsReport{}
+		title = "arktree compact statistics"
+	}
+
+	schema := jsonSchema(reflect.TypeOf(report))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = title
+	schema["description"] = fmt.Sprintf("Statistics of a tree printed by arktree generate, arktree.Summary schema_version %d", statsSchemaVersion)
+	return schema
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// jsonSchema returns the JSON Schema of the values of t as encoding/json
+// encodes them. A struct field is required unless it is omitempty, and a
+// slice, map or pointer that isn't may be null. Types with their own
+// encoding accept any value, but for text marshalers which are strings.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch {
+	case t.Implements(jsonMarshalerType):
+		return map[string]any{}
+	case t.Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 { // base64
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := structProperties(t, properties, []string{})
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default: // interfaces
+		return map[string]any{}
+	}
+}
+
+// structProperties adds the schemas of the fields of the struct t to
+// properties and returns required with the names of those always present
+// appended. The fields of embedded structs without a JSON name are added as
+// those of t, as encoding/json promotes them.
+func structProperties(t reflect.Type, properties map[string]any, required []string) []string {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			required = structProperties(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := jsonSchema(field.Type)
+		if strings.Contains(","+options+",", ",omitempty,") {
+			properties[name] = property
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			property = map[string]any{"anyOf": []any{property, map[string]any{"type": "null"}}}
+		}
+		properties[name] = property
+		required = append(required, name)
+	}
+	return required
+}
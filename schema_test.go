@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// matchJSONSchema checks value, decoded from JSON at path, against the subset
+// of JSON Schema jsonSchema generates
+func matchJSONSchema(value any, schema map[string]any, path string) error {
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		for _, option := range anyOf {
+			if matchJSONSchema(value, option.(map[string]any), path) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v matches none of %v", path, value, anyOf)
+	}
+
+	switch schema["type"] {
+	case nil:
+		return nil
+	case "null":
+		if value != nil {
+			return fmt.Errorf("%s: expected null, got %v", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %v", path, value)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %v", path, value)
+		}
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok || (schema["type"] == "integer" && n != math.Trunc(n)) {
+			return fmt.Errorf("%s: expected an %s, got %v", path, schema["type"], value)
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+			return fmt.Errorf("%s: %v below %v", path, n, minimum)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %v", path, value)
+		}
+		for i, item := range items {
+			if err := matchJSONSchema(item, schema["items"].(map[string]any), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %v", path, value)
+		}
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := object[key.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, key)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for key, item := range object {
+			property, ok := properties[key].(map[string]any)
+			if !ok {
+				if property, ok = schema["additionalProperties"].(map[string]any); !ok {
+					return fmt.Errorf("%s: unexpected key %s", path, key)
+				}
+			}
+			if err := matchJSONSchema(item, property, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestStatsFollowTheirSchema(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	// every optional key is set, so that the schema covers all of them
+	report := newStatsReport(stats, nil)
+	report.Checksum = treeChecksum(stats.Tree)
+	report.WorstCase = newWorstCaseReport(stats)
+	report.CosignersPerNode = &stats.CosignersPerNode
+	report.Arity = arityJSON(arktree.ComputeFanOut(stats.Tree).Arity)
+	report.RawArrays = newRawArraysReport(stats)
+	for _, output := range []struct {
+		report  any
+		compact bool
+	}{{report, false}, {newCompactStatsReport(report, stats), true}} {
+		var b bytes.Buffer
+		if err := writeJSON(&b, output.report, false, false); err != nil {
+			t.Fatal(err)
+		}
+		var decoded any
+		if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := json.Marshal(statsSchema(output.compact))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(encoded, &schema); err != nil {
+			t.Fatal(err)
+		}
+		if err := matchJSONSchema(decoded, schema, "$"); err != nil {
+			t.Errorf("compact %t: %s", output.compact, err)
+		}
+	}
+}
+
+func TestSchemaCoversTheSummary(t *testing.T) {
+	schema := statsSchema(false)
+	properties := schema["properties"].(map[string]any)
+	required := schema["required"].([]string)
+
+	summary := reflect.TypeFor[arktree.Summary]()
+	for i := 0; i < summary.NumField(); i++ {
+		name, options, _ := strings.Cut(summary.Field(i).Tag.Get("json"), ",")
+		if _, ok := properties[name]; !ok {
+			t.Errorf("%s of arktree.Summary missing from the schema", name)
+		}
+		if optional := options == "omitempty"; optional == slices.Contains(required, name) {
+			t.Errorf("%s of arktree.Summary: optional %t, required in the schema %t", name, optional, !optional)
+		}
+	}
+}
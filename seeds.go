@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// validateSeedsStdin checks --seeds-stdin against the other generate flags:
+// every seed builds its own tree, so nothing is written but the JSONL lines
+func validateSeedsStdin(seedChanged bool) error {
+	if !seedsStdin {
+		if outputFormat == outputJSONL {
+			return errors.New("--output jsonl requires --seeds-stdin")
+		}
+		return nil
+	}
+
+	switch {
+	case outputFormat != outputJSONL:
+		return errors.New("--seeds-stdin requires --output jsonl")
+	case seedChanged:
+		return errors.New("--seed can't be used with --seeds-stdin")
+	case len(leavesFiles) > 0:
+		return errors.New("--seeds-stdin can't be used with --leaves-file, whose leaves don't depend on the seed")
+	case outPath != "" || keysOutput != "":
+		return errors.New("--seeds-stdin can't be used with --out or --keys-output")
+	}
+	return nil
+}
+
+// generateSeeds builds a tree with opts for every seed read from r, one per
+// line, and writes the statistics of each to w as a line of JSON: the report
+// of --output json, or of --compact-stats, with a leading seed field. The
+// scanner, the encoding buffer and the writer are reused from one seed to the
+// next, and each line is flushed once written so that consumers can follow
+// the run.
+func generateSeeds(r io.Reader, w io.Writer, opts arktree.GenerateOptions, analyzeOpts arktree.AnalyzeOptions) error {
+	scanner := bufio.NewScanner(r)
+	bw := bufio.NewWriter(w)
+	var encoded bytes.Buffer
+	enc := json.NewEncoder(&encoded)
+
+	var groupLabels []string
+	if opts.CosignerGroups != 0 {
+		groupLabels = cosignerGroupLabels(opts.NumLeaves, opts.CosignerGroups)
+	}
+
+	var seed int64
+	opts.Seed = &seed
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var err error
+		seed, err = strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid seed %q", line, text)
+		}
+
+		generation, err := arktree.Generate(opts)
+		if err != nil {
+			return fmt.Errorf("seed %d: %w", seed, err)
+		}
+		stats, err := analyze(generation.Tree, analyzeOpts)
+		if err != nil {
+			return fmt.Errorf("seed %d: %w", seed, err)
+		}
+
+		var labels map[string]string
+		if groupLabels != nil {
+			labels, err = leafLabels(generation.Tree, generation.Leaves, groupLabels)
+			if err != nil {
+				return fmt.Errorf("seed %d: %w", seed, err)
+			}
+		}
+		report := newStatsReport(stats, labels)
+		if checksum {
+			report.Checksum = treeChecksum(generation.Tree)
+		}
+
+		encoded.Reset()
+		if compactStats {
+			err = enc.Encode(newCompactStatsReport(report, stats))
+		} else {
+			err = enc.Encode(report)
+		}
+		if err != nil {
+			return err
+		}
+		// the seed goes first, in place of the opening brace of the report
+		fmt.Fprintf(bw, `{"seed":%d,`, seed)
+		bw.Write(encoded.Bytes()[1:])
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		exitIfPartial(stats)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestGenerateSeeds(t *testing.T) {
+	const numLeaves = 3
+	var lines bytes.Buffer
+	if err := generateSeeds(strings.NewReader("4\n\n5\n"), &lines, arktree.GenerateOptions{NumLeaves: numLeaves}, arktree.AnalyzeOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blank line is skipped, every seed gives a line
+	dec := json.NewDecoder(&lines)
+	for _, seed := range []int64{4, 5} {
+		var line struct {
+			Seed int64 `json:"seed"`
+			statsReport
+		}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		if line.Seed != seed || line.Leaves != numLeaves || len(line.Branches) != numLeaves {
+			t.Errorf("line of seed %d: seed %d, %d leaves, %d branches", seed, line.Seed, line.Leaves, len(line.Branches))
+		}
+	}
+	if dec.More() {
+		t.Error("more lines than seeds")
+	}
+
+	if err := generateSeeds(strings.NewReader("x\n"), &lines, arktree.GenerateOptions{NumLeaves: numLeaves}, arktree.AnalyzeOptions{Workers: 1}); err == nil {
+		t.Error("invalid seed accepted")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+// selftestSizes are the number of leaves of the trees built by selftest,
+// small enough to run in a few seconds and covering odd and power of two sizes
+var selftestSizes = []int{1, 2, 3, 7, 16, 33}
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Build a few small seeded trees and check the statistics invariants",
+	Long: `Build a few small seeded trees, compute their statistics and check the main invariants,
+as a smoke check of the binary against the linked ark version. The full checks are the Go tests.
+
+Prints PASS or FAIL per check and exits with a non-zero status if any check fails.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("🧪 Arktree Self-Test")
+		fmt.Println("=" + strings.Repeat("=", 50))
+
+		failures := 0
+		for i, numLeaves := range selftestSizes {
+			seed := int64(i + 1)
+			fmt.Printf("\n🌳 %d leaves (seed %d)\n", numLeaves, seed)
+
+			for _, check := range selftestTree(numLeaves, seed) {
+				if check.err != nil {
+					failures++
+					fmt.Printf("❌ FAIL  %s: %s\n", check.name, check.err)
+				} else {
+					fmt.Printf("✅ PASS  %s\n", check.name)
+				}
+			}
+		}
+
+		fmt.Println("\n" + strings.Repeat("=", 60))
+		if failures > 0 {
+			fmt.Printf("❌ %d check(s) failed\n", failures)
+			fmt.Println(strings.Repeat("=", 60))
+			os.Exit(1)
+		}
+		fmt.Println("🎉 All checks passed!")
+		fmt.Println(strings.Repeat("=", 60))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+}
+
+type selftestCheck struct {
+	name string
+	err  error
+}
+
+// selftestTree builds a seeded tree of numLeaves leaves and checks its statistics
+func selftestTree(numLeaves int, seed int64) []selftestCheck {
+	stats, err := arktree.GenerateAndAnalyze(arktree.GenerateOptions{
+		NumLeaves: numLeaves,
+		Seed:      &seed,
+		AnalyzeOptions: arktree.AnalyzeOptions{
+			Workers:         1,
+			WithAnchors:     true,
+			VerifyCosigners: true,
+			Feerate:         1,
+		},
+	})
+	if err != nil {
+		return []selftestCheck{{"build tree and compute statistics", err}}
+	}
+	txtree := stats.Tree
+
+	checks := []selftestCheck{
+		{"build tree and compute statistics", nil},
+	}
+	check := func(name string, fn func() error) {
+		checks = append(checks, selftestCheck{name, fn()})
+	}
+
+	check("report is consistent", stats.Validate)
+
+	check("node count is 2N-1", func() error {
+		if expected := expectedNodeCount(numLeaves); stats.TotalSize != expected {
+			return fmt.Errorf("%d nodes, expected %d", stats.TotalSize, expected)
+		}
+		return nil
+	})
+
+	check("value is conserved", func() error {
+		discrepancies, err := arktree.ValueConservation(txtree)
+		if err != nil {
+			return err
+		}
+		if len(discrepancies) > 0 {
+			return fmt.Errorf("%d tx(s) don't conserve value, first %s", len(discrepancies), discrepancies[0])
+		}
+		return nil
+	})
+
+	check("parallel statistics match serial", func() error {
+		sizes, weights, err := arktree.BranchStatsParallel(txtree, 4)
+		if err != nil {
+			return err
+		}
+		if !equalInts(sizes, stats.BranchSizes) || !equalFloats(weights, stats.BranchWeights) {
+			return fmt.Errorf("parallel and serial branch statistics differ")
+		}
+		return nil
+	})
+
+	return checks
+}
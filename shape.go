@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+const (
+	shapeDefault = "default"
+	shapeWorst   = "worst"
+)
+
+// validateShape checks the --shape value
+func validateShape(shape string) error {
+	switch shape {
+	case shapeDefault, shapeWorst:
+		return nil
+	default:
+		return fmt.Errorf("unknown shape %q (expected %s or %s)", shape, shapeDefault, shapeWorst)
+	}
+}
+
+// worstCaseReport compares the biggest branch of a tree with the bounds of
+// BranchSizeBounds
+type worstCaseReport struct {
+	BiggestBranch  int     `json:"biggest_branch"`
+	BestPossible   int     `json:"best_possible"`
+	TheoreticalMax int     `json:"theoretical_max"`
+	Closeness      float64 `json:"closeness"` // 0 for the best possible tree, 1 for the worst
+}
+
+func newWorstCaseReport(stats *arktree.Report) *worstCaseReport {
+	best, worst := arktree.BranchSizeBounds(stats.NumLeaves)
+	return &worstCaseReport{
+		BiggestBranch:  stats.BiggestBranch(),
+		BestPossible:   best,
+		TheoreticalMax: worst,
+		Closeness:      arktree.WorstCaseCloseness(stats.BiggestBranch(), stats.NumLeaves),
+	}
+}
+
+func printWorstCase(stats *arktree.Report) {
+	report := newWorstCaseReport(stats)
+
+	fmt.Println("\n🧨 WORST CASE:")
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.row("Biggest branch:", strconv.Itoa(report.BiggestBranch), "tx")
+	t.row("Best possible:", strconv.Itoa(report.BestPossible), "tx (balanced binary tree)")
+	t.row("Theoretical max:", strconv.Itoa(report.TheoreticalMax), "tx (chain)")
+	t.row("Closeness:", fmt.Sprintf("%.2f", report.Closeness), "(0 = best, 1 = worst)")
+	t.flush()
+}
+
+// printFanOut prints the average fan-out and fill factor of the tree
+func printFanOut(stats *arktree.Report) {
+	fanOut := arktree.ComputeFanOut(stats.Tree)
+
+	fmt.Println("\n📐 FAN-OUT:")
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, false, true)
+	t.row("Average fan-out:", fmt.Sprintf("%.2f", fanOut.Average()), "children per internal node")
+	t.row("Max fan-out:", strconv.Itoa(fanOut.MaxChildren), "children")
+	t.row("Capacity:", fmt.Sprintf("%.0f", fanOut.Capacity()), fmt.Sprintf("leaves (perfect tree of depth %d)", fanOut.Depth))
+	t.row("Fill factor:", fmt.Sprintf("%.2f", fanOut.FillFactor()), "(1 = perfectly packed)")
+	t.flush()
+}
+
+// printArity prints the number of internal nodes with each number of
+// children, the fewest children first
+func printArity(stats *arktree.Report) {
+	fanOut := arktree.ComputeFanOut(stats.Tree)
+	children := make([]int, 0, len(fanOut.Arity))
+	for count := range fanOut.Arity {
+		children = append(children, count)
+	}
+	sort.Ints(children)
+
+	fmt.Println("\n🔀 ARITY:")
+	fmt.Println(strings.Repeat("─", 40))
+	if fanOut.Internal == 0 {
+		fmt.Println("No internal node, the root is the only leaf")
+		return
+	}
+	t := newTable(os.Stdout, true, true, false)
+	t.row("Children", "Nodes", "")
+	for _, count := range children {
+		t.row(strconv.Itoa(count), strconv.Itoa(fanOut.Arity[count]), fmt.Sprintf("(%.1f%%)", 100*float64(fanOut.Arity[count])/float64(fanOut.Internal)))
+	}
+	t.flush()
+}
+
+// arityJSON keys the arity of the tree by number of children, as JSON object
+// keys must be strings
+func arityJSON(arity map[int]int) map[string]int {
+	counts := make(map[string]int, len(arity))
+	for children, count := range arity {
+		counts[strconv.Itoa(children)] = count
+	}
+	return counts
+}
+
+// printLevelVsizes prints the number of nodes and the vsize of each level of
+// the tree, showing whether its top or its bottom weighs the most in bytes
+func printLevelVsizes(stats *arktree.Report) {
+	if len(stats.LevelVsizes) == 0 { // not computed in interrupted runs
+		return
+	}
+	nodes := arktree.NodesPerLevel(stats.Tree)
+	total := 0
+	for _, vsize := range stats.LevelVsizes {
+		total += vsize
+	}
+
+	fmt.Println("\n📏 VSIZE PER LEVEL:")
+	fmt.Println(strings.Repeat("─", 40))
+	t := newTable(os.Stdout, true, true, true, true)
+	t.row("level", "nodes", "vB", "share")
+	for i, vsize := range stats.LevelVsizes {
+		t.row(strconv.Itoa(i+1), strconv.Itoa(nodes[i]), strconv.Itoa(vsize), fmt.Sprintf("%.1f%%", float64(vsize)/float64(total)*100))
+	}
+	t.flush()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signKeys      string
+	signSweepRoot string
+	signOut       string
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign [tree-file]",
+	Short: "Cosign an exported tree with MuSig2 and verify the signatures",
+	Long: `Import a tree exported with "generate --out" and run its MuSig2 signing round with the private keys of --keys, as written by "generate --keys-output": every cosigner sends its nonces, then its partial signatures, and the aggregated signature of each tx is set as its taproot key spend signature. The signatures are then verified against the aggregated keys of the cosigners.
+
+Every cosigner of the tree needs its private key. Signing also needs the sweep tree root the outputs are tweaked with, recorded in the exports of generate and rebuild, to give with --sweep-root otherwise. The signed tree is exported with --out.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if signKeys == "" {
+			fmt.Printf("Error: --keys is required\n")
+			os.Exit(1)
+		}
+
+		txtree, manifest, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		sweepRoot := signSweepRoot
+		if sweepRoot == "" {
+			sweepRoot = manifest.SweepTreeRoot
+		}
+		if sweepRoot == "" {
+			fmt.Printf("Error: %s records no sweep tree root, give it with --sweep-root\n", args[0])
+			os.Exit(1)
+		}
+		sweepTreeRoot, err := parseSweepRoot(sweepRoot)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		keys, err := readKeysFile(signKeys)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to read keys: %s\n", err)
+			os.Exit(1)
+		}
+
+		nodes, err := arktree.NumberOfNodes(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✍️  Signing %d tx with %d keys... ", nodes, len(keys))
+		start := time.Now()
+		if err := arktree.SignTree(txtree, keys, sweepTreeRoot); err != nil {
+			fmt.Printf("\n❌ Error: Failed to sign tree: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ (%s)\n", time.Since(start))
+
+		fmt.Print("🔏 Verifying signatures... ")
+		if err := arktree.VerifyTreeSignatures(txtree, sweepTreeRoot); err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅")
+
+		if signOut != "" {
+			fmt.Printf("💾 Exporting signed tree to %s... ", signOut)
+			if err := exportTree(signOut, txtree, manifest.Build, sweepTreeRoot, nil); err != nil {
+				fmt.Printf("\n❌ Error: Failed to export tree: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("✅")
+		}
+		fmt.Println(strings.Repeat("─", 40))
+	},
+}
+
+// parseSweepRoot decodes a hex sweep tree root, checking its length as a
+// shorter or longer one would be silently padded or truncated by the builder
+func parseSweepRoot(value string) ([]byte, error) {
+	root, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sweep tree root %q: %w", value, err)
+	}
+	if len(root) != arktree.SweepTreeRootSize {
+		return nil, fmt.Errorf("sweep tree root must be %d bytes (%d hex characters), got %d", arktree.SweepTreeRootSize, 2*arktree.SweepTreeRootSize, len(root))
+	}
+	return root, nil
+}
+
+func init() {
+	signCmd.Flags().StringVar(&signKeys, "keys", "", "File of the hex private keys of the cosigners, one per line")
+	signCmd.Flags().StringVar(&signSweepRoot, "sweep-root", "", "Hex sweep tree root the outputs are tweaked with, read from the export if empty")
+	signCmd.Flags().StringVar(&signOut, "out", "", "Export the signed tree to the given file, gzip compressed if it ends in .gz")
+	signCmd.Flags().BoolVar(&includeCosigners, "include-cosigners", false, "Store the cosigner keys of every node in the export, checked against the tree on import")
+
+	rootCmd.AddCommand(signCmd)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestParseSweepRoot(t *testing.T) {
+	root := bytes.Repeat([]byte{0xab}, arktree.SweepTreeRootSize)
+	if parsed, err := parseSweepRoot(hex.EncodeToString(root)); err != nil || !bytes.Equal(parsed, root) {
+		t.Errorf("parsed %x (%v), expected %x", parsed, err, root)
+	}
+	for _, invalid := range []string{"abcd", hex.EncodeToString(append(root, 0)), strings.Repeat("z", 64)} {
+		if _, err := parseSweepRoot(invalid); err == nil {
+			t.Errorf("sweep root %q accepted", invalid)
+		}
+	}
+}
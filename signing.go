@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// musig2PubNonceSize is the size of a MuSig2 public nonce (two compressed points)
+	musig2PubNonceSize = 66
+	// musig2PartialSigSize is the size of a MuSig2 partial signature
+	musig2PartialSigSize = 32
+)
+
+var simulateSigningCmd = &cobra.Command{
+	Use:   "simulate-signing [tree-file]",
+	Short: "Count the MuSig2 messages needed to sign an exported tree",
+	Long: `Import a tree exported with "generate --out" and count, without signing, the MuSig2 messages of a signing round: every cosigner of a node sends one public nonce and one partial signature for it.
+
+Counts are reported per level, level 1 being the root, and in total.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		levels, err := signingMessages(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to count signing messages: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println("✍️  MUSIG2 SIGNING ROUND")
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("%6s %8s %10s %10s %10s\n", "level", "nodes", "nonces", "partials", "bytes")
+
+		var total levelSigning
+		for _, level := range levels {
+			fmt.Printf("%6d %8d %10d %10d %10d\n", level.level, level.nodes, level.nonces, level.partialSigs, level.bytes())
+			total.nodes += level.nodes
+			total.nonces += level.nonces
+			total.partialSigs += level.partialSigs
+		}
+
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("%6s %8d %10d %10d %10d\n", "total", total.nodes, total.nonces, total.partialSigs, total.bytes())
+		fmt.Printf("\n📨 Signing Messages:      %8d\n", total.nonces+total.partialSigs)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simulateSigningCmd)
+}
+
+// levelSigning counts the MuSig2 messages needed to sign the nodes of a level
+type levelSigning struct {
+	level       int
+	nodes       int
+	nonces      int
+	partialSigs int
+}
+
+// bytes is the size of the nonces and partial signatures exchanged
+func (l levelSigning) bytes() int {
+	return l.nonces*musig2PubNonceSize + l.partialSigs*musig2PartialSigSize
+}
+
+// signingMessages counts per level the nonces and partial signatures of a
+// signing round, one of each per cosigner of each node
+func signingMessages(g *tree.TxGraph) ([]levelSigning, error) {
+	var levels []levelSigning
+
+	if err := arktree.Walk(g, func(node, _ *tree.TxGraph, level int) error {
+		keys, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+
+		for len(levels) < level {
+			levels = append(levels, levelSigning{level: len(levels) + 1})
+		}
+		levels[level-1].nodes++
+		levels[level-1].nonces += len(keys)
+		levels[level-1].partialSigs += len(keys)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
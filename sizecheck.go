@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var sizeCheckCmd = &cobra.Command{
+	Use:   "size-check [number-of-leaves...]",
+	Short: "Compare the expected and actual node count of trees",
+	Long: `Build a tree for each given number of leaves and compare its node count with the expected one.
+
+BuildVtxoTree builds binary trees where each leaf has its own transaction, so a tree of N leaves is expected to have N leaf nodes and N-1 internal nodes: 2N-1 nodes. A non-zero difference means the builder pads or prunes the tree.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		counts := make([]int, 0, len(args))
+		for _, arg := range args {
+			numLeaves, err := strconv.Atoi(arg)
+			if err != nil || numLeaves <= 0 {
+				fmt.Printf("Error: Invalid number of leaves: %s\n", arg)
+				os.Exit(1)
+			}
+			counts = append(counts, numLeaves)
+		}
+
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("%10s %10s %10s %10s\n", "leaves", "expected", "actual", "diff")
+		fmt.Println(strings.Repeat("─", 60))
+
+		mismatches := 0
+		for _, numLeaves := range counts {
+			generation, err := arktree.Generate(arktree.GenerateOptions{NumLeaves: numLeaves})
+			if err != nil {
+				fmt.Printf("❌ Error: tree of %d leaves: %s\n", numLeaves, err)
+				os.Exit(1)
+			}
+
+			actual, err := arktree.NumberOfNodes(generation.Tree)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to get total size: %s\n", err)
+				os.Exit(1)
+			}
+
+			expected := expectedNodeCount(numLeaves)
+			if actual != expected {
+				mismatches++
+			}
+			fmt.Printf("%10d %10d %10d %+10d\n", numLeaves, expected, actual, actual-expected)
+		}
+
+		fmt.Println(strings.Repeat("─", 60))
+		if mismatches > 0 {
+			fmt.Printf("❌ %d tree(s) differ from the expected size\n", mismatches)
+			os.Exit(1)
+		}
+		fmt.Println("✅ All trees have the expected size")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sizeCheckCmd)
+}
+
+// expectedNodeCount is the number of nodes of a binary tree of numLeaves
+// leaves where every leaf has its own transaction
+func expectedNodeCount(numLeaves int) int {
+	return 2*numLeaves - 1
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestExpectedNodeCount(t *testing.T) {
+	forEachTestTree(t, func(t *testing.T, _ int64, stats *arktree.Report) {
+		if expected := expectedNodeCount(stats.NumLeaves); stats.TotalSize != expected {
+			t.Errorf("%d nodes, expected %d", stats.TotalSize, expected)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	splitOutDir string
+	splitGzip   bool
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split [tree-file]",
+	Short: "Split an exported tree into one file per branch",
+	Long: `Import a tree exported with "generate --out" and write the branch of each leaf, from the root to the leaf, to its own export named <leaf txid>.json, as a server would hand each user only their exit path.
+
+Each file can be imported and analyzed on its own like any export. A branch is a chain with the siblings pruned, so it is reported as degenerate and its cosigner sets, which still cover the pruned leaves, fail the validate check.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if splitOutDir == "" {
+			fmt.Printf("Error: --out-dir is required\n")
+			os.Exit(1)
+		}
+
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(splitOutDir, 0755); err != nil {
+			fmt.Printf("❌ Error: Failed to create output directory: %s\n", err)
+			os.Exit(1)
+		}
+
+		ext := ".json"
+		if splitGzip {
+			ext = ".json.gz"
+		}
+
+		leaves := arktree.LeafTxids(txtree)
+		for _, leaf := range leaves {
+			branch, err := txtree.SubGraph([]string{leaf})
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to extract branch of %s: %s\n", leaf, err)
+				os.Exit(1)
+			}
+
+			if err := exportTree(filepath.Join(splitOutDir, leaf+ext), branch, nil, nil, nil); err != nil {
+				fmt.Printf("❌ Error: Failed to export branch of %s: %s\n", leaf, err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("💾 Wrote %d branches to %s\n", len(leaves), splitOutDir)
+	},
+}
+
+func init() {
+	splitCmd.Flags().StringVar(&splitOutDir, "out-dir", "", "Directory the branch files are written to")
+	splitCmd.Flags().BoolVar(&splitGzip, "gzip", false, "Gzip compress the branch files, named <leaf txid>.json.gz")
+
+	rootCmd.AddCommand(splitCmd)
+}
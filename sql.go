@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// defaultSQLTable is the default --table of the sql output
+const defaultSQLTable = "tree_nodes"
+
+// sqlTable is the --table flag of generate
+var sqlTable string
+
+// validateSQLTable checks --table, which only names the table of the sql
+// output
+func validateSQLTable(table string, changed bool) error {
+	if changed && !hasOutput(outputSQL) {
+		return errors.New("--table requires --output sql")
+	}
+	if table == "" {
+		return errors.New("--table can't be empty")
+	}
+	return nil
+}
+
+// writeSQL writes the nodes of g as SQL, in a transaction creating table if
+// it doesn't exist then inserting a row per node, parents first: its txid,
+// the txid of its parent, NULL for the root, its depth, the root being at 1,
+// its number of cosigners and its vsize with witness. Identifiers and strings
+// are quoted as standard SQL does, which PostgreSQL and SQLite accept and
+// MySQL with ANSI_QUOTES.
+func writeSQL(w io.Writer, g *tree.TxGraph, table string, witness arktree.WitnessModel) error {
+	table = sqlIdentifier(table)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "BEGIN;")
+	fmt.Fprintf(bw, "CREATE TABLE IF NOT EXISTS %s (txid VARCHAR(64) PRIMARY KEY, parent_txid VARCHAR(64), depth INTEGER NOT NULL, cosigner_count INTEGER NOT NULL, vsize INTEGER NOT NULL);\n", table)
+	if err := arktree.Walk(g, func(node, parent *tree.TxGraph, depth int) error {
+		cosigners, err := tree.GetCosignerKeys(node.Root.Inputs[0])
+		if err != nil {
+			return err
+		}
+		vsize, err := witness.Vsize(node.Root)
+		if err != nil {
+			return err
+		}
+
+		parentTxid := "NULL"
+		if parent != nil {
+			parentTxid = sqlString(parent.Root.UnsignedTx.TxID())
+		}
+		_, err = fmt.Fprintf(bw, "INSERT INTO %s (txid, parent_txid, depth, cosigner_count, vsize) VALUES (%s, %s, %d, %d, %d);\n",
+			table, sqlString(node.Root.UnsignedTx.TxID()), parentTxid, depth, len(cosigners), vsize)
+		return err
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintln(bw, "COMMIT;")
+	return bw.Flush()
+}
+
+// sqlIdentifier quotes name as an SQL identifier, doubling its double quotes
+func sqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlString quotes s as an SQL string literal, doubling its single quotes
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
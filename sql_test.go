@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestWriteSQL(t *testing.T) {
+	txtree := seededTree(t, 7, 1).Tree
+	nodes, err := arktree.NumberOfNodes(txtree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := writeSQL(&b, txtree, `it's "quoted"`, arktree.DefaultWitnessModel); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != nodes+3 || lines[0] != "BEGIN;" || lines[len(lines)-1] != "COMMIT;" {
+		t.Fatalf("%d lines for %d txs, expected them between BEGIN and COMMIT", len(lines), nodes)
+	}
+	inserts := lines[2 : len(lines)-1]
+	if !strings.HasPrefix(inserts[0], `INSERT INTO "it's ""quoted""" `) {
+		t.Errorf("table not quoted: %s", inserts[0])
+	}
+	if roots := strings.Count(b.String(), ", NULL, 1, "); roots != 1 {
+		t.Errorf("%d rows without a parent, expected the root", roots)
+	}
+}
+
+func TestSQLString(t *testing.T) {
+	for value, want := range map[string]string{"ab": "'ab'", "a'b": "'a''b'", "": "''"} {
+		if quoted := sqlString(value); quoted != want {
+			t.Errorf("%s quoted as %s, expected %s", value, quoted, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var sweepPathOutDir string
+
+var sweepPathCmd = &cobra.Command{
+	Use:   "sweep-path [tree-file]",
+	Short: "Write the txs an operator broadcasts for a cooperative sweep of an exported tree",
+	Long: `Import a tree exported with "generate --out" and write the txs of its cooperative sweep to --out, each as a base64 PSBT named <txid>.psbt, with their vsize and the fee of broadcasting them at --feerate.
+
+When the users cooperate none of their branches is broadcast: the funds are claimed by a single tx spending the batch output, which the root spends too. The trees built here have no sweep-specific tx, so the sweep path is the root alone, the tx the cooperative sweep statistics count. It is the counterpart of the per-user branches written by split.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if sweepPathOutDir == "" {
+			fmt.Printf("Error: --out is required\n")
+			os.Exit(1)
+		}
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(sweepPathOutDir, 0755); err != nil {
+			fmt.Printf("❌ Error: Failed to create output directory: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("\n🧹 COOPERATIVE SWEEP PATH:")
+		fmt.Println(strings.Repeat("─", 40))
+		t := newTable(os.Stdout, true, false, true)
+		total := 0
+		txs := sweepTxs(txtree)
+		short := txidShortener(os.Stdout, broadcastOrder(txtree))
+		for i, tx := range txs {
+			txid := tx.UnsignedTx.TxID()
+			vsize, err := witnessModel().Vsize(tx)
+			if err != nil {
+				fmt.Printf("❌ Error: Failed to estimate the vsize of %s: %s\n", txid, err)
+				os.Exit(1)
+			}
+			if err := writePSBT(filepath.Join(sweepPathOutDir, txid+".psbt"), tx); err != nil {
+				fmt.Printf("❌ Error: Failed to write %s: %s\n", txid, err)
+				os.Exit(1)
+			}
+			total += vsize
+			t.row(strconv.Itoa(i+1), short(txid), strconv.Itoa(vsize), "vB")
+		}
+		t.flush()
+
+		nodes, err := arktree.NumberOfNodes(txtree)
+		if err != nil {
+			fmt.Printf("❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(strings.Repeat("─", 40))
+		t = newTable(os.Stdout, false, true)
+		t.row("Transactions:", strconv.Itoa(len(txs)), fmt.Sprintf("tx (the whole tree: %d)", nodes))
+		t.row("Total vsize:", strconv.Itoa(total), "vB")
+		t.row("Estimated fee:", strconv.FormatInt(arktree.FeeForVsize(total, feerate), 10), fmt.Sprintf("sats at %g sat/vB", feerate))
+		t.flush()
+		fmt.Printf("💾 Wrote %d tx to %s\n", len(txs), sweepPathOutDir)
+	},
+}
+
+func init() {
+	sweepPathCmd.Flags().StringVar(&sweepPathOutDir, "out", "", "Directory the PSBTs of the sweep txs are written to")
+	sweepPathCmd.Flags().Float64Var(&feerate, "feerate", 1, "Feerate in sat/vB used to estimate the fee of the sweep")
+	addWitnessFlags(sweepPathCmd)
+
+	rootCmd.AddCommand(sweepPathCmd)
+}
+
+// sweepTxs returns the txs of the cooperative sweep of txtree, in broadcast
+// order: the root only, see arktree.CooperativeSweepCost
+func sweepTxs(txtree *tree.TxGraph) []*psbt.Packet {
+	return []*psbt.Packet{txtree.Root}
+}
+
+// writePSBT writes tx to path as a base64 PSBT and a newline
+func writePSBT(path string, tx *psbt.Packet) error {
+	encoded, err := tx.B64Encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(encoded+"\n"), 0644)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestSweepTxsDecodeToTheSweepCost(t *testing.T) {
+	stats := seededReport(t, 7, 1)
+	vsize := 0
+	for _, tx := range sweepTxs(stats.Tree) {
+		encoded, err := tx.B64Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := psbt.NewFromRawBytes(strings.NewReader(encoded), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.UnsignedTx.TxID() != tx.UnsignedTx.TxID() {
+			t.Errorf("tx %s decodes to %s", tx.UnsignedTx.TxID(), decoded.UnsignedTx.TxID())
+		}
+		txVsize, err := arktree.DefaultWitnessModel.Vsize(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vsize += txVsize
+	}
+	if vsize != stats.CooperativeSweep.Vsize {
+		t.Errorf("sweep path of %d vB, the sweep cost counts %d", vsize, stats.CooperativeSweep.Vsize)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// table renders rows of cells in aligned columns separated by a space. Unlike
+// text/tabwriter it measures cells by their width on a terminal, so that the
+// emoji prefixed labels line up whatever their number of runes.
+type table struct {
+	w     io.Writer
+	right []bool     // columns aligned to the right, the others to the left
+	rows  [][]string // nil for the entries of lines
+	lines []string
+	// notes maps row labels, their first cell, to a line added under them
+	notes map[string]string
+}
+
+func newTable(w io.Writer, right ...bool) *table {
+	return &table{w: w, right: right}
+}
+
+func (t *table) row(cells ...string) {
+	t.rows = append(t.rows, cells)
+	if note, ok := t.notes[cells[0]]; ok {
+		t.line("   ↳ %s", note)
+	}
+}
+
+// line adds a line printed as is, which does not widen the columns
+func (t *table) line(format string, a ...any) {
+	t.rows = append(t.rows, nil)
+	t.lines = append(t.lines, fmt.Sprintf(format, a...))
+}
+
+func (t *table) rowf(label, format string, a ...any) {
+	t.row(label, fmt.Sprintf(format, a...))
+}
+
+// flush writes the rows, trailing spaces excluded
+func (t *table) flush() {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	lines := t.lines
+	for _, row := range t.rows {
+		if row == nil {
+			fmt.Fprintln(t.w, lines[0])
+			lines = lines[1:]
+			continue
+		}
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteByte(' ')
+			}
+			padding := strings.Repeat(" ", widths[i]-displayWidth(cell))
+			if i < len(t.right) && t.right[i] {
+				line.WriteString(padding + cell)
+			} else {
+				line.WriteString(cell + padding)
+			}
+		}
+		fmt.Fprintln(t.w, strings.TrimRight(line.String(), " "))
+	}
+	t.rows, t.lines = nil, nil
+}
+
+// displayWidth returns the number of terminal columns s takes: emoji take two
+// columns, variation selectors and ANSI color sequences none
+func displayWidth(s string) int {
+	width := 0
+	escape := false
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == '\x1b':
+			escape = true
+		case r == '️' || r == '‍' || unicode.Is(unicode.Mn, r):
+		case r >= 0x1f000, r >= 0x2300 && r <= 0x23ff, r >= 0x2600 && r <= 0x27bf:
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
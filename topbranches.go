@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+// heaviestBranches returns the indexes of the n branches of stats with the
+// highest broadcast weight, heaviest first, ties broken by leaf txid
+func heaviestBranches(stats *arktree.Report, n int) []int {
+	indexes := make([]int, len(stats.BranchWeights))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	// branches follow leaf txid order, so a stable sort breaks ties by txid
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return stats.BranchWeights[indexes[i]] > stats.BranchWeights[indexes[j]]
+	})
+
+	return indexes[:min(n, len(indexes))]
+}
+
+// printTopBranches prints the n heaviest branches with their size, weight and
+// exit fee, in place of the detail sections
+func printTopBranches(stats *arktree.Report, n int) {
+	top := heaviestBranches(stats, n)
+
+	fmt.Printf("\n🔝 TOP %d BRANCHES BY TX TO BROADCAST (%.2f sat/vB):\n", len(top), stats.Feerate)
+	fmt.Println(strings.Repeat("─", 60))
+	t := newTable(os.Stdout, true, false, true, true, true)
+	t.row("#", "leaf txid", "size", "weight", "fee (sats)")
+	short := txidShortener(os.Stdout, stats.LeafTxids)
+	sizes := reportedBranchSizes(stats)
+	for rank, i := range top {
+		fee := "-" // not computed in interrupted runs
+		if i < len(stats.ExitCosts) {
+			fee = strconv.FormatInt(stats.ExitCosts[i].Fee, 10)
+		}
+		t.row(strconv.Itoa(rank+1), short(stats.LeafTxids[i]), strconv.Itoa(sizes[i]), fmt.Sprintf("%.2f", stats.BranchWeights[i]), fee)
+	}
+	t.flush()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var txSizesTop int
+
+var txSizesCmd = &cobra.Command{
+	Use:   "tx-sizes [tree-file]",
+	Short: "List the txs of an exported tree by estimated vsize, the biggest first",
+	Long: `Import a tree exported with "generate --out" and list its txs by estimated vsize with the witness model, the biggest first, with their level, the root being at 1, and number of cosigners: where the bytes of the tree concentrate, as generate --top-branches shows where its txs to broadcast do.
+
+Txs of the same vsize are ordered by level then txid. --top limits the list to the biggest ones.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if txSizesTop < 0 {
+			fmt.Printf("Error: --top must be positive, got %d\n", txSizesTop)
+			os.Exit(1)
+		}
+		if err := witnessModel().Validate(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		sizes, err := arktree.TxSizes(txtree, witnessModel())
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to estimate the tx sizes: %s\n", err)
+			os.Exit(1)
+		}
+		total := 0
+		for _, size := range sizes {
+			total += size.Vsize
+		}
+		shown := sizes
+		if txSizesTop > 0 {
+			shown = sizes[:min(txSizesTop, len(sizes))]
+		}
+
+		fmt.Printf("\n📦 TXS BY VSIZE (%d of %d):\n", len(shown), len(sizes))
+		fmt.Println(strings.Repeat("─", 60))
+		t := newTable(os.Stdout, true, false, true, true, true)
+		t.row("#", "txid", "level", "cosigners", "vsize (vB)")
+		txids := make([]string, len(sizes))
+		for i, size := range sizes {
+			txids[i] = size.Txid
+		}
+		short := txidShortener(os.Stdout, txids)
+		shownVsize := 0
+		for rank, size := range shown {
+			shownVsize += size.Vsize
+			t.row(strconv.Itoa(rank+1), short(size.Txid), strconv.Itoa(size.Level), strconv.Itoa(size.Cosigners), strconv.Itoa(size.Vsize))
+		}
+		t.flush()
+
+		fmt.Println(strings.Repeat("─", 60))
+		t = newTable(os.Stdout, false, true)
+		t.row("Total vsize:", strconv.Itoa(total), "vB")
+		if len(shown) < len(sizes) {
+			t.row("Listed txs:", strconv.Itoa(shownVsize), fmt.Sprintf("vB (%.1f%%)", 100*float64(shownVsize)/float64(total)))
+		}
+		t.flush()
+	},
+}
+
+func init() {
+	txSizesCmd.Flags().IntVar(&txSizesTop, "top", 0, "List only the N biggest txs (0 for all)")
+	addWitnessFlags(txSizesCmd)
+
+	rootCmd.AddCommand(txSizesCmd)
+}
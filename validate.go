@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var validateStrict bool
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [tree-file]",
+	Short: "Check an exported tree for inconsistencies",
+	Long: `Import a tree exported with "generate --out" and check it: every node must be reachable from the root, its manifest must match the tree and each node must be cosigned by the union of its children's cosigners.
+
+With --strict, orphaned nodes, not reachable from the root, and manifest mismatches fail instead of warning, and every tx must also conserve the value of the parent output it spends, its outputs anchor included summing to it, less the per-tx fee the manifest records.
+
+Prints PASS or FAIL per check and exits with a non-zero status if any check fails.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		export, err := readTreeExport(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		failures := 0
+		check := func(name string, err error) {
+			if err != nil {
+				failures++
+				fmt.Printf("❌ FAIL  %s: %s\n", name, err)
+			} else {
+				fmt.Printf("✅ PASS  %s\n", name)
+			}
+		}
+
+		// orphans make the whole graph invalid, the other checks run on the
+		// nodes reachable from the root
+		chunks, orphans, err := arktree.SplitOrphans(export.Chunks)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+		if len(orphans) > 0 {
+			err = fmt.Errorf("%d node(s) not reachable from the root: %s", len(orphans), strings.Join(orphans, ", "))
+			if !validateStrict {
+				warnf(os.Stdout, "%s", err)
+				err = nil
+			}
+		}
+		check("every node is reachable from the root", err)
+
+		txtree, err := importedGraph(chunks)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+		manifest := &export.Manifest
+
+		warnings, err := checkManifest(txtree, manifest)
+		if err == nil && len(warnings) > 0 {
+			if validateStrict {
+				err = errors.New(strings.Join(warnings, ", "))
+				for _, warning := range warnings {
+					fmt.Printf("⚠️  WARNING: %s\n", warning)
+				}
+			} else {
+				for _, warning := range warnings {
+					warnf(os.Stdout, "%s", warning)
+				}
+			}
+		}
+		check("manifest matches the tree", err)
+		if warning := checkArkVersion(manifest); warning != "" {
+			warnf(os.Stdout, "%s", warning)
+		}
+
+		check("cosigner sets are the union of the children's", arktree.VerifyCosignerSets(txtree))
+
+		if export.Cosigners != nil {
+			mismatches, err := checkCosigners(txtree, export.Cosigners)
+			if err == nil && len(mismatches) > 0 {
+				err = errors.New(strings.Join(mismatches, ", "))
+			}
+			check("stored cosigners match the inputs", err)
+		}
+
+		if validateStrict {
+			var fee int64
+			if manifest.Build != nil {
+				fee = manifest.Build.PerTxFee
+			}
+			check("value is conserved", checkConservation(txtree, fee))
+		}
+
+		if failures > 0 {
+			fmt.Printf("\n❌ %d check(s) failed\n", failures)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Fail on manifest mismatches and check that every tx conserves value")
+	addIgnoreVersionFlag(validateCmd)
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+// checkConservation fails on the first tx of txtree not conserving value
+// once its fee of fee sats paid, printing the others
+func checkConservation(txtree *tree.TxGraph, fee int64) error {
+	discrepancies, err := arktree.ValueConservationWithFee(txtree, fee)
+	if err != nil {
+		return err
+	}
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	for _, discrepancy := range discrepancies[1:] {
+		fmt.Printf("   %s\n", discrepancy)
+	}
+	return fmt.Errorf("%d tx(s) don't conserve value, first %s", len(discrepancies), discrepancies[0])
+}
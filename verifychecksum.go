@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var verifyChecksumCmd = &cobra.Command{
+	Use:   "verify-checksum [tree-or-manifest-file] [checksum]",
+	Short: "Rebuild a tree from its manifest and check that it has the given checksum",
+	Long: `Read the build parameters recorded in the manifest of a tree exported with "generate --out", rebuild the tree as rebuild does and check that the SHA256 of its txids in broadcast order is the given checksum, as printed by generate --checksum. The command exits with status 1 if they differ.
+
+Sharing the manifest and the checksum of a tree is enough to check that it reproduces, without the whole export. Only seeded trees built from generated leaves reproduce: the manifest of a tree built without --seed is rejected, and that of a tree built from a leaves file has no build parameters.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		claimed := strings.ToLower(args[1])
+		if err := validateChecksum(claimed); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		build, err := loadBuildParams(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to load build parameters: %s\n", err)
+			os.Exit(1)
+		}
+		if build.Seed == nil {
+			fmt.Println("❌ Error: The tree wasn't seeded, it can't be rebuilt identically")
+			os.Exit(1)
+		}
+		opts, err := build.generateOptions()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("🔒 Ark Tree Checksum Verifier")
+		fmt.Println("=" + strings.Repeat("=", 50))
+		fmt.Printf("📄 Parameters from %s (seed %d)\n", args[0], *build.Seed)
+		fmt.Printf("🌿 Rebuilding Vtxo tree with %d leaves... ", build.Leaves)
+		generation, err := arktree.Generate(opts)
+		if err != nil {
+			fmt.Printf("\n❌ Error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ (%s)\n", generation.Timings[len(generation.Timings)-1].Elapsed)
+
+		rebuilt := treeChecksum(generation.Tree)
+		t := newTable(os.Stdout, false, false)
+		t.row("Claimed:", claimed)
+		t.row("Rebuilt:", rebuilt)
+		t.flush()
+		if rebuilt != claimed {
+			fmt.Println("❌ Checksum mismatch: the parameters don't reproduce the tree")
+			os.Exit(1)
+		}
+		fmt.Println("✅ Checksum matches: the tree reproduces from its parameters")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyChecksumCmd)
+}
+
+// validateChecksum checks that checksum is a hex SHA256, as treeChecksum
+// returns it
+func validateChecksum(checksum string) error {
+	decoded, err := hex.DecodeString(checksum)
+	if err != nil || len(decoded) != 32 {
+		return errors.New("the checksum must be 64 hex characters, the SHA256 printed by generate --checksum")
+	}
+	return nil
+}
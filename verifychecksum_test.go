@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/louisinger/arktree/pkg/arktree"
+)
+
+func TestRebuildFromManifest(t *testing.T) {
+	seed := int64(3)
+	build := &buildParams{Leaves: 5, Seed: &seed, Amount: arktree.DefaultLeafAmount, LocktimeType: locktimeTypeBlock, LocktimeValue: arktree.DefaultLocktime.Value}
+	opts, err := build.generateOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	generation, err := arktree.Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tree.json")
+	if err := exportTree(path, generation.Tree, build, generation.SweepTreeRoot, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadBuildParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err = loaded.generateOptions(); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := arktree.Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := treeChecksum(rebuilt.Tree), treeChecksum(generation.Tree); got != want {
+		t.Errorf("rebuilt checksum %s, expected %s", got, want)
+	}
+}
+
+func TestValidateChecksum(t *testing.T) {
+	checksum := treeChecksum(seededTree(t, 2, 1).Tree)
+	for _, test := range []struct {
+		name     string
+		checksum string
+		valid    bool
+	}{
+		{"tree checksum", checksum, true},
+		{"63 characters", checksum[1:], false},
+		{"not hex", "z" + checksum[1:], false},
+	} {
+		if err := validateChecksum(test.checksum); (err == nil) != test.valid {
+			t.Errorf("%s: error %v, expected valid: %t", test.name, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Version and BuildTime are set at build time via -ldflags (see Makefile)
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+const arkModulePath = "github.com/ark-network/ark/common"
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the arktree version and the linked ark version",
+	Long:  `Print the arktree build version, the version of the ark dependency used to build trees and the Go version.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(versionInfo())
+	},
+}
+
+func init() {
+	rootCmd.Version = Version
+	rootCmd.SetVersionTemplate(versionInfo())
+	rootCmd.AddCommand(versionCmd)
+}
+
+func versionInfo() string {
+	arkVersion, arkCommit := arkDependencyVersion()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "arktree:    %s\n", Version)
+	fmt.Fprintf(&sb, "build time: %s\n", BuildTime)
+	fmt.Fprintf(&sb, "ark:        %s\n", arkVersion)
+	if arkCommit != "" {
+		fmt.Fprintf(&sb, "ark commit: %s\n", arkCommit)
+	}
+	fmt.Fprintf(&sb, "go:         %s\n", runtime.Version())
+	return sb.String()
+}
+
+// arkDependencyVersion returns the version of the ark module linked in the binary
+// and, if it is a pseudo-version, the commit it points to
+func arkDependencyVersion() (string, string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != arkModulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		return dep.Version, pseudoVersionCommit(dep.Version)
+	}
+
+	return "unknown", ""
+}
+
+// pseudoVersionCommit extracts the commit hash from a pseudo-version
+// like v0.0.0-20250702115148-7e78caf133ed
+func pseudoVersionCommit(version string) string {
+	parts := strings.Split(version, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	commit := parts[len(parts)-1]
+	if len(commit) != 12 {
+		return ""
+	}
+	return commit
+}
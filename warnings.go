@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// werror is the global --werror flag, failing the command once it's done if
+// any warning was printed
+var werror bool
+
+// warningCount is the number of warnings printed by the command
+var warningCount int
+
+// warnf prints a warning line to w and counts it for --werror. The warnings
+// are: leaves of a leaves file sharing a script, a degenerate tree, an export
+// not matching its manifest or built with another ark version, nodes not
+// reachable from the root, rebuilding an unseeded tree and txids ambiguous at
+// --txid-length.
+func warnf(w io.Writer, format string, a ...any) {
+	warningCount++
+	fmt.Fprintf(w, "⚠️  WARNING: "+format+"\n", a...)
+}
+
+// exitOnWarnings exits with status 1 if warnings were printed with --werror
+func exitOnWarnings() {
+	if werror && warningCount > 0 {
+		fmt.Fprintf(os.Stderr, "❌ Error: %d warning(s) treated as errors with --werror\n", warningCount)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ark-network/ark/common/tree"
+	"github.com/louisinger/arktree/pkg/arktree"
+	"github.com/spf13/cobra"
+)
+
+var psbtOutDir string
+
+var worstBranchCmd = &cobra.Command{
+	Use:   "worst-branch [tree-file]",
+	Short: "Write the heaviest branch of an exported tree as PSBTs",
+	Long: `Import a tree exported with "generate --out", find the branch with the highest broadcast weight and write its transactions as base64 PSBTs in broadcast order, root first.
+
+Files are named <position>-<txid>.psbt so that sorting them by name gives the broadcast order.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		txtree, _, err := importTree(args[0])
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to import tree: %s\n", err)
+			os.Exit(1)
+		}
+
+		weights, err := arktree.WeightOfBranches(txtree, false)
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to get weight of branches: %s\n", err)
+			os.Exit(1)
+		}
+
+		// ties are broken by leaf txid since weights follow arktree.LeafTxids order
+		leaves := arktree.LeafTxids(txtree)
+		worst := 0
+		for i, weight := range weights {
+			if weight > weights[worst] {
+				worst = i
+			}
+		}
+
+		branch, err := txtree.SubGraph([]string{leaves[worst]})
+		if err != nil {
+			fmt.Printf("❌ Error: Failed to extract branch: %s\n", err)
+			os.Exit(1)
+		}
+		path := branchPath(branch)
+
+		if psbtOutDir != "" {
+			if err := writeBranchPSBTs(psbtOutDir, path); err != nil {
+				fmt.Printf("❌ Error: Failed to write PSBTs: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println("📡 HEAVIEST BRANCH")
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("🍃 Leaf:                  %s\n", leaves[worst])
+		fmt.Printf("📡 Tx to Broadcast:       %8.2f\n", weights[worst])
+		fmt.Printf("📏 Branch Size:           %8d tx\n", len(path))
+		fmt.Println("\n🔗 Broadcast order:")
+		short := txidShortener(os.Stdout, broadcastOrder(txtree))
+		for i, node := range path {
+			fmt.Printf("%3d. %s\n", i+1, short(node.Root.UnsignedTx.TxID()))
+		}
+		if psbtOutDir != "" {
+			fmt.Printf("\n💾 PSBTs written to %s\n", psbtOutDir)
+		}
+	},
+}
+
+func init() {
+	worstBranchCmd.Flags().StringVar(&psbtOutDir, "psbt-out", "", "Directory to write the branch PSBTs to")
+
+	rootCmd.AddCommand(worstBranchCmd)
+}
+
+// branchPath returns the nodes of a single branch subgraph from root to leaf
+func branchPath(branch *tree.TxGraph) []*tree.TxGraph {
+	var path []*tree.TxGraph
+	for node := branch; node != nil; {
+		path = append(path, node)
+
+		var next *tree.TxGraph
+		for _, child := range node.Children {
+			next = child
+		}
+		node = next
+	}
+	return path
+}
+
+// writeBranchPSBTs writes each node of path as a base64 PSBT in dir
+func writeBranchPSBTs(dir string, path []*tree.TxGraph) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for i, node := range path {
+		b64, err := node.Root.B64Encode()
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%02d-%s.psbt", i+1, node.Root.UnsignedTx.TxID())
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(b64), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}